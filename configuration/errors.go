@@ -0,0 +1,28 @@
+//
+// Copyright (C) 2026 EdgeX Foundry Contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package configuration
+
+import "github.com/edgexfoundry/go-mod-configuration/v2/pkg/types"
+
+// The errors returned from the Client implementations wrap one of these sentinel errors when the failure can be
+// classified, so callers can check them with errors.Is rather than matching on the error message.
+var (
+	// ErrNotFound is wrapped when the requested configuration doesn't exist in the Configuration service.
+	// Note that GetConfigurationValue returns nil without an error for a missing value.
+	ErrNotFound = types.ErrNotFound
+	// ErrUnauthorized is wrapped when the Configuration service rejects the access token
+	ErrUnauthorized = types.ErrUnauthorized
+	// ErrUnavailable is wrapped when the Configuration service can't be reached or is temporarily unavailable
+	ErrUnavailable = types.ErrUnavailable
+	// ErrDecode is wrapped when the stored configuration can't be decoded into the target struct
+	ErrDecode = types.ErrDecode
+	// ErrConflict is wrapped when PutConfigurationValueCAS is rejected because the value has been changed since its
+	// revision was read
+	ErrConflict = types.ErrConflict
+)
+
+// ProviderError is the error type used by the providers to classify their own errors with the sentinel errors above
+type ProviderError = types.ProviderError
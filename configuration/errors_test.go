@@ -0,0 +1,167 @@
+//
+// Copyright (C) 2026 EdgeX Foundry Contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package configuration
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/edgexfoundry/go-mod-configuration/v2/internal/pkg/consul"
+	"github.com/edgexfoundry/go-mod-configuration/v2/internal/pkg/keeper"
+	"github.com/edgexfoundry/go-mod-configuration/v2/pkg/types"
+)
+
+type errorTestConfig struct {
+	Port int
+}
+
+type mockProvider struct {
+	Type           string
+	Server         *httptest.Server
+	SetToken       func(token string)
+	ClearToken     func()
+	InjectFailures func(count int, statusCode int)
+}
+
+func startMockProviders(t *testing.T) []mockProvider {
+	mockConsul := consul.NewMockConsul()
+	mockKeeper := keeper.NewMockCoreKeeper()
+
+	providers := []mockProvider{
+		{
+			Type:           "consul",
+			Server:         mockConsul.Start(),
+			SetToken:       mockConsul.SetExpectedAccessToken,
+			ClearToken:     mockConsul.ClearExpectedAccessToken,
+			InjectFailures: mockConsul.InjectTransientFailures,
+		},
+		{
+			Type:           "keeper",
+			Server:         mockKeeper.Start(),
+			SetToken:       mockKeeper.SetExpectedAccessToken,
+			ClearToken:     mockKeeper.ClearExpectedAccessToken,
+			InjectFailures: mockKeeper.InjectTransientFailures,
+		},
+	}
+
+	t.Cleanup(func() {
+		for _, provider := range providers {
+			provider.Server.Close()
+		}
+	})
+
+	return providers
+}
+
+func makeMockProviderClient(t *testing.T, provider mockProvider) Client {
+	URL, _ := url.Parse(provider.Server.URL)
+	serverPort, _ := strconv.Atoi(URL.Port())
+
+	client, err := NewConfigurationClient(types.ServiceConfig{
+		Host:     URL.Hostname(),
+		Port:     serverPort,
+		Type:     provider.Type,
+		BasePath: "edgex/errors/" + strconv.Itoa(time.Now().Nanosecond()),
+	})
+	require.NoError(t, err)
+
+	return client
+}
+
+func TestProviderErrors(t *testing.T) {
+	testCases := []struct {
+		Name     string
+		Run      func(client Client, provider mockProvider) error
+		Expected error
+	}{
+		{
+			Name: "Missing value",
+			Run: func(client Client, _ mockProvider) error {
+				value, err := client.GetConfigurationValue("Missing")
+				if err == nil && value != nil {
+					return errors.New("expected nil value for missing key")
+				}
+				return err
+			},
+			Expected: nil,
+		},
+		{
+			Name: "Missing configuration",
+			Run: func(client Client, _ mockProvider) error {
+				_, err := client.GetConfiguration(&errorTestConfig{})
+				return err
+			},
+			Expected: ErrNotFound,
+		},
+		{
+			Name: "Unauthorized",
+			Run: func(client Client, provider mockProvider) error {
+				provider.SetToken("expected-token")
+				defer provider.ClearToken()
+				_, err := client.GetConfigurationValue("Port")
+				return err
+			},
+			Expected: ErrUnauthorized,
+		},
+		{
+			Name: "Decode failure",
+			Run: func(client Client, _ mockProvider) error {
+				if err := client.PutConfigurationValue("Port", []byte("not a number")); err != nil {
+					return err
+				}
+				_, err := client.GetConfiguration(&errorTestConfig{})
+				return err
+			},
+			Expected: ErrDecode,
+		},
+		{
+			Name: "Internal server error",
+			Run: func(client Client, provider mockProvider) error {
+				provider.InjectFailures(1, http.StatusInternalServerError)
+				_, err := client.GetConfigurationValue("Port")
+				return err
+			},
+			Expected: ErrUnavailable,
+		},
+		{
+			Name: "Unavailable",
+			Run: func(client Client, provider mockProvider) error {
+				provider.Server.Close()
+				_, err := client.GetConfigurationValue("Port")
+				return err
+			},
+			Expected: ErrUnavailable,
+		},
+	}
+
+	for _, provider := range startMockProviders(t) {
+		provider := provider
+		t.Run(provider.Type, func(t *testing.T) {
+			for _, test := range testCases {
+				t.Run(test.Name, func(t *testing.T) {
+					client := makeMockProviderClient(t, provider)
+
+					err := test.Run(client, provider)
+					if test.Expected == nil {
+						require.NoError(t, err)
+						return
+					}
+
+					require.Error(t, err)
+					assert.True(t, errors.Is(err, test.Expected), "expected %v, got: %v", test.Expected, err)
+				})
+			}
+		})
+	}
+}
@@ -21,25 +21,47 @@ import (
 	"fmt"
 
 	"github.com/edgexfoundry/go-mod-configuration/v2/internal/pkg/consul"
+	"github.com/edgexfoundry/go-mod-configuration/v2/internal/pkg/file"
 	"github.com/edgexfoundry/go-mod-configuration/v2/internal/pkg/keeper"
+	"github.com/edgexfoundry/go-mod-configuration/v2/pkg/memory"
 	"github.com/edgexfoundry/go-mod-configuration/v2/pkg/types"
 )
 
 func NewConfigurationClient(config types.ServiceConfig) (Client, error) {
 
-	if config.Host == "" || config.Port == 0 {
-		return nil, fmt.Errorf("unable to create Configuration Client: Configuration service host and/or port or serviceKey not set")
-	}
-
 	switch config.Type {
 	case "consul":
+		if err := checkHostAndPort(config); err != nil {
+			return nil, err
+		}
 		var err error
 		client, err := consul.NewConsulClient(config)
 		return client, err
 	case "keeper":
-		client := keeper.NewKeeperClient(config)
+		if err := checkHostAndPort(config); err != nil {
+			return nil, err
+		}
+		client, err := keeper.NewKeeperClient(config)
+		if err != nil {
+			return nil, err
+		}
+		return client, nil
+	case "file":
+		client, err := file.NewFileClient(config)
+		if err != nil {
+			return nil, err
+		}
 		return client, nil
+	case "memory":
+		return memory.NewMemoryClient(config), nil
 	default:
 		return nil, fmt.Errorf("unknown configuration client type '%s' requested", config.Type)
 	}
 }
+
+func checkHostAndPort(config types.ServiceConfig) error {
+	if config.Host == "" || config.Port == 0 {
+		return fmt.Errorf("unable to create Configuration Client: Configuration service host and/or port or serviceKey not set")
+	}
+	return nil
+}
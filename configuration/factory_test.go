@@ -17,6 +17,7 @@
 package configuration
 
 import (
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -40,6 +41,22 @@ func TestNewClientConsul(t *testing.T) {
 	}
 
 	assert.False(t, client.IsAlive(), "Consul service not expected be running")
+
+	_, ok := client.(ContextClient)
+	assert.True(t, ok, "Consul client expected to implement ContextClient")
+}
+
+func TestNewClientKeeper(t *testing.T) {
+
+	config.Type = "keeper"
+
+	client, err := NewConfigurationClient(config)
+	if assert.Nil(t, err, "New Configuration client failed: ", err) == false {
+		t.Fatal()
+	}
+
+	_, ok := client.(ContextClient)
+	assert.True(t, ok, "Keeper client expected to implement ContextClient")
 }
 
 func TestNewClientBogusType(t *testing.T) {
@@ -51,3 +68,36 @@ func TestNewClientBogusType(t *testing.T) {
 		t.Fatal()
 	}
 }
+
+func TestNewClientFile(t *testing.T) {
+	fileConfig := types.ServiceConfig{
+		Type:     "file",
+		FilePath: filepath.Join(t.TempDir(), "configuration.toml"),
+	}
+
+	client, err := NewConfigurationClient(fileConfig)
+	if assert.Nil(t, err, "New Configuration client failed: ", err) == false {
+		t.Fatal()
+	}
+
+	assert.True(t, client.IsAlive(), "file provider expected to be alive")
+
+	_, ok := client.(ContextClient)
+	assert.True(t, ok, "file client expected to implement ContextClient")
+
+	fileConfig.FilePath = ""
+	_, err = NewConfigurationClient(fileConfig)
+	assert.Error(t, err, "Expected missing file path error")
+}
+
+func TestNewClientMemory(t *testing.T) {
+	client, err := NewConfigurationClient(types.ServiceConfig{Type: "memory", BasePath: "config"})
+	if assert.Nil(t, err, "New Configuration client failed: ", err) == false {
+		t.Fatal()
+	}
+
+	assert.True(t, client.IsAlive(), "in-memory provider expected to be alive")
+
+	_, ok := client.(ContextClient)
+	assert.True(t, ok, "in-memory client expected to implement ContextClient")
+}
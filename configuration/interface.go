@@ -17,7 +17,12 @@
 package configuration
 
 import (
+	"context"
+	"io"
+
 	"github.com/pelletier/go-toml"
+
+	"github.com/edgexfoundry/go-mod-configuration/v2/pkg/types"
 )
 
 type Client interface {
@@ -38,10 +43,45 @@ type Client interface {
 	// Returns the configuration in the target struct as interface{}, which caller must cast
 	GetConfiguration(configStruct interface{}) (interface{}, error)
 
-	// WatchForChanges sets up a Consul watch for the target key and send back updates on the update channel.
-	// Passed in struct is only a reference for Configuration service, empty struct is ok
-	// Sends the configuration in the target struct as interface{} on updateChannel, which caller must cast
-	WatchForChanges(updateChannel chan<- interface{}, errorChannel chan<- error, configuration interface{}, waitKey string)
+	// ExportConfiguration writes the service's whole configuration to w as a document in the format. The imported
+	// document is exported exactly, including the types of its values, its empty arrays and sections, as long as its
+	// values haven't been changed since. The other values are typed as they are stored, so importing the exported
+	// document stores exactly the same values.
+	ExportConfiguration(w io.Writer, format types.ConfigurationFormat) error
+
+	// ImportConfiguration reads a document in the format from r and puts it into the Configuration service as the
+	// service's configuration, the same way as PutConfiguration. Existing values are only replaced if overwrite is set.
+	// The types the stored values don't tell, e.g. of the string "1" or of the empty arrays, are stored along with
+	// them for ExportConfiguration.
+	ImportConfiguration(r io.Reader, format types.ConfigurationFormat, overwrite bool) error
+
+	// PlanConfiguration compares the desired configuration, either a struct, a map or a *toml.Tree, with the stored
+	// one and returns the plan of the values to add and modify, with their old and new values, and of the stored
+	// values missing from the desired configuration to remove. Nothing is changed until the plan is applied.
+	PlanConfiguration(desired interface{}) (types.Plan, error)
+
+	// ApplyPlan executes exactly the changes of the plan. If a value changed by the plan has been changed since the
+	// plan was made, the plan isn't applied and an error matching types.ErrConflict is returned.
+	ApplyPlan(plan types.Plan) error
+
+	// WatchForChanges sets up a watch of the target key in the Configuration service, sends back updates on the
+	// update channel and the errors of the watch on the error channel.
+	// Passed in struct is only a reference for the decoder, empty struct is ok
+	// Sends the configuration in a new struct of the same type as interface{} on updateChannel, which caller must cast
+	// Every provider sends the current configuration first, then the configuration after each change.
+	// The options, i.e. types.WithDebounce, apply to this watch only.
+	// Returns the handle of this watch: its Ready channel is closed once the changes are watched, before the first
+	// update is received, its Done channel once the watch has stopped, and its Stop method stops this watch without
+	// stopping the other ones.
+	WatchForChanges(updateChannel chan<- interface{}, errorChannel chan<- error, configuration interface{}, waitKey string, options ...types.WatchOption) types.WatchHandle
+
+	// WatchForChangeSets sets up a watch for the target key and sends back an update on the update channel each time
+	// the configuration under it changes. Each update holds the configuration decoded into a new struct of the same
+	// type as the passed in struct, along with the values which have been added, modified or removed since the
+	// previous update. The first update holds the current configuration, with all its values added.
+	// The options, i.e. types.WithDebounce, apply to this watch only.
+	// Returns the handle used to stop this watch without stopping the other ones
+	WatchForChangeSets(updateChannel chan<- types.WatchUpdate, errorChannel chan<- error, configuration interface{}, waitKey string, options ...types.WatchOption) types.WatchHandle
 
 	// StopWatching causes all WatchForChanges processing to stop and waits until they have stopped.
 	StopWatching()
@@ -52,9 +92,127 @@ type Client interface {
 	// ConfigurationValueExists checks if a configuration value exists in the Configuration service
 	ConfigurationValueExists(name string) (bool, error)
 
-	// GetConfigurationValue gets a specific configuration value from the Configuration service
+	// GetConfigurationValue gets a specific configuration value from the Configuration service.
+	// Returns nil without an error if the value doesn't exist.
 	GetConfigurationValue(name string) ([]byte, error)
 
+	// ListConfigurationKeys lists the keys of the configuration values under the prefix, which is a value or a sub
+	// configuration name and lists the service's whole configuration if empty. The keys are sorted and relative to
+	// the service's base path. The options, i.e. types.WithDepth and types.WithSeparator, limit how deep the keys are
+	// listed and delimit their levels.
+	ListConfigurationKeys(prefix string, options ...types.ListOption) ([]string, error)
+
+	// GetConfigurationValues gets the configuration values under the prefix from the Configuration service, keyed
+	// the same way as ListConfigurationKeys lists them. The sections deeper than the depth option aren't returned.
+	GetConfigurationValues(prefix string, options ...types.ListOption) (map[string][]byte, error)
+
 	// PutConfigurationValue puts a specific configuration value into the Configuration service
 	PutConfigurationValue(name string, value []byte) error
+
+	// GetConfigurationValueWithVersion gets a specific configuration value from the Configuration service along with
+	// its revision, which PutConfigurationValueCAS checks. Returns nil and types.NoRevision without an error if the
+	// value doesn't exist.
+	GetConfigurationValueWithVersion(name string) ([]byte, types.Revision, error)
+
+	// PutConfigurationValueCAS puts a specific configuration value into the Configuration service only if its
+	// revision is still the given one, i.e. it hasn't been changed since it was read. types.NoRevision only creates
+	// the value if it doesn't exist. Returns an error matching types.ErrConflict if the revision doesn't match.
+	// The revision is checked atomically with the write by the Configuration service.
+	PutConfigurationValueCAS(name string, value []byte, revision types.Revision) error
+
+	// DeleteConfigurationValue deletes a specific configuration value from the Configuration service.
+	// Deleting a value that doesn't exist is not an error.
+	DeleteConfigurationValue(name string) error
+
+	// DeleteSubConfiguration deletes all configuration values under the sub configuration (section) with
+	// the specified name from the Configuration service. An empty name deletes the service's whole configuration.
+	DeleteSubConfiguration(name string) error
+}
+
+// ContextClient provides the same operations as Client, but each call takes a context.Context which is used to
+// cancel the call or apply a deadline to it. The context is passed all the way down to the requests sent to the
+// Configuration service.
+type ContextClient interface {
+	// HasConfigurationCtx checks to see if the Configuration service contains the service's configuration.
+	HasConfigurationCtx(ctx context.Context) (bool, error)
+
+	// HasSubConfigurationCtx checks to see if the Configuration service contains the service's sub configuration.
+	HasSubConfigurationCtx(ctx context.Context, name string) (bool, error)
+
+	// PutConfigurationTomlCtx puts a full toml configuration into the Configuration service
+	PutConfigurationTomlCtx(ctx context.Context, configuration *toml.Tree, overwrite bool) error
+
+	// PutConfigurationCtx puts a full configuration struct into the Configuration service
+	PutConfigurationCtx(ctx context.Context, configStruct interface{}, overwrite bool) error
+
+	// GetConfigurationCtx gets the full configuration from the Configuration service into the target configuration struct.
+	// Passed in struct is only a reference for Configuration service. Empty struct is fine
+	// Returns the configuration in the target struct as interface{}, which caller must cast
+	GetConfigurationCtx(ctx context.Context, configStruct interface{}) (interface{}, error)
+
+	// ExportConfigurationCtx writes the service's whole configuration to w as a document in the format
+	ExportConfigurationCtx(ctx context.Context, w io.Writer, format types.ConfigurationFormat) error
+
+	// ImportConfigurationCtx reads a document in the format from r and puts it into the Configuration service as the
+	// service's configuration
+	ImportConfigurationCtx(ctx context.Context, r io.Reader, format types.ConfigurationFormat, overwrite bool) error
+
+	// PlanConfigurationCtx compares the desired configuration with the stored one and returns the plan of the values
+	// to add, modify and remove
+	PlanConfigurationCtx(ctx context.Context, desired interface{}) (types.Plan, error)
+
+	// ApplyPlanCtx executes exactly the changes of the plan, unless a value changed by the plan has been changed since
+	// the plan was made
+	ApplyPlanCtx(ctx context.Context, plan types.Plan) error
+
+	// WatchForChangesCtx sets up a watch for the target key and send back updates on the update channel, the current
+	// configuration first.
+	// The watch stops when either the context is done, it is stopped through the returned handle or StopWatching
+	// is called.
+	WatchForChangesCtx(ctx context.Context, updateChannel chan<- interface{}, errorChannel chan<- error, configuration interface{}, waitKey string, options ...types.WatchOption) types.WatchHandle
+
+	// WatchForChangeSetsCtx sets up a watch for the target key and sends back an update, along with the values which
+	// have been added, modified or removed, on the update channel each time the configuration under it changes.
+	// The watch stops when either the context is done, it is stopped through the returned handle or StopWatching
+	// is called.
+	WatchForChangeSetsCtx(ctx context.Context, updateChannel chan<- types.WatchUpdate, errorChannel chan<- error, configuration interface{}, waitKey string, options ...types.WatchOption) types.WatchHandle
+
+	// StopWatchingCtx causes all WatchForChanges processing to stop and waits until they have stopped or
+	// the context is done, in which case the context's error is returned.
+	StopWatchingCtx(ctx context.Context) error
+
+	// IsAliveCtx simply checks if Configuration service is up and running at the configured URL
+	IsAliveCtx(ctx context.Context) bool
+
+	// ConfigurationValueExistsCtx checks if a configuration value exists in the Configuration service
+	ConfigurationValueExistsCtx(ctx context.Context, name string) (bool, error)
+
+	// GetConfigurationValueCtx gets a specific configuration value from the Configuration service
+	GetConfigurationValueCtx(ctx context.Context, name string) ([]byte, error)
+
+	// ListConfigurationKeysCtx lists the keys of the configuration values under the prefix, relative to the service's
+	// base path
+	ListConfigurationKeysCtx(ctx context.Context, prefix string, options ...types.ListOption) ([]string, error)
+
+	// GetConfigurationValuesCtx gets the configuration values under the prefix from the Configuration service, keyed
+	// relative to the service's base path
+	GetConfigurationValuesCtx(ctx context.Context, prefix string, options ...types.ListOption) (map[string][]byte, error)
+
+	// PutConfigurationValueCtx puts a specific configuration value into the Configuration service
+	PutConfigurationValueCtx(ctx context.Context, name string, value []byte) error
+
+	// GetConfigurationValueWithVersionCtx gets a specific configuration value from the Configuration service along
+	// with its revision
+	GetConfigurationValueWithVersionCtx(ctx context.Context, name string) ([]byte, types.Revision, error)
+
+	// PutConfigurationValueCASCtx puts a specific configuration value into the Configuration service only if its
+	// revision is still the given one
+	PutConfigurationValueCASCtx(ctx context.Context, name string, value []byte, revision types.Revision) error
+
+	// DeleteConfigurationValueCtx deletes a specific configuration value from the Configuration service
+	DeleteConfigurationValueCtx(ctx context.Context, name string) error
+
+	// DeleteSubConfigurationCtx deletes all configuration values under the sub configuration from the
+	// Configuration service
+	DeleteSubConfigurationCtx(ctx context.Context, name string) error
 }
@@ -0,0 +1,110 @@
+//
+// Copyright (C) 2022 IOTech Ltd
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package configuration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/edgexfoundry/go-mod-configuration/v2/pkg/types"
+)
+
+// Update is sent by Watch each time the watched configuration changes, or the watch fails
+type Update[T any] struct {
+	// Value is the new configuration, nil if Err is set
+	Value *T
+	// ChangedKeys are the keys, relative to the watched key, which have been added, modified or removed since the
+	// previous update. All the keys are listed in the first update.
+	ChangedKeys []string
+	// Err is the error reported by the watch, the watch keeps running after an error
+	Err error
+}
+
+// Get gets the full configuration from the Configuration service as a T
+func Get[T any](client Client) (*T, error) {
+	if client == nil {
+		return nil, errors.New("unable to get configuration: client is nil")
+	}
+
+	target := new(T)
+	raw, err := client.GetConfiguration(target)
+	if err != nil {
+		return nil, err
+	}
+
+	return asValue[T](raw)
+}
+
+// Watch watches the configuration under the key and sends an Update with the configuration as a T each time it
+// changes. The options, i.e. types.WithDebounce, are passed to the underlying watch. The returned channel is closed
+// once the context is done, after the underlying watch has stopped.
+func Watch[T any](ctx context.Context, client Client, key string, options ...types.WatchOption) (<-chan Update[T], error) {
+	if client == nil {
+		return nil, errors.New("unable to watch configuration: client is nil")
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	updateChannel := make(chan types.WatchUpdate)
+	errorChannel := make(chan error)
+	var handle types.WatchHandle
+	if contextClient, ok := client.(ContextClient); ok {
+		handle = contextClient.WatchForChangeSetsCtx(ctx, updateChannel, errorChannel, new(T), key, options...)
+	} else {
+		handle = client.WatchForChangeSets(updateChannel, errorChannel, new(T), key, options...)
+	}
+
+	updates := make(chan Update[T])
+	go func() {
+		defer close(updates)
+		defer handle.Stop()
+
+		for {
+			var update Update[T]
+			select {
+			case <-ctx.Done():
+				return
+			case err := <-errorChannel:
+				update.Err = err
+			case watchUpdate := <-updateChannel:
+				value, err := asValue[T](watchUpdate.Configuration)
+				if err != nil {
+					update.Err = err
+				} else {
+					update.Value = value
+					update.ChangedKeys = watchUpdate.Changes.Keys()
+				}
+			}
+
+			select {
+			case updates <- update:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return updates, nil
+}
+
+// asValue returns a copy of the configuration received from the client as a T, so it isn't changed by the client
+// decoding later updates into the same struct
+func asValue[T any](raw interface{}) (*T, error) {
+	switch value := raw.(type) {
+	case *T:
+		if value == nil {
+			return nil, fmt.Errorf("unexpected nil configuration")
+		}
+		result := *value
+		return &result, nil
+	case T:
+		return &value, nil
+	default:
+		return nil, fmt.Errorf("unexpected configuration type %T, expected %T", raw, new(T))
+	}
+}
@@ -0,0 +1,129 @@
+//
+// Copyright (C) 2022 IOTech Ltd
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package configuration
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/edgexfoundry/go-mod-configuration/v2/pkg/memory"
+	"github.com/edgexfoundry/go-mod-configuration/v2/pkg/types"
+)
+
+type typedTestWritable struct {
+	LogLevel string
+	Interval int
+}
+
+type typedTestConfig struct {
+	Writable typedTestWritable
+	Host     string
+}
+
+func makeMemoryClient(t *testing.T) *memory.Client {
+	client := memory.NewMemoryClient(types.ServiceConfig{BasePath: "edgex/typed"})
+	require.NoError(t, client.PutConfiguration(&typedTestConfig{
+		Writable: typedTestWritable{LogLevel: "INFO", Interval: 10},
+		Host:     "localhost",
+	}, true))
+	return client
+}
+
+func TestGet(t *testing.T) {
+	client := makeMemoryClient(t)
+
+	actual, err := Get[typedTestConfig](client)
+	require.NoError(t, err)
+	assert.Equal(t, "localhost", actual.Host)
+	assert.Equal(t, typedTestWritable{LogLevel: "INFO", Interval: 10}, actual.Writable)
+
+	client.SetUnavailable(true)
+	_, err = Get[typedTestConfig](client)
+	assert.True(t, errors.Is(err, ErrUnavailable))
+
+	_, err = Get[typedTestConfig](nil)
+	assert.Error(t, err)
+}
+
+func TestWatch(t *testing.T) {
+	client := makeMemoryClient(t)
+	defer client.StopWatching()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	updates, err := Watch[typedTestWritable](ctx, client, "Writable")
+	require.NoError(t, err)
+
+	receive := func() Update[typedTestWritable] {
+		select {
+		case update, ok := <-updates:
+			require.True(t, ok, "updates channel closed")
+			return update
+		case <-time.After(5 * time.Second):
+			require.Fail(t, "timed out waiting for update")
+		}
+		return Update[typedTestWritable]{}
+	}
+
+	// All the keys are changed in the first update
+	update := receive()
+	require.NoError(t, update.Err)
+	assert.Equal(t, typedTestWritable{LogLevel: "INFO", Interval: 10}, *update.Value)
+	assert.Equal(t, []string{"Interval", "LogLevel"}, update.ChangedKeys)
+
+	require.NoError(t, client.PutConfigurationValue("Writable/LogLevel", []byte("DEBUG")))
+	update = receive()
+	require.NoError(t, update.Err)
+	assert.Equal(t, typedTestWritable{LogLevel: "DEBUG", Interval: 10}, *update.Value)
+	assert.Equal(t, []string{"LogLevel"}, update.ChangedKeys)
+
+	// The errors of the watch are sent as well
+	client.SetOperationError(memory.OperationWatchForChanges, errors.New("watch failed"))
+	require.NoError(t, client.PutConfigurationValue("Writable/Interval", []byte("20")))
+	update = receive()
+	assert.EqualError(t, update.Err, "watch failed")
+	assert.Nil(t, update.Value)
+
+	cancel()
+	select {
+	case _, ok := <-updates:
+		assert.False(t, ok, "updates channel expected to be closed")
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "updates channel not closed")
+	}
+}
+
+func TestWatchContextDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := Watch[typedTestWritable](ctx, makeMemoryClient(t), "Writable")
+	assert.True(t, errors.Is(err, context.Canceled))
+}
+
+func TestAsValue(t *testing.T) {
+	value := typedTestWritable{LogLevel: "INFO"}
+
+	actual, err := asValue[typedTestWritable](&value)
+	require.NoError(t, err)
+	assert.Equal(t, value, *actual)
+	// a copy is returned so later updates decoded into the same struct don't change it
+	value.LogLevel = "DEBUG"
+	assert.Equal(t, "INFO", actual.LogLevel)
+
+	actual, err = asValue[typedTestWritable](value)
+	require.NoError(t, err)
+	assert.Equal(t, value, *actual)
+
+	_, err = asValue[typedTestWritable]("not a configuration")
+	assert.Error(t, err)
+}
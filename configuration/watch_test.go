@@ -0,0 +1,78 @@
+//
+// Copyright (C) 2026 EdgeX Foundry Contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package configuration
+
+import (
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/edgexfoundry/go-mod-configuration/v2/pkg/types"
+)
+
+func TestWatchForChangesFirstUpdate(t *testing.T) {
+	optional := map[string]any{"PollInterval": "10ms"}
+	configs := []types.ServiceConfig{
+		{Type: "memory", BasePath: "edgex/watch"},
+		{Type: "file", BasePath: "edgex/watch", FilePath: filepath.Join(t.TempDir(), "configuration.toml"), Optional: optional},
+	}
+	for _, provider := range startMockProviders(t) {
+		URL, _ := url.Parse(provider.Server.URL)
+		serverPort, _ := strconv.Atoi(URL.Port())
+		configs = append(configs, types.ServiceConfig{
+			Type:     provider.Type,
+			Host:     URL.Hostname(),
+			Port:     serverPort,
+			BasePath: "edgex/watch",
+			Optional: optional,
+		})
+	}
+
+	for _, config := range configs {
+		t.Run(config.Type, func(t *testing.T) {
+			client, err := NewConfigurationClient(config)
+			require.NoError(t, err)
+			defer client.StopWatching()
+			require.NoError(t, client.PutConfiguration(&typedTestConfig{
+				Writable: typedTestWritable{LogLevel: "INFO", Interval: 10},
+				Host:     "localhost",
+			}, true))
+
+			updateChannel := make(chan interface{})
+			errorChannel := make(chan error)
+			handle := client.WatchForChanges(updateChannel, errorChannel, &typedTestWritable{}, "Writable")
+
+			receive := func() interface{} {
+				select {
+				case update := <-updateChannel:
+					return update
+				case err := <-errorChannel:
+					require.NoError(t, err)
+				case <-time.After(5 * time.Second):
+					require.Fail(t, "timed out waiting for update")
+				}
+				return nil
+			}
+
+			// every provider is ready before the first update is received, which holds the current configuration
+			select {
+			case <-handle.Ready():
+			case <-time.After(5 * time.Second):
+				require.Fail(t, "timed out waiting for the watch to be ready")
+			}
+			assert.Equal(t, &typedTestWritable{LogLevel: "INFO", Interval: 10}, receive())
+
+			// then each change is sent
+			require.NoError(t, client.PutConfigurationValue("Writable/LogLevel", []byte("DEBUG")))
+			assert.Equal(t, &typedTestWritable{LogLevel: "DEBUG", Interval: 10}, receive())
+		})
+	}
+}
@@ -0,0 +1,389 @@
+//
+// Copyright (C) 2022 IOTech Ltd
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package codec converts the configuration between the TOML, YAML and JSON documents and the flat '/' separated
+// raw values stored by the configuration providers.
+package codec
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pelletier/go-toml"
+	"gopkg.in/yaml.v3"
+
+	"github.com/edgexfoundry/go-mod-configuration/v2/internal/pkg/decode"
+	"github.com/edgexfoundry/go-mod-configuration/v2/pkg/types"
+)
+
+// Unmarshal parses the document into a tree of maps, slices and scalar values
+func Unmarshal(format types.ConfigurationFormat, data []byte) (map[string]interface{}, error) {
+	document := make(map[string]interface{})
+
+	switch format {
+	case types.FormatTOML:
+		tree, err := toml.LoadBytes(data)
+		if err != nil {
+			return nil, err
+		}
+		document = tree.ToMap()
+	case types.FormatYAML:
+		if err := yaml.Unmarshal(data, &document); err != nil {
+			return nil, err
+		}
+	case types.FormatJSON:
+		if len(bytes.TrimSpace(data)) == 0 {
+			break
+		}
+		// Keep the numbers as they are written in the document, they are converted when decoded into the target struct
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		if err := decoder.Decode(&document); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported configuration format '%s'", format)
+	}
+
+	if document == nil {
+		document = make(map[string]interface{})
+	}
+
+	return document, nil
+}
+
+// Marshal renders the tree of maps, slices and scalar values in the format
+func Marshal(format types.ConfigurationFormat, document map[string]interface{}) ([]byte, error) {
+	switch format {
+	case types.FormatTOML:
+		tree, err := toml.TreeFromMap(document)
+		if err != nil {
+			return nil, err
+		}
+		return tree.Marshal()
+	case types.FormatYAML:
+		return yaml.Marshal(withFloats(document, func(value string) interface{} {
+			return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!float", Value: value}
+		}))
+	case types.FormatJSON:
+		data, err := json.MarshalIndent(withFloats(document, func(value string) interface{} {
+			return json.Number(value)
+		}), "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(data, '\n'), nil
+	default:
+		return nil, fmt.Errorf("unsupported configuration format '%s'", format)
+	}
+}
+
+// withFloats returns a copy of the node with the finite floats replaced by the conversion of their decimal notation,
+// which always has a fraction so they aren't read back as integers, i.e. 1.0 rather than 1
+func withFloats(node interface{}, convert func(value string) interface{}) interface{} {
+	switch value := node.(type) {
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(value))
+		for key, child := range value {
+			result[key] = withFloats(child, convert)
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(value))
+		for index, child := range value {
+			result[index] = withFloats(child, convert)
+		}
+		return result
+	case float64:
+		if math.IsInf(value, 0) || math.IsNaN(value) {
+			return value
+		}
+		text := strconv.FormatFloat(value, 'f', -1, 64)
+		if !strings.Contains(text, ".") {
+			text += ".0"
+		}
+		return convert(text)
+	default:
+		return value
+	}
+}
+
+// Read reads the document from r and normalizes its values, so it can be stored by any provider
+func Read(r io.Reader, format types.ConfigurationFormat) (map[string]interface{}, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the configuration document: %w", err)
+	}
+
+	document, err := Unmarshal(format, data)
+	if err != nil {
+		return nil, types.NewProviderError(types.ErrDecode, fmt.Errorf("unable to parse the %s configuration document: %w", format, err))
+	}
+
+	return Normalize(document).(map[string]interface{}), nil
+}
+
+// Write renders the document in the format and writes it to w
+func Write(w io.Writer, format types.ConfigurationFormat, document map[string]interface{}) error {
+	data, err := Marshal(format, document)
+	if err != nil {
+		return fmt.Errorf("unable to render the %s configuration document: %w", format, err)
+	}
+
+	if _, err := w.Write(data); err != nil {
+		return fmt.Errorf("unable to write the configuration document: %w", err)
+	}
+
+	return nil
+}
+
+// Normalize returns a copy of the node with the values converted to the types the providers store, i.e. the JSON
+// numbers to int64 or float64, the dates to RFC 3339 strings and the null values to empty strings
+func Normalize(node interface{}) interface{} {
+	switch value := node.(type) {
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(value))
+		for key, child := range value {
+			result[key] = Normalize(child)
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(value))
+		for index, child := range value {
+			result[index] = Normalize(child)
+		}
+		return result
+	case json.Number:
+		if number, err := value.Int64(); err == nil {
+			return number
+		}
+		if number, err := value.Float64(); err == nil {
+			return number
+		}
+		return value.String()
+	case int:
+		return int64(value)
+	case uint64:
+		if value <= math.MaxInt64 {
+			return int64(value)
+		}
+		return strconv.FormatUint(value, 10)
+	case time.Time:
+		return value.Format(time.RFC3339Nano)
+	case nil:
+		return ""
+	default:
+		return value
+	}
+}
+
+// ToMap returns the configuration as a map. A map or a *toml.Tree is used as is, while a struct is converted through
+// its TOML encoding, the way the providers put it.
+func ToMap(configuration interface{}) (map[string]interface{}, error) {
+	switch value := configuration.(type) {
+	case *toml.Tree:
+		return value.ToMap(), nil
+	case map[string]interface{}:
+		return value, nil
+	}
+
+	bytes, err := toml.Marshal(configuration)
+	if err != nil {
+		return nil, err
+	}
+
+	tree, err := toml.LoadBytes(bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	return tree.ToMap(), nil
+}
+
+// ToValues returns the raw values of the node keyed by their '/' separated path under the prefix, the array items
+// keyed by their index. The values are formatted by FormatValue, so every provider stores the same raw values.
+func ToValues(prefix string, node interface{}) map[string][]byte {
+	values := make(map[string][]byte)
+	addValues(prefix, node, values)
+	return values
+}
+
+func addValues(prefix string, node interface{}, values map[string][]byte) {
+	join := func(key string) string {
+		if prefix == "" {
+			return key
+		}
+		return prefix + decode.KeyDelimiter + key
+	}
+
+	switch value := node.(type) {
+	case map[string]interface{}:
+		for key, child := range value {
+			addValues(join(key), child, values)
+		}
+	case []interface{}:
+		for index, child := range value {
+			addValues(join(strconv.Itoa(index)), child, values)
+		}
+	default:
+		values[prefix] = FormatValue(value)
+	}
+}
+
+// FormatValue returns the raw value of a leaf node, the way the providers store it: the numbers in decimal notation
+// without exponent, the dates as RFC 3339 strings and the null values as empty strings
+func FormatValue(node interface{}) []byte {
+	switch value := node.(type) {
+	case string:
+		return []byte(value)
+	case []byte:
+		return value
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return []byte(fmt.Sprintf("%d", value))
+	case float32:
+		return []byte(strconv.FormatFloat(float64(value), 'f', -1, 32))
+	case float64:
+		return []byte(strconv.FormatFloat(value, 'f', -1, 64))
+	case bool:
+		return []byte(strconv.FormatBool(value))
+	case json.Number:
+		return []byte(value.String())
+	case time.Time:
+		return []byte(value.Format(time.RFC3339Nano))
+	case nil:
+		return []byte{}
+	default:
+		return []byte(fmt.Sprintf("%v", value))
+	}
+}
+
+// FromValues returns the document of the raw values keyed by their '/' separated path. A value is typed by the
+// valueTypes recorded for it while its raw value is still the recorded one, otherwise it's typed back only if
+// formatting the typed value gives the raw value again, the way the providers store them, i.e. "1" is a number but
+// "1.0" or "01" are strings. The empty arrays and tables of the valueTypes are added if no value is stored under them.
+// The sections whose keys are the indexes from 0 become arrays, unless they are recorded as tables.
+func FromValues(values map[string][]byte, valueTypes Types) map[string]interface{} {
+	document := make(map[string]interface{})
+	for key, value := range values {
+		node := addSections(document, key)
+		if node == nil {
+			continue
+		}
+		levels := strings.Split(key, decode.KeyDelimiter)
+		node[levels[len(levels)-1]] = valueTypes[key].typed(string(value))
+	}
+
+	// the empty arrays and tables have no value, their keys are sorted so the outer ones are added first
+	keys := make([]string, 0, len(valueTypes))
+	for key := range valueTypes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		var empty interface{}
+		switch valueTypes[key].Type {
+		case TypeArray:
+			empty = []interface{}{}
+		case TypeTable:
+			empty = make(map[string]interface{})
+		}
+		if empty == nil || valueTypes[key].Value != "" || hasValues(values, key) {
+			continue
+		}
+		levels := strings.Split(key, decode.KeyDelimiter)
+		if node := addSections(document, key); node != nil {
+			if _, exists := node[levels[len(levels)-1]]; !exists {
+				node[levels[len(levels)-1]] = empty
+			}
+		}
+	}
+
+	// the document itself stays a table
+	for key, child := range document {
+		document[key] = toArrays(key, child, valueTypes)
+	}
+	return document
+}
+
+// addSections adds the sections of the '/' separated path of the key to the document and returns the section the key
+// belongs to, nil if one of them is already a value
+func addSections(document map[string]interface{}, key string) map[string]interface{} {
+	node := document
+	levels := strings.Split(key, decode.KeyDelimiter)
+	for _, level := range levels[:len(levels)-1] {
+		child, exists := node[level]
+		if !exists {
+			child = make(map[string]interface{})
+			node[level] = child
+		}
+		section, ok := child.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		node = section
+	}
+	return node
+}
+
+// hasValues checks if a value is stored at the key or under it
+func hasValues(values map[string][]byte, key string) bool {
+	for valueKey := range values {
+		if valueKey == key || strings.HasPrefix(valueKey, key+decode.KeyDelimiter) {
+			return true
+		}
+	}
+	return false
+}
+
+// TypedValue returns the raw value as a bool, an int64 or a float64 if it is formatted as such, otherwise as is
+func TypedValue(raw string) interface{} {
+	if value, err := strconv.ParseBool(raw); err == nil && strconv.FormatBool(value) == raw {
+		return value
+	}
+	if value, err := strconv.ParseInt(raw, 10, 64); err == nil && strconv.FormatInt(value, 10) == raw {
+		return value
+	}
+	if value, err := strconv.ParseFloat(raw, 64); err == nil && !math.IsInf(value, 0) && !math.IsNaN(value) &&
+		strconv.FormatFloat(value, 'f', -1, 64) == raw {
+		return value
+	}
+	return raw
+}
+
+// toArrays converts the sections whose keys are exactly the indexes from 0 into arrays, except the ones recorded as
+// tables in the valueTypes
+func toArrays(key string, node interface{}, valueTypes Types) interface{} {
+	section, ok := node.(map[string]interface{})
+	if !ok {
+		return node
+	}
+
+	for childKey, child := range section {
+		section[childKey] = toArrays(key+decode.KeyDelimiter+childKey, child, valueTypes)
+	}
+	if valueTypes[key].Type == TypeTable {
+		return section
+	}
+
+	array := make([]interface{}, len(section))
+	for index := range array {
+		child, exists := section[strconv.Itoa(index)]
+		if !exists {
+			return section
+		}
+		array[index] = child
+	}
+	if len(array) == 0 {
+		return section
+	}
+	return array
+}
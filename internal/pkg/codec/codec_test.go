@@ -0,0 +1,236 @@
+//
+// Copyright (C) 2022 IOTech Ltd
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package codec
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/edgexfoundry/go-mod-configuration/v2/pkg/types"
+)
+
+// storedValues are raw values as stored by Consul and Core Keeper
+var storedValues = map[string][]byte{
+	"Host":                 []byte("localhost"),
+	"Port":                 []byte("59880"),
+	"Temp":                 []byte("36.6"),
+	"Enabled":              []byte("true"),
+	"Version":              []byte("3.0"),
+	"Timeout":              []byte("1e5"),
+	"Empty":                []byte(""),
+	"Topics/0":             []byte("events"),
+	"Topics/1":             []byte("commands"),
+	"Routes/0/Path":        []byte("/api"),
+	"Routes/0/Port":        []byte("1"),
+	"Routes/1/Path":        []byte("/ping"),
+	"Routes/1/Port":        []byte("2"),
+	"Writable/LogLevel":    []byte("INFO"),
+	"Writable/Sparse/1":    []byte("x"),
+	"Writable/Sparse/2":    []byte("y"),
+	"Writable/Nested/Deep": []byte("-12"),
+}
+
+func TestFromValues(t *testing.T) {
+	document := FromValues(storedValues, nil)
+
+	assert.Equal(t, "localhost", document["Host"])
+	assert.Equal(t, int64(59880), document["Port"])
+	assert.Equal(t, 36.6, document["Temp"])
+	assert.Equal(t, true, document["Enabled"])
+	// the values which wouldn't be stored the same way once typed are kept as strings
+	assert.Equal(t, "3.0", document["Version"])
+	assert.Equal(t, "1e5", document["Timeout"])
+	assert.Equal(t, "", document["Empty"])
+	assert.Equal(t, []interface{}{"events", "commands"}, document["Topics"])
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"Path": "/api", "Port": int64(1)},
+		map[string]interface{}{"Path": "/ping", "Port": int64(2)},
+	}, document["Routes"])
+	writable := document["Writable"].(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{"1": "x", "2": "y"}, writable["Sparse"])
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, format := range []types.ConfigurationFormat{types.FormatTOML, types.FormatYAML, types.FormatJSON} {
+		t.Run(string(format), func(t *testing.T) {
+			var exported bytes.Buffer
+			require.NoError(t, Write(&exported, format, FromValues(storedValues, nil)))
+
+			imported, err := Read(bytes.NewReader(exported.Bytes()), format)
+			require.NoError(t, err)
+			values := ToValues("", imported)
+			assert.Equal(t, storedValues, values)
+
+			var again bytes.Buffer
+			require.NoError(t, Write(&again, format, FromValues(values, nil)))
+			assert.Equal(t, exported.String(), again.String())
+		})
+	}
+}
+
+func TestTypesRoundTrip(t *testing.T) {
+	original, err := Read(strings.NewReader(`
+Version = "1.0"
+Code = "01"
+Port = "8000"
+Enabled = "true"
+Ratio = 1.0
+Temp = 36.6
+Count = 3
+Topics = []
+
+[Empty]
+
+[Codes]
+  "0" = "a"
+  "1" = "b"
+
+[Writable]
+  LogLevel = "INFO"
+  Labels = {}
+  Retries = [1, 2]
+`), types.FormatTOML)
+	require.NoError(t, err)
+
+	// the types are stored apart from the raw values
+	stored, err := MarshalTypes(TypesOf(original))
+	require.NoError(t, err)
+	valueTypes, err := UnmarshalTypes(stored)
+	require.NoError(t, err)
+	assert.Equal(t, Types{
+		"Port":            {Type: TypeString, Value: "8000"},
+		"Enabled":         {Type: TypeString, Value: "true"},
+		"Ratio":           {Type: TypeFloat, Value: "1"},
+		"Topics":          {Type: TypeArray},
+		"Empty":           {Type: TypeTable},
+		"Codes":           {Type: TypeTable},
+		"Writable/Labels": {Type: TypeTable},
+	}, valueTypes)
+
+	for _, format := range []types.ConfigurationFormat{types.FormatTOML, types.FormatYAML, types.FormatJSON} {
+		t.Run(string(format), func(t *testing.T) {
+			var exported bytes.Buffer
+			require.NoError(t, Write(&exported, format, FromValues(ToValues("", original), valueTypes)))
+
+			imported, err := Read(bytes.NewReader(exported.Bytes()), format)
+			require.NoError(t, err)
+			assert.Equal(t, original, imported)
+		})
+	}
+
+	// the types only apply while the raw values are the recorded ones and no value is stored under the empty ones
+	values := ToValues("", original)
+	values["Port"] = []byte("8001")
+	values["Topics/0"] = []byte("events")
+	document := FromValues(values, valueTypes)
+	assert.Equal(t, int64(8001), document["Port"])
+	assert.Equal(t, []interface{}{"events"}, document["Topics"])
+	assert.Equal(t, 1.0, document["Ratio"])
+
+	_, err = UnmarshalTypes([]byte("{"))
+	assert.True(t, errors.Is(err, types.ErrDecode), "unexpected error: %v", err)
+}
+
+func TestTypesKey(t *testing.T) {
+	assert.Equal(t, "edgex/v2/.types/core-data", TypesKey("edgex/v2/core-data"))
+	assert.Equal(t, "edgex/v2/.types/core-data", TypesKey("edgex/v2/core-data/"))
+	assert.Equal(t, ".types/core-data", TypesKey("core-data"))
+}
+
+func TestToValues(t *testing.T) {
+	date := time.Date(2022, 5, 4, 10, 30, 0, 0, time.UTC)
+	values := ToValues("edgex/core-data", map[string]interface{}{
+		"Host":    "localhost",
+		"Port":    59880,
+		"Temp":    36.6,
+		"Large":   1e21,
+		"Small":   float32(0.5),
+		"Enabled": true,
+		"Number":  json.Number("4.20"),
+		"Date":    date,
+		"Null":    nil,
+		"Topics":  []interface{}{"events", uint64(2)},
+		"Writable": map[string]interface{}{
+			"LogLevel": "INFO",
+		},
+	})
+
+	assert.Equal(t, map[string][]byte{
+		"edgex/core-data/Host":              []byte("localhost"),
+		"edgex/core-data/Port":              []byte("59880"),
+		"edgex/core-data/Temp":              []byte("36.6"),
+		"edgex/core-data/Large":             []byte("1000000000000000000000"),
+		"edgex/core-data/Small":             []byte("0.5"),
+		"edgex/core-data/Enabled":           []byte("true"),
+		"edgex/core-data/Number":            []byte("4.20"),
+		"edgex/core-data/Date":              []byte("2022-05-04T10:30:00Z"),
+		"edgex/core-data/Null":              []byte(""),
+		"edgex/core-data/Topics/0":          []byte("events"),
+		"edgex/core-data/Topics/1":          []byte("2"),
+		"edgex/core-data/Writable/LogLevel": []byte("INFO"),
+	}, values)
+	assert.Equal(t, map[string][]byte{"Port": []byte("1")}, ToValues("Port", int8(1)))
+}
+
+func TestToMap(t *testing.T) {
+	type writable struct {
+		LogLevel string
+	}
+	type config struct {
+		Port     int
+		Writable writable
+	}
+
+	configMap, err := ToMap(config{Port: 59880, Writable: writable{LogLevel: "INFO"}})
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]byte{"Port": []byte("59880"), "Writable/LogLevel": []byte("INFO")}, ToValues("", configMap))
+
+	document := map[string]interface{}{"Port": int64(1)}
+	configMap, err = ToMap(document)
+	require.NoError(t, err)
+	assert.Equal(t, document, configMap)
+}
+
+func TestNormalize(t *testing.T) {
+	date := time.Date(2022, 5, 4, 10, 30, 0, 0, time.UTC)
+	normalized := Normalize(map[string]interface{}{
+		"Int":    7,
+		"Number": json.Number("42"),
+		"Float":  json.Number("4.2"),
+		"Large":  uint64(1 << 63),
+		"Date":   date,
+		"Null":   nil,
+		"List":   []interface{}{json.Number("1"), nil},
+	})
+
+	assert.Equal(t, map[string]interface{}{
+		"Int":    int64(7),
+		"Number": int64(42),
+		"Float":  4.2,
+		"Large":  "9223372036854775808",
+		"Date":   "2022-05-04T10:30:00Z",
+		"Null":   "",
+		"List":   []interface{}{int64(1), ""},
+	}, normalized)
+}
+
+func TestReadErrors(t *testing.T) {
+	_, err := Read(strings.NewReader("Port = "), types.FormatTOML)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, types.ErrDecode))
+
+	_, err = Read(strings.NewReader("{}"), "ini")
+	require.Error(t, err)
+
+	require.Error(t, Write(&bytes.Buffer{}, "ini", map[string]interface{}{}))
+}
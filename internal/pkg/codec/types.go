@@ -0,0 +1,150 @@
+//
+// Copyright (C) 2026 EdgeX Foundry Contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package codec
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/edgexfoundry/go-mod-configuration/v2/internal/pkg/decode"
+	"github.com/edgexfoundry/go-mod-configuration/v2/pkg/types"
+)
+
+// The types of the values recorded in Types, named as in TOML
+const (
+	TypeString  = "string"
+	TypeInteger = "integer"
+	TypeFloat   = "float"
+	TypeBoolean = "boolean"
+	TypeArray   = "array"
+	TypeTable   = "table"
+)
+
+// typesDirectory is the directory, next to the base paths, the Types of the documents imported under them are stored in
+const typesDirectory = ".types"
+
+// ValueType is the type of a value of a document which its raw value doesn't tell
+type ValueType struct {
+	// Type is either TypeString, TypeInteger, TypeFloat, TypeBoolean, TypeArray or TypeTable
+	Type string `json:"type"`
+	// Value is the raw value the Type applies to, empty for the arrays and tables
+	Value string `json:"value,omitempty"`
+}
+
+// Types holds the types of the values of a document which their raw values don't tell, keyed by their '/' separated
+// path: the strings and floats which would be typed back as other types, the empty arrays and tables, which have no
+// raw value, and the tables whose keys are indexes. Along with the raw values, they give back the exact document.
+type Types map[string]ValueType
+
+// TypesOf returns the Types of the normalized document
+func TypesOf(document map[string]interface{}) Types {
+	valueTypes := make(Types)
+	for key, child := range document {
+		addTypes(key, child, valueTypes)
+	}
+	return valueTypes
+}
+
+func addTypes(key string, node interface{}, valueTypes Types) {
+	switch value := node.(type) {
+	case map[string]interface{}:
+		if len(value) == 0 || isIndexed(value) {
+			valueTypes[key] = ValueType{Type: TypeTable}
+		}
+		for childKey, child := range value {
+			addTypes(key+decode.KeyDelimiter+childKey, child, valueTypes)
+		}
+	case []interface{}:
+		if len(value) == 0 {
+			valueTypes[key] = ValueType{Type: TypeArray}
+		}
+		for index, child := range value {
+			addTypes(key+decode.KeyDelimiter+strconv.Itoa(index), child, valueTypes)
+		}
+	default:
+		raw := string(FormatValue(value))
+		if valueType := typeOf(value); valueType != "" && valueType != typeOf(TypedValue(raw)) {
+			valueTypes[key] = ValueType{Type: valueType, Value: raw}
+		}
+	}
+}
+
+// isIndexed checks if the keys of the section are exactly the indexes from 0, which FromValues turns into an array
+func isIndexed(section map[string]interface{}) bool {
+	for index := 0; index < len(section); index++ {
+		if _, exists := section[strconv.Itoa(index)]; !exists {
+			return false
+		}
+	}
+	return len(section) > 0
+}
+
+// typeOf returns the type of the scalar value, empty if it isn't one of the recorded types
+func typeOf(value interface{}) string {
+	switch value.(type) {
+	case string:
+		return TypeString
+	case bool:
+		return TypeBoolean
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return TypeInteger
+	case float32, float64:
+		return TypeFloat
+	default:
+		return ""
+	}
+}
+
+// typed returns the raw value as the type if it applies to it, otherwise as TypedValue types it
+func (valueType ValueType) typed(raw string) interface{} {
+	if valueType.Value == raw {
+		switch valueType.Type {
+		case TypeString:
+			return raw
+		case TypeInteger:
+			if value, err := strconv.ParseInt(raw, 10, 64); err == nil {
+				return value
+			}
+		case TypeFloat:
+			if value, err := strconv.ParseFloat(raw, 64); err == nil {
+				return value
+			}
+		case TypeBoolean:
+			if value, err := strconv.ParseBool(raw); err == nil {
+				return value
+			}
+		}
+	}
+	return TypedValue(raw)
+}
+
+// TypesKey returns the key the Types of the document imported under the base path are stored at. It's kept apart
+// from the base path, i.e. edgex/v2/.types/core-data for edgex/v2/core-data, so the values under the base path are
+// only the ones of the configuration.
+func TypesKey(basePath string) string {
+	dir, name := path.Split(strings.TrimSuffix(basePath, decode.KeyDelimiter))
+	return path.Join(dir, typesDirectory, name)
+}
+
+// MarshalTypes encodes the Types as stored
+func MarshalTypes(valueTypes Types) ([]byte, error) {
+	return json.Marshal(valueTypes)
+}
+
+// UnmarshalTypes decodes the stored Types, none if nothing is stored
+func UnmarshalTypes(data []byte) (Types, error) {
+	valueTypes := make(Types)
+	if len(data) == 0 {
+		return valueTypes, nil
+	}
+	if err := json.Unmarshal(data, &valueTypes); err != nil {
+		return nil, types.NewProviderError(types.ErrDecode, fmt.Errorf("unable to parse the stored types of the configuration: %w", err))
+	}
+	return valueTypes, nil
+}
@@ -18,28 +18,46 @@ package consul
 
 import (
 	"context"
-	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	consulapi "github.com/hashicorp/consul/api"
-	"github.com/mitchellh/consulstructure"
 	"github.com/pelletier/go-toml"
 
+	"github.com/edgexfoundry/go-mod-configuration/v2/internal/pkg/codec"
+	"github.com/edgexfoundry/go-mod-configuration/v2/internal/pkg/decode"
+	"github.com/edgexfoundry/go-mod-configuration/v2/internal/pkg/listing"
+	"github.com/edgexfoundry/go-mod-configuration/v2/internal/pkg/planning"
+	"github.com/edgexfoundry/go-mod-configuration/v2/internal/pkg/retry"
+	"github.com/edgexfoundry/go-mod-configuration/v2/internal/pkg/watch"
 	"github.com/edgexfoundry/go-mod-configuration/v2/pkg/types"
 )
 
 const (
 	consulStatusPath = "/v1/status/leader"
 	aclError         = "Unexpected response code: 403"
+
+	// watchRateLimit is the minimum time between the blocking queries of a watch which haven't returned any change,
+	// so a Consul index going backwards or not advancing can't make the watch spin
+	watchRateLimit = 100 * time.Millisecond
+	// txnMaxOps is the maximum number of operations Consul accepts in a single transaction
+	txnMaxOps = 64
+
+	// watchErrorRetryInterval is the time a watch waits before querying Consul again after an error
+	watchErrorRetryInterval = time.Second
 )
 
 type consulClient struct {
-	consulUrl       string
+	consulUrl string
+	// lock guards the Consul client, which is recreated when the access token is renewed
+	lock            sync.RWMutex
 	consulClient    *consulapi.Client
 	consulConfig    *consulapi.Config
 	configBasePath  string
@@ -47,6 +65,7 @@ type consulClient struct {
 	watchingDone    context.CancelFunc
 	watchingWait    sync.WaitGroup
 	getAccessToken  types.GetAccessTokenCallback
+	retryPolicy     types.RetryPolicy
 }
 
 // NewConsulClient creates a new Consul Client. Service details are optional, not needed just for configuration, but required if registering
@@ -56,6 +75,7 @@ func NewConsulClient(config types.ServiceConfig) (*consulClient, error) {
 		consulUrl:      config.GetUrl(),
 		configBasePath: config.BasePath,
 		getAccessToken: config.GetAccessToken,
+		retryPolicy:    config.Retry,
 	}
 
 	client.watchingDoneCtx, client.watchingDone = context.WithCancel(context.Background())
@@ -69,6 +89,18 @@ func NewConsulClient(config types.ServiceConfig) (*consulClient, error) {
 	client.consulConfig = consulapi.DefaultConfig()
 	client.consulConfig.Token = config.AccessToken
 	client.consulConfig.Address = client.consulUrl
+	if !config.TLS.IsEmpty() {
+		client.consulConfig.TLSConfig = consulapi.TLSConfig{
+			Address:            config.TLS.ServerName,
+			CAFile:             config.TLS.CAFile,
+			CAPem:              config.TLS.CAPem,
+			CertFile:           config.TLS.CertFile,
+			CertPEM:            config.TLS.CertPem,
+			KeyFile:            config.TLS.KeyFile,
+			KeyPEM:             config.TLS.KeyPem,
+			InsecureSkipVerify: config.TLS.InsecureSkipVerify,
+		}
+	}
 	err = client.createConsulClient()
 	if err != nil {
 		return nil, err
@@ -87,15 +119,43 @@ func (client *consulClient) createConsulClient() error {
 	return nil
 }
 
+// kv returns the KV endpoint of the current Consul client
+func (client *consulClient) kv() *consulapi.KV {
+	client.lock.RLock()
+	defer client.lock.RUnlock()
+	return client.consulClient.KV()
+}
+
+// txn returns the transaction endpoint of the current Consul client
+func (client *consulClient) txn() *consulapi.Txn {
+	client.lock.RLock()
+	defer client.lock.RUnlock()
+	return client.consulClient.Txn()
+}
+
 // IsAlive simply checks if Consul is up and running at the configured URL
 func (client *consulClient) IsAlive() bool {
-	netClient := http.Client{Timeout: time.Second * 10}
+	return client.IsAliveCtx(context.Background())
+}
+
+// IsAliveCtx simply checks if Consul is up and running at the configured URL
+func (client *consulClient) IsAliveCtx(ctx context.Context) bool {
+	// Use the transport of the Consul client so the TLS settings also apply to this request
+	netClient := http.Client{Timeout: time.Second * 10, Transport: client.consulConfig.HttpClient.Transport}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, client.consulUrl+consulStatusPath, nil)
+	if err != nil {
+		return false
+	}
 
 	// This REST endpoint doesn't require Access Token, so no need to handle Auth Error.
-	resp, err := netClient.Get(client.consulUrl + consulStatusPath)
+	resp, err := netClient.Do(req)
 	if err != nil {
 		return false
 	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
 
 	if resp.StatusCode >= http.StatusOK && resp.StatusCode < http.StatusMultipleChoices {
 		return true
@@ -106,15 +166,20 @@ func (client *consulClient) IsAlive() bool {
 
 // HasConfiguration checks to see if Consul contains the service's configuration.
 func (client *consulClient) HasConfiguration() (bool, error) {
-	stemKeys, _, err := client.consulClient.KV().Keys(client.configBasePath, "", nil)
-	retry, err := client.reloadAccessTokenOnAuthError(err)
-	if retry {
-		// Try again with new Access Token
-		stemKeys, _, err = client.consulClient.KV().Keys(client.configBasePath, "", nil)
-	}
+	return client.HasConfigurationCtx(context.Background())
+}
+
+// HasConfigurationCtx checks to see if Consul contains the service's configuration.
+func (client *consulClient) HasConfigurationCtx(ctx context.Context) (bool, error) {
+	var stemKeys []string
+	err := client.callWithRetry(ctx, func() error {
+		var err error
+		stemKeys, _, err = client.kv().Keys(client.configBasePath, "", client.queryOptions(ctx))
+		return err
+	})
 
 	if err != nil {
-		return false, fmt.Errorf("checking configuration existence from Consul failed: %v", err)
+		return false, fmt.Errorf("checking configuration existence from Consul failed: %w", err)
 	} else if len(stemKeys) == 0 {
 		return false, nil
 	}
@@ -124,15 +189,20 @@ func (client *consulClient) HasConfiguration() (bool, error) {
 
 // HasSubConfiguration checks to see if the Configuration service contains the service's sub configuration.
 func (client *consulClient) HasSubConfiguration(name string) (bool, error) {
-	stemKeys, _, err := client.consulClient.KV().Keys(client.fullPath(name), "", nil)
-	retry, err := client.reloadAccessTokenOnAuthError(err)
-	if retry {
-		// Try again with new Access Token
-		stemKeys, _, err = client.consulClient.KV().Keys(client.fullPath(name), "", nil)
-	}
+	return client.HasSubConfigurationCtx(context.Background(), name)
+}
+
+// HasSubConfigurationCtx checks to see if the Configuration service contains the service's sub configuration.
+func (client *consulClient) HasSubConfigurationCtx(ctx context.Context, name string) (bool, error) {
+	var stemKeys []string
+	err := client.callWithRetry(ctx, func() error {
+		var err error
+		stemKeys, _, err = client.kv().Keys(client.fullPath(name), "", client.queryOptions(ctx))
+		return err
+	})
 
 	if err != nil {
-		return false, fmt.Errorf("checking sub configuration existence from Consul failed: %v", err)
+		return false, fmt.Errorf("checking sub configuration existence from Consul failed: %w", err)
 	} else if len(stemKeys) == 0 {
 		return false, nil
 	}
@@ -142,25 +212,167 @@ func (client *consulClient) HasSubConfiguration(name string) (bool, error) {
 
 // PutConfigurationToml puts a full toml configuration into Consul
 func (client *consulClient) PutConfigurationToml(configuration *toml.Tree, overwrite bool) error {
+	return client.PutConfigurationTomlCtx(context.Background(), configuration, overwrite)
+}
 
-	configurationMap := configuration.ToMap()
-	keyValues := convertInterfaceToConsulPairs("", configurationMap)
+// PutConfigurationTomlCtx puts a full toml configuration into Consul. The values are written in transactions, each
+// creating a value only if it doesn't exist yet, unless overwrite is set. A transaction failing rolls back the values
+// written by the previous ones, so the configuration is either fully seeded or left unchanged.
+func (client *consulClient) PutConfigurationTomlCtx(ctx context.Context, configuration *toml.Tree, overwrite bool) error {
+	return client.putConfigurationMap(ctx, configuration.ToMap(), overwrite)
+}
 
-	// Put config properties into Consul.
-	for _, keyValue := range keyValues {
-		exists, _ := client.ConfigurationValueExists(keyValue.Key)
-		if !exists || overwrite {
-			if err := client.PutConfigurationValue(keyValue.Key, []byte(keyValue.Value)); err != nil {
-				return err
+// putConfigurationMap puts the configuration values of the map into Consul in transactions
+func (client *consulClient) putConfigurationMap(ctx context.Context, configurationMap map[string]interface{}, overwrite bool) error {
+	values := codec.ToValues("", configurationMap)
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var stored consulapi.KVPairs
+	err := client.callWithRetry(ctx, func() error {
+		var err error
+		stored, _, err = client.kv().List(client.configBasePath, client.queryOptions(ctx))
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("unable to get the configuration %s from Consul: %w", client.configBasePath, err)
+	}
+	previous := make(map[string]*consulapi.KVPair, len(stored))
+	for _, pair := range stored {
+		previous[pair.Key] = pair
+	}
+
+	var ops []*consulapi.KVTxnOp
+	for _, relativeKey := range keys {
+		key := client.fullPath(relativeKey)
+		if _, exists := previous[key]; exists && !overwrite {
+			continue
+		}
+
+		// a CAS with index 0 only creates the value if it still doesn't exist
+		op := &consulapi.KVTxnOp{Verb: consulapi.KVCAS, Key: key, Value: values[relativeKey]}
+		if _, exists := previous[key]; exists {
+			op.Verb = consulapi.KVSet
+		}
+		ops = append(ops, op)
+	}
+
+	return client.putTxn(ctx, ops, previous)
+}
+
+// putTxn runs the operations in transactions of at most txnMaxOps operations, which Consul applies atomically. If
+// a transaction fails, the values written and deleted by the previous ones are restored to their previous values.
+func (client *consulClient) putTxn(ctx context.Context, ops []*consulapi.KVTxnOp, previous map[string]*consulapi.KVPair) error {
+	var written []*consulapi.KVPair
+	var deleted []string
+	for start := 0; start < len(ops); start += txnMaxOps {
+		end := start + txnMaxOps
+		if end > len(ops) {
+			end = len(ops)
+		}
+
+		results, err := client.runTxn(ctx, ops[start:end])
+		if err != nil {
+			if rollbackErr := client.rollback(ctx, written, deleted, previous); rollbackErr != nil {
+				return fmt.Errorf("%w, rolling back the values already written failed: %v", err, rollbackErr)
 			}
+			return err
+		}
+		written = append(written, results...)
+		for _, op := range ops[start:end] {
+			if op.Verb == consulapi.KVDelete || op.Verb == consulapi.KVDeleteCAS {
+				deleted = append(deleted, op.Key)
+			}
+		}
+	}
+
+	return nil
+}
+
+// rollback restores the written values to their previous values, or deletes them if they didn't exist, and creates
+// the deleted values again. The values changed since they have been written or deleted are left as they are.
+func (client *consulClient) rollback(ctx context.Context, written []*consulapi.KVPair, deleted []string, previous map[string]*consulapi.KVPair) error {
+	var ops []*consulapi.KVTxnOp
+	for _, pair := range written {
+		op := &consulapi.KVTxnOp{Verb: consulapi.KVDeleteCAS, Key: pair.Key, Index: pair.ModifyIndex}
+		if previousPair, exists := previous[pair.Key]; exists {
+			op.Verb = consulapi.KVCAS
+			op.Value = previousPair.Value
+			op.Flags = previousPair.Flags
+		}
+		ops = append(ops, op)
+	}
+	for _, key := range deleted {
+		if previousPair, exists := previous[key]; exists {
+			// index 0 only creates the value again if it still doesn't exist
+			ops = append(ops, &consulapi.KVTxnOp{Verb: consulapi.KVCAS, Key: key, Value: previousPair.Value, Flags: previousPair.Flags})
+		}
+	}
+
+	for start := 0; start < len(ops); start += txnMaxOps {
+		end := start + txnMaxOps
+		if end > len(ops) {
+			end = len(ops)
+		}
+		if _, err := client.runTxn(ctx, ops[start:end]); err != nil {
+			return err
 		}
 	}
 
 	return nil
 }
 
+// runTxn runs the operations in a single transaction and returns the written key-value pairs. A transaction rolled
+// back by Consul, because the check of an operation failed, is reported as an error matching types.ErrConflict.
+func (client *consulClient) runTxn(ctx context.Context, ops []*consulapi.KVTxnOp) ([]*consulapi.KVPair, error) {
+	txnOps := make(consulapi.TxnOps, 0, len(ops))
+	for _, op := range ops {
+		txnOps = append(txnOps, &consulapi.TxnOp{KV: op})
+	}
+
+	var ok bool
+	var response *consulapi.TxnResponse
+	err := client.callWithRetry(ctx, func() error {
+		var err error
+		ok, response, _, err = client.txn().Txn(txnOps, client.queryOptions(ctx))
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to put the configuration values into Consul: %w", err)
+	}
+
+	if !ok {
+		// Consul has rolled back the whole transaction
+		var reasons []string
+		for _, txnErr := range response.Errors {
+			if txnErr.OpIndex >= 0 && txnErr.OpIndex < len(ops) {
+				reasons = append(reasons, fmt.Sprintf("%s: %s", ops[txnErr.OpIndex].Key, txnErr.What))
+			} else {
+				reasons = append(reasons, txnErr.What)
+			}
+		}
+		return nil, types.NewProviderError(types.ErrConflict, fmt.Errorf("unable to put the configuration values into Consul, transaction rolled back: %s", strings.Join(reasons, ", ")))
+	}
+
+	var pairs []*consulapi.KVPair
+	for _, result := range response.Results {
+		if result.KV != nil {
+			pairs = append(pairs, result.KV)
+		}
+	}
+	return pairs, nil
+}
+
 // PutConfiguration puts a full configuration struct into the Configuration provider
 func (client *consulClient) PutConfiguration(configuration interface{}, overwrite bool) error {
+	return client.PutConfigurationCtx(context.Background(), configuration, overwrite)
+}
+
+// PutConfigurationCtx puts a full configuration struct into the Configuration provider
+func (client *consulClient) PutConfigurationCtx(ctx context.Context, configuration interface{}, overwrite bool) error {
 	bytes, err := toml.Marshal(configuration)
 	if err != nil {
 		return err
@@ -171,7 +383,7 @@ func (client *consulClient) PutConfiguration(configuration interface{}, overwrit
 		return err
 	}
 
-	err = client.PutConfigurationToml(tree, overwrite)
+	err = client.PutConfigurationTomlCtx(ctx, tree, overwrite)
 	if err != nil {
 		return err
 	}
@@ -184,89 +396,379 @@ func (client *consulClient) PutConfiguration(configuration interface{}, overwrit
 // Passed in struct is only a reference for decoder, empty struct is ok
 // Returns the configuration in the target struct as interface{}, which caller must cast
 func (client *consulClient) GetConfiguration(configStruct interface{}) (interface{}, error) {
-	var err error
-	var configuration interface{}
+	return client.GetConfigurationCtx(context.Background(), configStruct)
+}
+
+// GetConfigurationCtx gets the full configuration from Consul into the target configuration struct.
+// The configuration is read for as long as the context allows.
+func (client *consulClient) GetConfigurationCtx(ctx context.Context, configStruct interface{}) (interface{}, error) {
+	var pairs consulapi.KVPairs
+	err := client.callWithRetry(ctx, func() error {
+		var err error
+		pairs, _, err = client.kv().List(client.configBasePath, client.queryOptions(ctx))
+		return err
+	})
 
-	exists, err := client.HasConfiguration()
 	if err != nil {
+		return nil, fmt.Errorf("unable to get the configuration %s from Consul: %w", client.configBasePath, err)
+	}
+
+	if len(pairs) == 0 {
+		return nil, fmt.Errorf("the Configuration service (Consul) doesn't contain configuration for %s: %w", client.configBasePath, types.ErrNotFound)
+	}
+
+	values := make(map[string][]byte, len(pairs))
+	for _, pair := range pairs {
+		values[pair.Key] = pair.Value
+	}
+	if err := decode.Decode(client.configBasePath, values, configStruct); err != nil {
 		return nil, err
 	}
 
-	if !exists {
-		return nil, fmt.Errorf("the Configuration service (Consul) doesn't contain configuration for %s", client.configBasePath)
+	return configStruct, nil
+}
+
+// parseModifyIndex returns the ModifyIndex of the revision of a stored value
+func parseModifyIndex(revision types.Revision) (uint64, error) {
+	modifyIndex, err := strconv.ParseUint(string(revision), 10, 64)
+	// index 0 would create the value rather than check its revision
+	if err != nil || modifyIndex == 0 {
+		return 0, fmt.Errorf("invalid revision %q", revision)
 	}
+	return modifyIndex, nil
+}
 
-	// Update configuration data from Consul using decoder
-	updateChannel := make(chan interface{})
-	errorChannel := make(chan error)
+// PlanConfiguration compares the desired configuration with the configuration stored in Consul
+func (client *consulClient) PlanConfiguration(desired interface{}) (types.Plan, error) {
+	return client.PlanConfigurationCtx(context.Background(), desired)
+}
 
-	decoder := client.newConsulDecoder()
-	decoder.Consul = client.consulConfig
-	decoder.Target = configStruct
-	decoder.Prefix = client.configBasePath
-	decoder.ErrCh = errorChannel
-	decoder.UpdateCh = updateChannel
+// PlanConfigurationCtx compares the desired configuration, either a struct, a map or a *toml.Tree, with the
+// configuration stored in Consul and returns the plan of the values to add, modify and remove. The desired values
+// are formatted the same way as PutConfiguration formats them, and the plan is checked against the ModifyIndex of
+// the stored values.
+func (client *consulClient) PlanConfigurationCtx(ctx context.Context, desired interface{}) (types.Plan, error) {
+	configurationMap, err := codec.ToMap(desired)
+	if err != nil {
+		return types.Plan{}, err
+	}
+	desiredValues := codec.ToValues("", configurationMap)
 
-	defer func() {
-		_ = decoder.Close()
-		close(updateChannel)
-		close(errorChannel)
-	}()
+	var pairs consulapi.KVPairs
+	err = client.callWithRetry(ctx, func() error {
+		var err error
+		pairs, _, err = client.kv().List(client.configBasePath, client.queryOptions(ctx))
+		return err
+	})
+	if err != nil {
+		return types.Plan{}, fmt.Errorf("unable to get the configuration %s from Consul: %w", client.configBasePath, err)
+	}
 
-	go decoder.Run()
+	lister := listing.NewLister(client.configBasePath, "", types.NewListOptions())
+	storedValues := make(map[string][]byte, len(pairs))
+	modifyIndexes := make(map[string]uint64, len(pairs))
+	for _, pair := range pairs {
+		if key, isValue, ok := lister.Key(pair.Key); ok && isValue {
+			storedValues[key] = pair.Value
+			modifyIndexes[key] = pair.ModifyIndex
+		}
+	}
 
-	select {
-	case <-time.After(2 * time.Second):
-		err = errors.New("timeout loading config from client")
-	case ex := <-errorChannel:
-		err = errors.New(ex.Error())
-	case raw := <-updateChannel:
-		configuration = raw
+	return planning.New(storedValues, desiredValues, func(key string, _ []byte) types.Revision {
+		return types.Revision(strconv.FormatUint(modifyIndexes[key], 10))
+	}), nil
+}
+
+// ApplyPlan executes exactly the changes of the plan in Consul
+func (client *consulClient) ApplyPlan(plan types.Plan) error {
+	return client.ApplyPlanCtx(context.Background(), plan)
+}
+
+// ApplyPlanCtx executes exactly the changes of the plan in Consul, in transactions of check-and-set operations on the
+// ModifyIndex of the values the plan was made against. A transaction failing because a value has been changed since
+// rolls back the previous ones, so the plan is either fully applied or not at all.
+func (client *consulClient) ApplyPlanCtx(ctx context.Context, plan types.Plan) error {
+	var ops []*consulapi.KVTxnOp
+	previous := make(map[string]*consulapi.KVPair)
+	for _, change := range plan.Changes.Added {
+		// index 0 only creates the value if it still doesn't exist
+		ops = append(ops, &consulapi.KVTxnOp{Verb: consulapi.KVCAS, Key: client.fullPath(change.Key), Value: change.NewValue})
+	}
+	verbs := map[consulapi.KVOp][]types.KeyChange{consulapi.KVCAS: plan.Changes.Modified, consulapi.KVDeleteCAS: plan.Changes.Removed}
+	for _, verb := range []consulapi.KVOp{consulapi.KVCAS, consulapi.KVDeleteCAS} {
+		for _, change := range verbs[verb] {
+			modifyIndex, err := parseModifyIndex(plan.Revisions[change.Key])
+			if err != nil {
+				return fmt.Errorf("unable to apply the plan to %s: %w", client.fullPath(change.Key), err)
+			}
+
+			key := client.fullPath(change.Key)
+			ops = append(ops, &consulapi.KVTxnOp{Verb: verb, Key: key, Value: change.NewValue, Index: modifyIndex})
+			previous[key] = &consulapi.KVPair{Key: key, Value: change.OldValue, ModifyIndex: modifyIndex}
+		}
+	}
+
+	return client.putTxn(ctx, ops, previous)
+}
+
+// ExportConfiguration writes the configuration from Consul to w as a document in the format
+func (client *consulClient) ExportConfiguration(w io.Writer, format types.ConfigurationFormat) error {
+	return client.ExportConfigurationCtx(context.Background(), w, format)
+}
+
+// ExportConfigurationCtx writes the configuration from Consul to w as a document in the format. Consul stores raw
+// values, which are typed back by the types stored along with the last imported document, so it's exported exactly as
+// it was imported.
+func (client *consulClient) ExportConfigurationCtx(ctx context.Context, w io.Writer, format types.ConfigurationFormat) error {
+	values, err := client.GetConfigurationValuesCtx(ctx, "")
+	if err != nil {
+		return err
+	}
+	if len(values) == 0 {
+		return fmt.Errorf("the Configuration service (Consul) doesn't contain configuration for %s: %w", client.configBasePath, types.ErrNotFound)
+	}
+
+	var keyPair *consulapi.KVPair
+	err = client.callWithRetry(ctx, func() error {
+		var err error
+		keyPair, _, err = client.kv().Get(codec.TypesKey(client.configBasePath), client.queryOptions(ctx))
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("unable to get the types of the configuration from Consul: %w", err)
+	}
+	var stored []byte
+	if keyPair != nil {
+		stored = keyPair.Value
+	}
+	valueTypes, err := codec.UnmarshalTypes(stored)
+	if err != nil {
+		return err
+	}
+
+	return codec.Write(w, format, codec.FromValues(values, valueTypes))
+}
+
+// ImportConfiguration puts the configuration document in the format read from r into Consul
+func (client *consulClient) ImportConfiguration(r io.Reader, format types.ConfigurationFormat, overwrite bool) error {
+	return client.ImportConfigurationCtx(context.Background(), r, format, overwrite)
+}
+
+// ImportConfigurationCtx puts the configuration document in the format read from r into Consul, in transactions the
+// same way as PutConfigurationToml, then stores the types its raw values don't tell for ExportConfiguration
+func (client *consulClient) ImportConfigurationCtx(ctx context.Context, r io.Reader, format types.ConfigurationFormat, overwrite bool) error {
+	configurationMap, err := codec.Read(r, format)
+	if err != nil {
+		return err
+	}
+
+	if err := client.putConfigurationMap(ctx, configurationMap, overwrite); err != nil {
+		return err
 	}
 
-	return configuration, err
+	stored, err := codec.MarshalTypes(codec.TypesOf(configurationMap))
+	if err != nil {
+		return err
+	}
+	keyPair := &consulapi.KVPair{
+		Key:   codec.TypesKey(client.configBasePath),
+		Value: stored,
+	}
+	err = client.callWithRetry(ctx, func() error {
+		_, err := client.kv().Put(keyPair, client.writeOptions(ctx))
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("unable to put the types of the configuration into Consul: %w", err)
+	}
+	return nil
 }
 
 // WatchForChanges sets up a Consul watch for the target key and send back updates on the update channel.
 // Passed in struct is only a reference for decoder, empty struct is ok
 // Sends the configuration in the target struct as interface{} on updateChannel, which caller must cast
-func (client *consulClient) WatchForChanges(updateChannel chan<- interface{}, errorChannel chan<- error, configuration interface{}, watchKey string) {
+func (client *consulClient) WatchForChanges(updateChannel chan<- interface{}, errorChannel chan<- error, configuration interface{}, watchKey string, options ...types.WatchOption) types.WatchHandle {
+	return client.WatchForChangesCtx(context.Background(), updateChannel, errorChannel, configuration, watchKey, options...)
+}
+
+// WatchForChangesCtx sets up a Consul watch for the target key and send back updates on the update channel.
+// The current configuration is sent first, then each time a value under the key is written.
+// The watch stops when either the context is done, it is stopped through the returned handle or StopWatching is
+// called.
+func (client *consulClient) WatchForChangesCtx(ctx context.Context, updateChannel chan<- interface{}, errorChannel chan<- error, configuration interface{}, watchKey string, options ...types.WatchOption) types.WatchHandle {
+	return client.watch(ctx, errorChannel, configuration, watchKey, types.NewWatchOptions(options...), func(ctx context.Context, update types.WatchUpdate) bool {
+		select {
+		case updateChannel <- update.Configuration:
+			return true
+		case <-ctx.Done():
+		}
+		return false
+	})
+}
+
+// WatchForChangeSets sets up a Consul watch for the target key and sends back an update on the update channel each
+// time the configuration under it changes, along with the values which have been added, modified or removed.
+// Passed in struct is only a reference for decoder, empty struct is ok
+func (client *consulClient) WatchForChangeSets(updateChannel chan<- types.WatchUpdate, errorChannel chan<- error, configuration interface{}, watchKey string, options ...types.WatchOption) types.WatchHandle {
+	return client.WatchForChangeSetsCtx(context.Background(), updateChannel, errorChannel, configuration, watchKey, options...)
+}
+
+// WatchForChangeSetsCtx sets up a Consul watch for the target key and sends back an update on the update channel
+// each time the configuration under it changes, along with the values which have been added, modified or removed.
+// The changes are found from the ModifyIndex of the values, so writing a value again, even unchanged, reports it as
+// modified. The current configuration is sent first, with all its values added.
+// The watch stops when either the context is done, it is stopped through the returned handle or StopWatching is
+// called.
+func (client *consulClient) WatchForChangeSetsCtx(ctx context.Context, updateChannel chan<- types.WatchUpdate, errorChannel chan<- error, configuration interface{}, watchKey string, options ...types.WatchOption) types.WatchHandle {
+	return client.watch(ctx, errorChannel, configuration, watchKey, types.NewWatchOptions(options...), func(ctx context.Context, update types.WatchUpdate) bool {
+		select {
+		case updateChannel <- update:
+			return true
+		case <-ctx.Done():
+		}
+		return false
+	})
+}
+
+// watch runs the blocking query loop of a watch with the configured Consul client, so its TLS settings and access
+// token apply. The configuration under the watch key is decoded into a new struct of the same type as the passed in
+// struct and delivered along with the raw values which have changed, the current configuration first.
+func (client *consulClient) watch(ctx context.Context, errorChannel chan<- error, configuration interface{}, watchKey string, options types.WatchOptions, deliver watch.DeliverFunc) types.WatchHandle {
 	// some watch keys may have start with "/", need to remove it since the base path already has it.
 	if strings.Index(watchKey, "/") == 0 {
 		watchKey = watchKey[1:]
 	}
+	prefix := client.configBasePath + watchKey
+	targetType := reflect.TypeOf(configuration)
+	if targetType.Kind() == reflect.Ptr {
+		targetType = targetType.Elem()
+	}
 
-	errs := make(chan error)
-	decoder := client.newConsulDecoder()
-	decoder.Consul = client.consulConfig
-	decoder.Target = configuration
-	decoder.Prefix = client.configBasePath + watchKey
-	decoder.ErrCh = errs
-	decoder.UpdateCh = updateChannel
+	watchCtx, handle := watch.NewHandle(ctx)
 
-	go decoder.Run()
 	client.watchingWait.Add(1)
+	go func() {
+		// the blocking query is canceled through the watch context, which StopWatching must cancel as well
+		select {
+		case <-client.watchingDoneCtx.Done():
+			handle.Cancel()
+		case <-watchCtx.Done():
+		}
+	}()
 
 	go func() {
-		for {
+		defer client.watchingWait.Done()
+		defer handle.SetStopped()
+
+		// the updates are coalesced over the debounce window of the watch, if set
+		deliver, stopDelivering := watch.Debounce(watchCtx, options.Debounce, deliver)
+		defer stopDelivering()
+
+		wait := func(delay time.Duration) bool {
+			timer := time.NewTimer(delay)
+			defer timer.Stop()
 			select {
-			case <-client.watchingDoneCtx.Done():
-				_ = decoder.Close() // Func always return nil for error so ignoring the return value
-				client.watchingWait.Done()
+			case <-timer.C:
+				return true
+			case <-watchCtx.Done():
+				return false
+			}
+		}
+
+		var waitIndex uint64
+		var previous map[string]*consulapi.KVPair
+		first := true
+		for {
+			var pairs consulapi.KVPairs
+			var meta *consulapi.QueryMeta
+			err := client.callWithRetry(watchCtx, func() error {
+				var err error
+				options := client.queryOptions(watchCtx)
+				options.WaitIndex = waitIndex
+				pairs, meta, err = client.kv().List(prefix, options)
+				return err
+			})
+			if watchCtx.Err() != nil {
 				return
+			}
+			if err != nil {
+				select {
+				case errorChannel <- fmt.Errorf("unable to watch %s in Consul: %w", prefix, err):
+				case <-watchCtx.Done():
+					return
+				}
+				if !wait(watchErrorRetryInterval) {
+					return
+				}
+				continue
+			}
 
-			case err := <-errs:
-				retry, err := client.reloadAccessTokenOnAuthError(err)
-				if retry {
-					_ = decoder.Close() // Func always return nil for error so ignoring the return value
-					decoder.Consul = client.consulConfig
-					go decoder.Run()
-				} else {
-					errorChannel <- err
+			advanced := meta != nil && meta.LastIndex > waitIndex
+			if meta != nil {
+				// the index must be reset when it goes backwards, i.e. after the Consul data has been restored
+				waitIndex = meta.LastIndex
+			}
+			// the changes made from now on advance the index past the one the next query waits for
+			handle.SetReady()
+
+			current := make(map[string]*consulapi.KVPair, len(pairs))
+			values := make(map[string][]byte, len(pairs))
+			for _, pair := range pairs {
+				// keys with the same prefix which aren't under the watched key, i.e. Writable2 when watching Writable
+				relativeKey := strings.TrimPrefix(pair.Key, prefix)
+				if watchKey != "" && relativeKey != "" && !strings.HasPrefix(relativeKey, "/") {
+					continue
+				}
+				relativeKey = strings.TrimPrefix(relativeKey, "/")
+				current[relativeKey] = pair
+				values[relativeKey] = pair.Value
+			}
+
+			changes := changeSet(previous, current)
+			if !first && changes.IsEmpty() {
+				if !advanced && !wait(watchRateLimit) {
+					return
+				}
+				continue
+			}
+
+			update := types.WatchUpdate{Configuration: reflect.New(targetType).Interface(), Changes: changes}
+			if err := decode.Decode("", values, update.Configuration); err != nil {
+				select {
+				case errorChannel <- err:
+				case <-watchCtx.Done():
+					return
 				}
+				continue
+			}
+			first = false
+			previous = current
+
+			if !deliver(watchCtx, update) {
+				return
 			}
 		}
 	}()
+
+	return handle
+}
+
+// changeSet finds the changes between the values from the ModifyIndex of the values
+func changeSet(previous map[string]*consulapi.KVPair, current map[string]*consulapi.KVPair) types.ChangeSet {
+	previousValues := make(map[string][]byte, len(previous))
+	for key, pair := range previous {
+		previousValues[key] = pair.Value
+	}
+	currentValues := make(map[string][]byte, len(current))
+	touched := make(map[string]bool)
+	for key, pair := range current {
+		currentValues[key] = pair.Value
+		if previousPair, exists := previous[key]; exists && previousPair.ModifyIndex != pair.ModifyIndex {
+			touched[key] = true
+		}
+	}
+
+	return watch.NewChangeSet(previousValues, currentValues, touched)
 }
 
 // StopWatching causes all WatchForChanges processing to stop and waits until they have exited.
@@ -275,18 +777,41 @@ func (client *consulClient) StopWatching() {
 	client.watchingWait.Wait()
 }
 
+// StopWatchingCtx causes all WatchForChanges processing to stop and waits until they have exited
+// or the context is done.
+func (client *consulClient) StopWatchingCtx(ctx context.Context) error {
+	client.watchingDone()
+
+	stopped := make(chan struct{})
+	go func() {
+		client.watchingWait.Wait()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 // ConfigurationValueExists checks if a configuration value exists in Consul
 func (client *consulClient) ConfigurationValueExists(name string) (bool, error) {
-	keyPair, _, err := client.consulClient.KV().Get(client.fullPath(name), nil)
+	return client.ConfigurationValueExistsCtx(context.Background(), name)
+}
 
-	retry, err := client.reloadAccessTokenOnAuthError(err)
-	if retry {
-		// Try again with new Access Token
-		keyPair, _, err = client.consulClient.KV().Get(client.fullPath(name), nil)
-	}
+// ConfigurationValueExistsCtx checks if a configuration value exists in Consul
+func (client *consulClient) ConfigurationValueExistsCtx(ctx context.Context, name string) (bool, error) {
+	var keyPair *consulapi.KVPair
+	err := client.callWithRetry(ctx, func() error {
+		var err error
+		keyPair, _, err = client.kv().Get(client.fullPath(name), client.queryOptions(ctx))
+		return err
+	})
 
 	if err != nil {
-		return false, fmt.Errorf("unable to check existence of %s in Consul: %v", client.fullPath(name), err)
+		return false, fmt.Errorf("unable to check existence of %s in Consul: %w", client.fullPath(name), err)
 	}
 
 	return keyPair != nil, nil
@@ -294,16 +819,20 @@ func (client *consulClient) ConfigurationValueExists(name string) (bool, error)
 
 // GetConfigurationValue gets a specific configuration value from Consul
 func (client *consulClient) GetConfigurationValue(name string) ([]byte, error) {
-	keyPair, _, err := client.consulClient.KV().Get(client.fullPath(name), nil)
+	return client.GetConfigurationValueCtx(context.Background(), name)
+}
 
-	retry, err := client.reloadAccessTokenOnAuthError(err)
-	if retry {
-		// Try again with new Access Token
-		keyPair, _, err = client.consulClient.KV().Get(client.fullPath(name), nil)
-	}
+// GetConfigurationValueCtx gets a specific configuration value from Consul
+func (client *consulClient) GetConfigurationValueCtx(ctx context.Context, name string) ([]byte, error) {
+	var keyPair *consulapi.KVPair
+	err := client.callWithRetry(ctx, func() error {
+		var err error
+		keyPair, _, err = client.kv().Get(client.fullPath(name), client.queryOptions(ctx))
+		return err
+	})
 
 	if err != nil {
-		return nil, fmt.Errorf("unable to get value for %s from Consul: %v", client.fullPath(name), err)
+		return nil, fmt.Errorf("unable to get value for %s from Consul: %w", client.fullPath(name), err)
 	}
 
 	if keyPair == nil {
@@ -313,28 +842,210 @@ func (client *consulClient) GetConfigurationValue(name string) ([]byte, error) {
 	return keyPair.Value, nil
 }
 
+// ListConfigurationKeys lists the keys of the configuration values under the prefix from Consul
+func (client *consulClient) ListConfigurationKeys(prefix string, options ...types.ListOption) ([]string, error) {
+	return client.ListConfigurationKeysCtx(context.Background(), prefix, options...)
+}
+
+// ListConfigurationKeysCtx lists the keys of the configuration values under the prefix from Consul, sorted and
+// relative to the base path
+func (client *consulClient) ListConfigurationKeysCtx(ctx context.Context, prefix string, options ...types.ListOption) ([]string, error) {
+	lister := listing.NewLister(client.configBasePath, prefix, types.NewListOptions(options...))
+
+	var keys []string
+	err := client.callWithRetry(ctx, func() error {
+		var err error
+		keys, _, err = client.kv().Keys(lister.Prefix(), "", client.queryOptions(ctx))
+		return err
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("unable to list keys under %s from Consul: %w", lister.Prefix(), err)
+	}
+
+	return lister.Keys(keys), nil
+}
+
+// GetConfigurationValues gets the configuration values under the prefix from Consul
+func (client *consulClient) GetConfigurationValues(prefix string, options ...types.ListOption) (map[string][]byte, error) {
+	return client.GetConfigurationValuesCtx(context.Background(), prefix, options...)
+}
+
+// GetConfigurationValuesCtx gets the configuration values under the prefix from Consul, keyed relative to the base
+// path
+func (client *consulClient) GetConfigurationValuesCtx(ctx context.Context, prefix string, options ...types.ListOption) (map[string][]byte, error) {
+	lister := listing.NewLister(client.configBasePath, prefix, types.NewListOptions(options...))
+
+	var pairs consulapi.KVPairs
+	err := client.callWithRetry(ctx, func() error {
+		var err error
+		pairs, _, err = client.kv().List(lister.Prefix(), client.queryOptions(ctx))
+		return err
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("unable to get values under %s from Consul: %w", lister.Prefix(), err)
+	}
+
+	values := make(map[string][]byte, len(pairs))
+	for _, pair := range pairs {
+		values[pair.Key] = pair.Value
+	}
+	return lister.Values(values), nil
+}
+
 // PutConfigurationValue puts a specific configuration value into Consul
 func (client *consulClient) PutConfigurationValue(name string, value []byte) error {
+	return client.PutConfigurationValueCtx(context.Background(), name, value)
+}
+
+// PutConfigurationValueCtx puts a specific configuration value into Consul
+func (client *consulClient) PutConfigurationValueCtx(ctx context.Context, name string, value []byte) error {
 	keyPair := &consulapi.KVPair{
 		Key:   client.fullPath(name),
 		Value: value,
 	}
 
-	_, err := client.consulClient.KV().Put(keyPair, nil)
+	err := client.callWithRetry(ctx, func() error {
+		_, err := client.kv().Put(keyPair, client.writeOptions(ctx))
+		return err
+	})
 
-	retry, err := client.reloadAccessTokenOnAuthError(err)
-	if retry {
-		// Try again with new Access Token
-		_, err = client.consulClient.KV().Put(keyPair, nil)
+	if err != nil {
+		return fmt.Errorf("unable to put value for %s into Consul: %w", client.fullPath(name), err)
+	}
+
+	return nil
+}
+
+// GetConfigurationValueWithVersion gets a specific configuration value from Consul along with its revision
+func (client *consulClient) GetConfigurationValueWithVersion(name string) ([]byte, types.Revision, error) {
+	return client.GetConfigurationValueWithVersionCtx(context.Background(), name)
+}
+
+// GetConfigurationValueWithVersionCtx gets a specific configuration value from Consul along with its revision, which
+// is the ModifyIndex of the value. Returns nil and types.NoRevision without an error if the value doesn't exist.
+func (client *consulClient) GetConfigurationValueWithVersionCtx(ctx context.Context, name string) ([]byte, types.Revision, error) {
+	var keyPair *consulapi.KVPair
+	err := client.callWithRetry(ctx, func() error {
+		var err error
+		keyPair, _, err = client.kv().Get(client.fullPath(name), client.queryOptions(ctx))
+		return err
+	})
+
+	if err != nil {
+		return nil, types.NoRevision, fmt.Errorf("unable to get value for %s from Consul: %w", client.fullPath(name), err)
+	}
+
+	if keyPair == nil {
+		return nil, types.NoRevision, nil
+	}
+
+	return keyPair.Value, types.Revision(strconv.FormatUint(keyPair.ModifyIndex, 10)), nil
+}
+
+// PutConfigurationValueCAS puts a specific configuration value into Consul only if its revision is still the given one
+func (client *consulClient) PutConfigurationValueCAS(name string, value []byte, revision types.Revision) error {
+	return client.PutConfigurationValueCASCtx(context.Background(), name, value, revision)
+}
+
+// PutConfigurationValueCASCtx puts a specific configuration value into Consul only if its revision is still the given
+// one, using a check-and-set on the ModifyIndex of the value. types.NoRevision only creates the value if it doesn't
+// exist.
+func (client *consulClient) PutConfigurationValueCASCtx(ctx context.Context, name string, value []byte, revision types.Revision) error {
+	var modifyIndex uint64
+	if revision != types.NoRevision {
+		var err error
+		if modifyIndex, err = parseModifyIndex(revision); err != nil {
+			return fmt.Errorf("%w for %s", err, client.fullPath(name))
+		}
+	}
+
+	keyPair := &consulapi.KVPair{
+		Key:         client.fullPath(name),
+		Value:       value,
+		ModifyIndex: modifyIndex,
+	}
+
+	var swapped bool
+	err := client.callWithRetry(ctx, func() error {
+		var err error
+		swapped, _, err = client.kv().CAS(keyPair, client.writeOptions(ctx))
+		return err
+	})
+
+	if err != nil {
+		return fmt.Errorf("unable to put value for %s into Consul: %w", client.fullPath(name), err)
+	}
+
+	if !swapped {
+		return types.NewProviderError(types.ErrConflict, fmt.Errorf("unable to put value for %s into Consul: revision %q is stale", client.fullPath(name), revision))
+	}
+
+	return nil
+}
+
+// DeleteConfigurationValue deletes a specific configuration value from Consul
+func (client *consulClient) DeleteConfigurationValue(name string) error {
+	return client.DeleteConfigurationValueCtx(context.Background(), name)
+}
+
+// DeleteConfigurationValueCtx deletes a specific configuration value from Consul
+func (client *consulClient) DeleteConfigurationValueCtx(ctx context.Context, name string) error {
+	err := client.callWithRetry(ctx, func() error {
+		_, err := client.kv().Delete(client.fullPath(name), client.writeOptions(ctx))
+		return err
+	})
+
+	if err != nil {
+		return fmt.Errorf("unable to delete value for %s from Consul: %w", client.fullPath(name), err)
 	}
 
+	return nil
+}
+
+// DeleteSubConfiguration deletes all configuration values under the sub configuration from Consul
+func (client *consulClient) DeleteSubConfiguration(name string) error {
+	return client.DeleteSubConfigurationCtx(context.Background(), name)
+}
+
+// DeleteSubConfigurationCtx deletes all configuration values under the sub configuration from Consul
+func (client *consulClient) DeleteSubConfigurationCtx(ctx context.Context, name string) error {
+	prefix := client.fullPath(name)
+	// only delete the keys of the section, i.e. Writable must not delete WritableX
+	if len(prefix) > 0 && !strings.HasSuffix(prefix, "/") {
+		prefix = prefix + "/"
+	}
+
+	err := client.callWithRetry(ctx, func() error {
+		_, err := client.kv().DeleteTree(prefix, client.writeOptions(ctx))
+		return err
+	})
+
 	if err != nil {
-		return fmt.Errorf("unable to put value for %s into Consul: %v", client.fullPath(name), err)
+		return fmt.Errorf("unable to delete sub configuration %s from Consul: %w", prefix, err)
 	}
 
 	return nil
 }
 
+// callWithRetry calls fn according to the retry policy while it fails with a retryable error. If fn is rejected because
+// of the ACL, the access token is renewed and fn is called once more. The returned error is classified with wrapError.
+func (client *consulClient) callWithRetry(ctx context.Context, fn func() error) error {
+	call := func() error {
+		return wrapError(fn())
+	}
+
+	err := retry.Do(ctx, client.retryPolicy, call)
+	reload, err := client.reloadAccessTokenOnAuthError(err)
+	if reload {
+		// Try again with new Access Token
+		err = retry.Do(ctx, client.retryPolicy, call)
+	}
+
+	return err
+}
+
 func (client *consulClient) reloadAccessTokenOnAuthError(err error) (bool, error) {
 	if err == nil {
 		return false, nil
@@ -343,10 +1054,12 @@ func (client *consulClient) reloadAccessTokenOnAuthError(err error) (bool, error
 	if strings.Contains(err.Error(), aclError) && client.getAccessToken != nil {
 		newToken, err := client.getAccessToken()
 		if err != nil {
-			err = fmt.Errorf("failed to renew access token: %s", err.Error())
+			err = types.NewProviderError(types.ErrUnauthorized, fmt.Errorf("failed to renew access token: %s", err.Error()))
 			return false, err
 		}
 
+		client.lock.Lock()
+		defer client.lock.Unlock()
 		client.consulConfig.Token = newToken
 
 		// Have to recreate the consul client with the new Access Token
@@ -365,59 +1078,12 @@ func (client *consulClient) fullPath(name string) string {
 	return client.configBasePath + name
 }
 
-type pair struct {
-	Key   string
-	Value string
+// queryOptions returns the options that bind a Consul read request to the context
+func (client *consulClient) queryOptions(ctx context.Context) *consulapi.QueryOptions {
+	return (&consulapi.QueryOptions{}).WithContext(ctx)
 }
 
-func convertInterfaceToConsulPairs(path string, interfaceMap interface{}) []*pair {
-	pairs := make([]*pair, 0)
-
-	pathPre := ""
-	if path != "" {
-		pathPre = path + "/"
-	}
-
-	switch value := interfaceMap.(type) {
-	case []interface{}:
-		for index, item := range value {
-			nextPairs := convertInterfaceToConsulPairs(pathPre+strconv.Itoa(index), item)
-			pairs = append(pairs, nextPairs...)
-		}
-
-	case map[string]interface{}:
-		for index, item := range value {
-			nextPairs := convertInterfaceToConsulPairs(pathPre+index, item)
-			pairs = append(pairs, nextPairs...)
-		}
-
-	case int:
-		pairs = append(pairs, &pair{Key: path, Value: strconv.Itoa(value)})
-
-	case int64:
-		var value64 = int(value)
-		pairs = append(pairs, &pair{Key: path, Value: strconv.Itoa(value64)})
-
-	case float64:
-		pairs = append(pairs, &pair{Key: path, Value: strconv.FormatFloat(value, 'f', -1, 64)})
-
-	case bool:
-		pairs = append(pairs, &pair{Key: path, Value: strconv.FormatBool(value)})
-
-	case nil:
-		pairs = append(pairs, &pair{Key: path, Value: ""})
-
-	default:
-		pairs = append(pairs, &pair{Key: path, Value: value.(string)})
-	}
-
-	return pairs
-}
-
-func (client *consulClient) newConsulDecoder() *consulstructure.Decoder {
-	return &consulstructure.Decoder{
-		Consul: &consulapi.Config{
-			Address: client.consulUrl,
-		},
-	}
+// writeOptions returns the options that bind a Consul write request to the context
+func (client *consulClient) writeOptions(ctx context.Context) *consulapi.WriteOptions {
+	return (&consulapi.WriteOptions{}).WithContext(ctx)
 }
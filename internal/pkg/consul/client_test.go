@@ -17,8 +17,13 @@
 package consul
 
 import (
+	"bytes"
+	"context"
+	"encoding/pem"
+	"errors"
 	"fmt"
 	"log"
+	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"os"
@@ -33,6 +38,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/edgexfoundry/go-mod-configuration/v2/internal/pkg/codec"
 	"github.com/edgexfoundry/go-mod-configuration/v2/pkg/types"
 )
 
@@ -309,6 +315,330 @@ func TestPutConfigurationValue(t *testing.T) {
 
 }
 
+func TestPutConfigurationValueCAS(t *testing.T) {
+	key := "Writable/LogLevel"
+	client := makeConsulClient(t, getUniqueServiceName(), "", nil)
+	reset(t, client)
+
+	value, revision, err := client.GetConfigurationValueWithVersion(key)
+	require.NoError(t, err)
+	assert.Nil(t, value)
+	assert.Equal(t, types.NoRevision, revision)
+
+	// no revision only creates the value
+	require.NoError(t, client.PutConfigurationValueCAS(key, []byte("INFO"), types.NoRevision))
+	err = client.PutConfigurationValueCAS(key, []byte("TRACE"), types.NoRevision)
+	assert.True(t, errors.Is(err, types.ErrConflict), "unexpected error: %v", err)
+
+	value, revision, err = client.GetConfigurationValueWithVersion(key)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("INFO"), value)
+	assert.NotEqual(t, types.NoRevision, revision)
+
+	// a concurrent write makes the revision stale
+	require.NoError(t, client.PutConfigurationValue(key, []byte("WARN")))
+	err = client.PutConfigurationValueCAS(key, []byte("DEBUG"), revision)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, types.ErrConflict), "unexpected error: %v", err)
+	var providerErr *types.ProviderError
+	assert.True(t, errors.As(err, &providerErr))
+
+	_, revision, err = client.GetConfigurationValueWithVersion(key)
+	require.NoError(t, err)
+	require.NoError(t, client.PutConfigurationValueCAS(key, []byte("DEBUG"), revision))
+	value, err = client.GetConfigurationValue(key)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("DEBUG"), value)
+
+	err = client.PutConfigurationValueCAS(key, []byte("ERROR"), "not a revision")
+	require.Error(t, err)
+	assert.False(t, errors.Is(err, types.ErrConflict))
+}
+
+func TestListConfigurationKeys(t *testing.T) {
+	client := makeConsulClient(t, getUniqueServiceName(), "", nil)
+	reset(t, client)
+
+	keys, err := client.ListConfigurationKeys("Writable")
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+
+	require.NoError(t, client.PutConfigurationValue("Writable/LogLevel", []byte("INFO")))
+	require.NoError(t, client.PutConfigurationValue("Writable/InsecureSecrets/DB/Path", []byte("redisdb")))
+	require.NoError(t, client.PutConfigurationValue("WritableX/LogLevel", []byte("DEBUG")))
+
+	keys, err = client.ListConfigurationKeys("Writable")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Writable/InsecureSecrets/DB/Path", "Writable/LogLevel"}, keys)
+
+	keys, err = client.ListConfigurationKeys("", types.WithDepth(2), types.WithSeparator("."))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Writable.InsecureSecrets.", "Writable.LogLevel", "WritableX.LogLevel"}, keys)
+
+	values, err := client.GetConfigurationValues("Writable", types.WithDepth(1))
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]byte{"Writable/LogLevel": []byte("INFO")}, values)
+
+	values, err = client.GetConfigurationValues("Writable")
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]byte{
+		"Writable/LogLevel":                []byte("INFO"),
+		"Writable/InsecureSecrets/DB/Path": []byte("redisdb"),
+	}, values)
+}
+
+const importedToml = `
+Host = "localhost"
+Port = 59880
+Temp = 36.6
+Enabled = true
+Empty = ""
+Topics = ["events", "commands"]
+
+[[Routes]]
+  Path = "/api"
+  Port = 1
+
+[Writable]
+  LogLevel = "INFO"
+`
+
+func TestExportImportConfiguration(t *testing.T) {
+	source := makeConsulClient(t, getUniqueServiceName(), "", nil)
+	reset(t, source)
+	target := makeConsulClient(t, getUniqueServiceName(), "", nil)
+	reset(t, target)
+
+	err := source.ExportConfiguration(&bytes.Buffer{}, types.FormatTOML)
+	assert.True(t, errors.Is(err, types.ErrNotFound), "unexpected error: %v", err)
+
+	require.NoError(t, source.ImportConfiguration(strings.NewReader(importedToml), types.FormatTOML, false))
+	var exported bytes.Buffer
+	require.NoError(t, source.ExportConfiguration(&exported, types.FormatYAML))
+
+	// the exported document is restored exactly, with the same raw values
+	require.NoError(t, target.ImportConfiguration(bytes.NewReader(exported.Bytes()), types.FormatYAML, false))
+	var restored bytes.Buffer
+	require.NoError(t, target.ExportConfiguration(&restored, types.FormatYAML))
+	assert.Equal(t, exported.String(), restored.String())
+	sourceValues, err := source.GetConfigurationValues("")
+	require.NoError(t, err)
+	targetValues, err := target.GetConfigurationValues("")
+	require.NoError(t, err)
+	assert.Equal(t, sourceValues, targetValues)
+	assert.Equal(t, []byte("events"), targetValues["Topics/0"])
+	assert.Equal(t, []byte(""), targetValues["Empty"])
+
+	// the existing values are only replaced when overwrite is set
+	changed := strings.NewReader(`{"Port": 1234, "Writable": {"LogLevel": "DEBUG"}}`)
+	require.NoError(t, target.ImportConfiguration(changed, types.FormatJSON, false))
+	value, err := target.GetConfigurationValue("Port")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("59880"), value)
+	changed = strings.NewReader(`{"Port": 1234, "Writable": {"LogLevel": "DEBUG"}}`)
+	require.NoError(t, target.ImportConfiguration(changed, types.FormatJSON, true))
+	value, err = target.GetConfigurationValue("Port")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("1234"), value)
+}
+
+func TestExportImportEmptyAndNumbers(t *testing.T) {
+	client := makeConsulClient(t, getUniqueServiceName(), "", nil)
+	reset(t, client)
+	target := makeConsulClient(t, getUniqueServiceName(), "", nil)
+	reset(t, target)
+
+	document := `{
+  "Code": "01",
+  "Codes": {
+    "0": "a",
+    "1": "b"
+  },
+  "Empty": {},
+  "Enabled": "true",
+  "Port": "8000",
+  "Ratio": 1.0,
+  "Topics": [],
+  "Version": "1.0",
+  "Writable": {
+    "Labels": {},
+    "LogLevel": "INFO"
+  }
+}
+`
+	require.NoError(t, client.ImportConfiguration(strings.NewReader(document), types.FormatJSON, false))
+
+	// the services read the raw values, formatted as Go formats them
+	values, err := client.GetConfigurationValues("")
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]byte{
+		"Code":              []byte("01"),
+		"Codes/0":           []byte("a"),
+		"Codes/1":           []byte("b"),
+		"Enabled":           []byte("true"),
+		"Port":              []byte("8000"),
+		"Ratio":             []byte("1"),
+		"Version":           []byte("1.0"),
+		"Writable/LogLevel": []byte("INFO"),
+	}, values)
+
+	// while the document is exported exactly, with its types and its empty arrays and sections
+	var exported bytes.Buffer
+	require.NoError(t, client.ExportConfiguration(&exported, types.FormatJSON))
+	assert.Equal(t, document, exported.String())
+
+	// and restored exactly under another base path
+	require.NoError(t, target.ImportConfiguration(&exported, types.FormatJSON, false))
+	var restored bytes.Buffer
+	require.NoError(t, target.ExportConfiguration(&restored, types.FormatJSON))
+	assert.Equal(t, document, restored.String())
+}
+
+func TestPlanConfiguration(t *testing.T) {
+	client := makeConsulClient(t, getUniqueServiceName(), "", nil)
+	reset(t, client)
+
+	require.NoError(t, client.PutConfigurationValue("Port", []byte("8000")))
+	require.NoError(t, client.PutConfigurationValue("Host", []byte("localhost")))
+	require.NoError(t, client.PutConfigurationValue("Writable/LogLevel", []byte("INFO")))
+
+	plan, err := client.PlanConfiguration(MyConfig{
+		Logging:  LoggingInfo{EnableRemote: true, File: "NONE"},
+		Port:     8001,
+		Host:     "localhost",
+		LogLevel: "DEBUG",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []types.KeyChange{
+		{Key: "LogLevel", NewValue: []byte("DEBUG")},
+		{Key: "Logging/EnableRemote", NewValue: []byte("true")},
+		{Key: "Logging/File", NewValue: []byte("NONE")},
+	}, plan.Changes.Added)
+	assert.Equal(t, []types.KeyChange{{Key: "Port", OldValue: []byte("8000"), NewValue: []byte("8001")}}, plan.Changes.Modified)
+	assert.Equal(t, []types.KeyChange{{Key: "Writable/LogLevel", OldValue: []byte("INFO")}}, plan.Changes.Removed)
+
+	// nothing is changed until the plan is applied
+	value, err := client.GetConfigurationValue("Port")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("8000"), value)
+
+	tree, err := toml.Load(`Port = 8000
+Host = "localhost"
+[Writable]
+  LogLevel = "INFO"
+`)
+	require.NoError(t, err)
+	plan, err = client.PlanConfiguration(tree)
+	require.NoError(t, err)
+	assert.True(t, plan.IsEmpty())
+}
+
+func TestApplyPlan(t *testing.T) {
+	client := makeConsulClient(t, getUniqueServiceName(), "", nil)
+	reset(t, client)
+
+	require.NoError(t, client.PutConfigurationValue("Port", []byte("8000")))
+	require.NoError(t, client.PutConfigurationValue("Writable/LogLevel", []byte("INFO")))
+	desired := map[string]interface{}{"Port": 8001, "Host": "localhost"}
+
+	plan, err := client.PlanConfiguration(desired)
+	require.NoError(t, err)
+	require.NoError(t, client.ApplyPlan(plan))
+
+	values, err := client.GetConfigurationValues("")
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]byte{"Port": []byte("8001"), "Host": []byte("localhost")}, values)
+	plan, err = client.PlanConfiguration(desired)
+	require.NoError(t, err)
+	assert.True(t, plan.IsEmpty())
+
+	// a value changed since the plan was made prevents applying any of it
+	plan, err = client.PlanConfiguration(map[string]interface{}{"Port": 8002, "Host": "127.0.0.1"})
+	require.NoError(t, err)
+	require.NoError(t, client.PutConfigurationValue("Host", []byte("edgex-core-data")))
+	err = client.ApplyPlan(plan)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, types.ErrConflict), "unexpected error: %v", err)
+
+	values, err = client.GetConfigurationValues("")
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]byte{"Port": []byte("8001"), "Host": []byte("edgex-core-data")}, values)
+}
+
+func TestApplyPlanRollback(t *testing.T) {
+	client, _ := makeDedicatedConsulClient(t, types.RetryPolicy{})
+	configuration, err := toml.TreeFromMap(createLargeKeyValueMap(200))
+	require.NoError(t, err)
+	require.NoError(t, client.PutConfigurationToml(configuration, false))
+	before, err := client.GetConfigurationValues("")
+	require.NoError(t, err)
+
+	// every value is modified, in a transaction per 64 values
+	desired := make(map[string]interface{})
+	for key := range before {
+		desired[key] = "changed"
+	}
+	plan, err := client.PlanConfiguration(desired)
+	require.NoError(t, err)
+	require.Len(t, plan.Changes.Modified, 200)
+
+	// a value of the last transaction drifts, so the changes of the previous transactions are rolled back
+	require.NoError(t, client.PutConfigurationValue("Section9/Key99", []byte("drifted")))
+	err = client.ApplyPlan(plan)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, types.ErrConflict), "unexpected error: %v", err)
+
+	after, err := client.GetConfigurationValues("")
+	require.NoError(t, err)
+	before["Section9/Key99"] = []byte("drifted")
+	assert.Equal(t, before, after)
+}
+
+func TestDeleteConfigurationValue(t *testing.T) {
+	client := makeConsulClient(t, getUniqueServiceName(), "", nil)
+
+	// Make sure the configuration doesn't already exists
+	reset(t, client)
+
+	key := "Foo"
+	_ = client.PutConfigurationValue(key, []byte("bar"))
+	_ = client.PutConfigurationValue("FooBar", []byte("bar"))
+
+	err := client.DeleteConfigurationValue(key)
+	require.NoError(t, err)
+
+	assert.False(t, configValueSet(key, client))
+	assert.True(t, configValueSet("FooBar", client))
+
+	// deleting a value which doesn't exist is not an error
+	err = client.DeleteConfigurationValue(key)
+	require.NoError(t, err)
+}
+
+func TestDeleteSubConfiguration(t *testing.T) {
+	client := makeConsulClient(t, getUniqueServiceName(), "", nil)
+
+	// Make sure the configuration doesn't already exists
+	reset(t, client)
+
+	_ = client.PutConfigurationValue("Logging/EnableRemote", []byte("true"))
+	_ = client.PutConfigurationValue("Logging/File", []byte("NONE"))
+	_ = client.PutConfigurationValue("LoggingSibling", []byte("sibling"))
+	_ = client.PutConfigurationValue("Host", []byte("localhost"))
+
+	err := client.DeleteSubConfiguration("Logging")
+	require.NoError(t, err)
+
+	assert.False(t, configValueSet("Logging/EnableRemote", client))
+	assert.False(t, configValueSet("Logging/File", client))
+	assert.True(t, configValueSet("LoggingSibling", client))
+	assert.True(t, configValueSet("Host", client))
+
+	// deleting a sub configuration which doesn't exist is not an error
+	err = client.DeleteSubConfiguration("Logging")
+	require.NoError(t, err)
+}
+
 func TestGetConfiguration(t *testing.T) {
 	expected := MyConfig{
 		Logging: LoggingInfo{
@@ -412,15 +742,12 @@ func TestPutConfigurationTomlNoPreviousValues(t *testing.T) {
 		t.Fatal()
 	}
 
-	keyValues := convertInterfaceToConsulPairs("", configMap)
-	for _, keyValue := range keyValues {
-		expected := keyValue.Value
-		value, err := client.GetConfigurationValue(keyValue.Key)
+	for key, expected := range codec.ToValues("", configMap) {
+		value, err := client.GetConfigurationValue(key)
 		if !assert.NoError(t, err) {
 			t.Fatal()
 		}
-		actual := string(value)
-		if !assert.Equal(t, expected, actual, "Values for %s are not equal", keyValue.Key) {
+		if !assert.Equal(t, string(expected), string(value), "Values for %s are not equal", key) {
 			t.Fatal()
 		}
 	}
@@ -459,15 +786,12 @@ func TestPutConfigurationTomlWithoutOverWrite(t *testing.T) {
 		t.Fatal()
 	}
 
-	keyValues := convertInterfaceToConsulPairs("", configMap)
-	for _, keyValue := range keyValues {
-		expected := keyValue.Value
-		value, err := client.GetConfigurationValue(keyValue.Key)
+	for key, expected := range codec.ToValues("", configMap) {
+		value, err := client.GetConfigurationValue(key)
 		if !assert.NoError(t, err) {
 			t.Fatal()
 		}
-		actual := string(value)
-		if !assert.NotEqual(t, expected, actual, "Values for %s are equal, expected not equal", keyValue.Key) {
+		if !assert.NotEqual(t, string(expected), string(value), "Values for %s are equal, expected not equal", key) {
 			t.Fatal()
 		}
 	}
@@ -504,20 +828,129 @@ func TestPutConfigurationTomlOverWrite(t *testing.T) {
 		t.Fatal()
 	}
 
-	keyValues := convertInterfaceToConsulPairs("", configMap)
-	for _, keyValue := range keyValues {
-		expected := keyValue.Value
-		value, err := client.GetConfigurationValue(keyValue.Key)
+	for key, expected := range codec.ToValues("", configMap) {
+		value, err := client.GetConfigurationValue(key)
 		if !assert.NoError(t, err) {
 			t.Fatal()
 		}
-		actual := string(value)
-		if !assert.Equal(t, expected, actual, "Values for %s are not equal", keyValue.Key) {
+		if !assert.Equal(t, string(expected), string(value), "Values for %s are not equal", key) {
 			t.Fatal()
 		}
 	}
 }
 
+// makeDedicatedConsulClient makes a client of a dedicated mock Consul, so the watches left running by other tests
+// don't receive the injected failures
+func makeDedicatedConsulClient(t *testing.T, retryPolicy types.RetryPolicy) (*consulClient, *MockConsul) {
+	if mockConsul == nil {
+		t.Skip("test requires the mock Consul")
+	}
+
+	mock := NewMockConsul()
+	server := httptest.NewServer(mock.newHandler())
+	t.Cleanup(server.Close)
+	URL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	serverPort, err := strconv.Atoi(URL.Port())
+	require.NoError(t, err)
+
+	client, err := NewConsulClient(types.ServiceConfig{
+		Host:     URL.Hostname(),
+		Port:     serverPort,
+		BasePath: consulBasePath + getUniqueServiceName(),
+		Retry:    retryPolicy,
+	})
+	require.NoError(t, err)
+	return client, mock
+}
+
+// createLargeKeyValueMap creates a configuration of count values, spread over several sections
+func createLargeKeyValueMap(count int) map[string]interface{} {
+	configMap := make(map[string]interface{})
+	for i := 0; i < count; i++ {
+		section, ok := configMap[fmt.Sprintf("Section%d", i%10)].(map[string]interface{})
+		if !ok {
+			section = make(map[string]interface{})
+			configMap[fmt.Sprintf("Section%d", i%10)] = section
+		}
+		section[fmt.Sprintf("Key%d", i)] = fmt.Sprintf("value%d", i)
+	}
+	return configMap
+}
+
+func TestPutConfigurationTomlTransactions(t *testing.T) {
+	client, mock := makeDedicatedConsulClient(t, types.RetryPolicy{})
+
+	configuration, err := toml.TreeFromMap(createLargeKeyValueMap(200))
+	require.NoError(t, err)
+	require.NoError(t, client.PutConfigurationToml(configuration, false))
+
+	// a single read of the stored values, then a transaction per 64 values
+	assert.Equal(t, 1+4, mock.Requests())
+	pairs, _, err := client.consulClient.KV().List(client.configBasePath, nil)
+	require.NoError(t, err)
+	assert.Len(t, pairs, 200)
+	value, err := client.GetConfigurationValue("Section3/Key123")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("value123"), value)
+}
+
+func TestPutConfigurationTomlRollback(t *testing.T) {
+	tests := []struct {
+		Name      string
+		Overwrite bool
+	}{
+		{"Create only", false},
+		{"Overwrite", true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			client, mock := makeDedicatedConsulClient(t, types.RetryPolicy{})
+			require.NoError(t, client.PutConfigurationValue("Section0/Key0", []byte("seeded")))
+			require.NoError(t, client.PutConfigurationValue("Section0/Key190", []byte("seeded")))
+			before, _, err := client.consulClient.KV().List(client.configBasePath, nil)
+			require.NoError(t, err)
+
+			// the third transaction fails, the values written by the first two must be rolled back
+			mock.InjectTxnFailure(2, http.StatusInternalServerError)
+			configuration, err := toml.TreeFromMap(createLargeKeyValueMap(200))
+			require.NoError(t, err)
+			require.Error(t, client.PutConfigurationToml(configuration, test.Overwrite))
+
+			after, _, err := client.consulClient.KV().List(client.configBasePath, nil)
+			require.NoError(t, err)
+			require.Len(t, after, len(before))
+			for _, pair := range after {
+				assert.Equal(t, []byte("seeded"), pair.Value, pair.Key)
+			}
+		})
+	}
+}
+
+func TestPutConfigurationTomlConflict(t *testing.T) {
+	client, mock := makeDedicatedConsulClient(t, types.RetryPolicy{})
+
+	// the value is created by another service between the read of the stored values and the transaction
+	ops := api.TxnOps{{KV: &api.KVTxnOp{Verb: api.KVSet, Key: client.fullPath("Host"), Value: []byte("other")}}}
+	_, ok := mock.txn(ops)
+	require.True(t, ok)
+	err := client.putTxn(context.Background(), []*api.KVTxnOp{
+		{Verb: api.KVCAS, Key: client.fullPath("Port"), Value: []byte("8000")},
+		{Verb: api.KVCAS, Key: client.fullPath("Host"), Value: []byte("localhost")},
+	}, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "transaction rolled back")
+
+	// none of the values of the transaction is written
+	exists, err := client.ConfigurationValueExists("Port")
+	require.NoError(t, err)
+	assert.False(t, exists)
+	value, err := client.GetConfigurationValue("Host")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("other"), value)
+}
+
 func TestWatchForChanges(t *testing.T) {
 	expectedConfig := MyConfig{
 		Logging: LoggingInfo{
@@ -605,6 +1038,251 @@ func TestAccessToken(t *testing.T) {
 	require.Contains(t, err.Error(), expectedErrMsg)
 }
 
+func TestContextCanceled(t *testing.T) {
+	client := makeConsulClient(t, getUniqueServiceName(), "", nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := client.PutConfigurationValueCtx(ctx, "Foo", []byte("bar"))
+	require.Error(t, err)
+
+	_, err = client.GetConfigurationValueCtx(ctx, "Foo")
+	require.Error(t, err)
+
+	_, err = client.HasConfigurationCtx(ctx)
+	require.Error(t, err)
+
+	assert.False(t, client.IsAliveCtx(ctx))
+}
+
+func TestGetConfigurationDeadline(t *testing.T) {
+	if mockConsul == nil {
+		t.Skip("deadline test requires the mock Consul")
+	}
+
+	client, mock := makeDedicatedConsulClient(t, types.RetryPolicy{MaxAttempts: 3, InitialBackoff: 2500 * time.Millisecond})
+	require.NoError(t, client.PutConfigurationValue("Host", []byte("localhost")))
+
+	// The retry backs off longer than the deadline, which must be honored
+	mock.InjectTransientFailures(1, http.StatusServiceUnavailable)
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := client.GetConfigurationCtx(ctx, &MyConfig{})
+	require.Error(t, err)
+	assert.Less(t, time.Since(start), time.Second)
+
+	// Without a deadline, the configuration is read however long it takes
+	mock.InjectTransientFailures(1, http.StatusServiceUnavailable)
+	result, err := client.GetConfigurationCtx(context.Background(), &MyConfig{})
+	require.NoError(t, err)
+	assert.Equal(t, "localhost", result.(*MyConfig).Host)
+}
+
+func TestWatchForChangesCtx(t *testing.T) {
+	client := makeConsulClient(t, getUniqueServiceName(), "", nil)
+
+	// Make sure the configuration doesn't already exists
+	reset(t, client)
+
+	_ = client.PutConfigurationValue("Logging/File", []byte("NONE"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	updates := make(chan interface{})
+	errs := make(chan error)
+	client.WatchForChangesCtx(ctx, updates, errs, &LoggingInfo{}, "Logging")
+
+	cancel()
+
+	stopped := make(chan struct{})
+	go func() {
+		client.watchingWait.Wait()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-time.After(2 * time.Second):
+		t.Fatal("watch not stopped when context canceled")
+	}
+}
+
+func TestWatchForChangeSets(t *testing.T) {
+	client := makeConsulClient(t, getUniqueServiceName(), "", nil)
+	reset(t, client)
+
+	require.NoError(t, client.PutConfigurationValue("Logging/EnableRemote", []byte("true")))
+	require.NoError(t, client.PutConfigurationValue("Logging/File", []byte("NONE")))
+	require.NoError(t, client.PutConfigurationValue("LoggingLevel", []byte("INFO")))
+
+	updates := make(chan types.WatchUpdate)
+	errs := make(chan error)
+	handle := client.WatchForChangeSets(updates, errs, &LoggingInfo{}, "Logging")
+
+	receive := func() types.WatchUpdate {
+		select {
+		case update := <-updates:
+			return update
+		case err := <-errs:
+			require.NoError(t, err)
+		case <-time.After(5 * time.Second):
+			require.Fail(t, "timed out waiting for update")
+		}
+		return types.WatchUpdate{}
+	}
+
+	// All the values are added in the first update, the keys starting with the watched key aren't part of it
+	update := receive()
+	select {
+	case <-handle.Ready():
+	default:
+		require.Fail(t, "watch expected to be ready once the current configuration is sent")
+	}
+	assert.Equal(t, &LoggingInfo{EnableRemote: true, File: "NONE"}, update.Configuration)
+	assert.Equal(t, types.ChangeSet{Added: []types.KeyChange{
+		{Key: "EnableRemote", NewValue: []byte("true")},
+		{Key: "File", NewValue: []byte("NONE")},
+	}}, update.Changes)
+
+	require.NoError(t, client.PutConfigurationValue("Logging/File", []byte("changed.log")))
+	update = receive()
+	assert.Equal(t, &LoggingInfo{EnableRemote: true, File: "changed.log"}, update.Configuration)
+	assert.Equal(t, types.ChangeSet{Modified: []types.KeyChange{
+		{Key: "File", OldValue: []byte("NONE"), NewValue: []byte("changed.log")},
+	}}, update.Changes)
+
+	// The ModifyIndex changes when a value is written again, even unchanged
+	require.NoError(t, client.PutConfigurationValue("Logging/File", []byte("changed.log")))
+	update = receive()
+	assert.Equal(t, types.ChangeSet{Modified: []types.KeyChange{
+		{Key: "File", OldValue: []byte("changed.log"), NewValue: []byte("changed.log")},
+	}}, update.Changes)
+
+	require.NoError(t, client.DeleteConfigurationValue("Logging/EnableRemote"))
+	update = receive()
+	assert.Equal(t, &LoggingInfo{File: "changed.log"}, update.Configuration)
+	assert.Equal(t, types.ChangeSet{Removed: []types.KeyChange{
+		{Key: "EnableRemote", OldValue: []byte("true")},
+	}}, update.Changes)
+
+	stopped := make(chan struct{})
+	go func() {
+		handle.Stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "watch not stopped")
+	}
+}
+
+func TestWatchForChangeSetsDebounce(t *testing.T) {
+	client := makeConsulClient(t, getUniqueServiceName(), "", nil)
+	reset(t, client)
+
+	require.NoError(t, client.PutConfigurationValue("Logging/EnableRemote", []byte("true")))
+	require.NoError(t, client.PutConfigurationValue("Logging/File", []byte("NONE")))
+
+	updates := make(chan types.WatchUpdate)
+	errs := make(chan error)
+	// the window is longer than the mock blocking queries, which may miss a change and wait for their timeout
+	handle := client.WatchForChangeSets(updates, errs, &LoggingInfo{}, "Logging", types.WithDebounce(1500*time.Millisecond))
+	defer handle.Stop()
+
+	receive := func() types.WatchUpdate {
+		select {
+		case update := <-updates:
+			return update
+		case err := <-errs:
+			require.NoError(t, err)
+		case <-time.After(10 * time.Second):
+			require.Fail(t, "timed out waiting for update")
+		}
+		return types.WatchUpdate{}
+	}
+	receive()
+
+	// The changes made within the window are sent as a single update
+	require.NoError(t, client.PutConfigurationValue("Logging/File", []byte("changed.log")))
+	require.NoError(t, client.PutConfigurationValue("Logging/EnableRemote", []byte("false")))
+	require.NoError(t, client.PutConfigurationValue("Logging/File", []byte("final.log")))
+	update := receive()
+	assert.Equal(t, &LoggingInfo{EnableRemote: false, File: "final.log"}, update.Configuration)
+	assert.Equal(t, types.ChangeSet{Modified: []types.KeyChange{
+		{Key: "EnableRemote", OldValue: []byte("true"), NewValue: []byte("false")},
+		{Key: "File", OldValue: []byte("NONE"), NewValue: []byte("final.log")},
+	}}, update.Changes)
+}
+
+func TestTLS(t *testing.T) {
+	if mockConsul == nil {
+		t.Skip("TLS test requires the mock Consul")
+	}
+
+	server := mockConsul.StartTLS()
+	defer server.Close()
+
+	URL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	serverPort, err := strconv.Atoi(URL.Port())
+	require.NoError(t, err)
+
+	caPem := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+
+	tests := []struct {
+		name          string
+		tlsConfig     types.TLSConfig
+		expectedAlive bool
+	}{
+		{"CA PEM", types.TLSConfig{CAPem: caPem}, true},
+		{"insecure skip verify", types.TLSConfig{InsecureSkipVerify: true}, true},
+		{"unknown authority", types.TLSConfig{}, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client, err := NewConsulClient(types.ServiceConfig{
+				Protocol: "https",
+				Host:     URL.Hostname(),
+				Port:     serverPort,
+				BasePath: consulBasePath + getUniqueServiceName(),
+				TLS:      test.tlsConfig,
+			})
+			require.NoError(t, err)
+
+			assert.Equal(t, test.expectedAlive, client.IsAlive())
+
+			err = client.PutConfigurationValue("Foo", []byte("bar"))
+			if !test.expectedAlive {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			actual, err := client.GetConfigurationValue("Foo")
+			require.NoError(t, err)
+			assert.Equal(t, []byte("bar"), actual)
+
+			// The watches query Consul with the TLS settings as well
+			updates := make(chan interface{})
+			errs := make(chan error)
+			handle := client.WatchForChanges(updates, errs, &struct{ Foo string }{}, "")
+			defer handle.Stop()
+			select {
+			case update := <-updates:
+				assert.Equal(t, &struct{ Foo string }{Foo: "bar"}, update)
+			case err := <-errs:
+				require.NoError(t, err)
+			case <-time.After(5 * time.Second):
+				require.Fail(t, "timed out waiting for update")
+			}
+		})
+	}
+}
+
 func makeConsulClient(t *testing.T, serviceName string, accessToken string, tokenCallback types.GetAccessTokenCallback) *consulClient {
 	config := types.ServiceConfig{
 		Host:           testHost,
@@ -659,6 +1337,7 @@ func TestRenewAccessToken(t *testing.T) {
 	goodToken := "bfb78dc5-c6a3-33d9-88b5-e3a4b63dda77" // nolint: gosec
 	badToken := "badToken-c6a3-33d9-88b5-e3a4b63dda77"  // nolint: gosec
 	serviceName := "RenewAccessToken-Test"
+	defer mockConsul.ClearExpectedAccessToken()
 
 	getAccessToken := func() (string, error) {
 		fmt.Println("RenewAccessToken called")
@@ -770,8 +1449,8 @@ func TestRenewAccessToken(t *testing.T) {
 						return
 					}
 					require.NotNil(t, raw)
-					wg.Done()
 					receivedUpdate = true
+					wg.Done()
 					fmt.Println("WatchForChanges update received")
 					return
 				}
@@ -791,7 +1470,7 @@ func TestRenewAccessToken(t *testing.T) {
 		putTestConfig()
 		client := createClient(false)
 
-		allStopped := false
+		allStopped := make(chan struct{})
 		updates := make(chan interface{})
 		errs := make(chan error)
 		client.WatchForChanges(updates, errs, &myConfig, "Host")
@@ -799,10 +1478,67 @@ func TestRenewAccessToken(t *testing.T) {
 
 		go func() {
 			client.StopWatching()
-			allStopped = true
+			close(allStopped)
 		}()
 
-		<-time.Tick(2 * time.Second)
-		assert.True(t, allStopped)
+		select {
+		case <-allStopped:
+		case <-time.After(2 * time.Second):
+			assert.Fail(t, "watches not stopped")
+		}
 	})
 }
+
+func TestRetryPolicy(t *testing.T) {
+	if mockConsul == nil {
+		t.Skip("retry test requires the mock Consul")
+	}
+
+	// Use a dedicated mock so the watches left running by other tests don't receive the injected failures
+	mock := NewMockConsul()
+	server := httptest.NewServer(mock.newHandler())
+	defer server.Close()
+	URL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	serverPort, err := strconv.Atoi(URL.Port())
+	require.NoError(t, err)
+
+	testCases := []struct {
+		Name          string
+		Failures      int
+		StatusCode    int
+		Policy        types.RetryPolicy
+		ExpectedError error
+	}{
+		{Name: "No retry policy", Failures: 1, StatusCode: http.StatusServiceUnavailable, ExpectedError: types.ErrUnavailable},
+		{Name: "Recovers", Failures: 2, StatusCode: http.StatusServiceUnavailable, Policy: types.RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond, Jitter: 0.5}},
+		{Name: "Attempts exhausted", Failures: 3, StatusCode: http.StatusBadGateway, Policy: types.RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond}, ExpectedError: types.ErrUnavailable},
+		{Name: "Not retryable", Failures: 1, StatusCode: http.StatusForbidden, Policy: types.RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond}, ExpectedError: types.ErrUnauthorized},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.Name, func(t *testing.T) {
+			client, err := NewConsulClient(types.ServiceConfig{
+				Host:     URL.Hostname(),
+				Port:     serverPort,
+				BasePath: consulBasePath + getUniqueServiceName(),
+				Retry:    test.Policy,
+			})
+			require.NoError(t, err)
+
+			mock.InjectTransientFailures(test.Failures, test.StatusCode)
+			err = client.PutConfigurationValue("Foo", []byte("bar"))
+			if test.ExpectedError != nil {
+				require.Error(t, err)
+				assert.True(t, errors.Is(err, test.ExpectedError), "unexpected error: %v", err)
+				return
+			}
+			require.NoError(t, err)
+
+			mock.InjectTransientFailures(test.Failures, test.StatusCode)
+			actual, err := client.GetConfigurationValue("Foo")
+			require.NoError(t, err)
+			assert.Equal(t, []byte("bar"), actual)
+		})
+	}
+}
@@ -0,0 +1,56 @@
+//
+// Copyright (C) 2022 IOTech Ltd
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package consul
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+
+	consulapi "github.com/hashicorp/consul/api"
+
+	"github.com/edgexfoundry/go-mod-configuration/v2/pkg/types"
+)
+
+// wrapError classifies an error returned from the Consul API with the sentinel errors from the types package.
+// Errors which can't be classified are returned unchanged.
+func wrapError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var statusErr consulapi.StatusError
+	if errors.As(err, &statusErr) {
+		switch statusErr.Code {
+		case http.StatusUnauthorized, http.StatusForbidden:
+			return types.NewProviderError(types.ErrUnauthorized, err)
+		case http.StatusNotFound:
+			return types.NewProviderError(types.ErrNotFound, err)
+		case http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout:
+			return types.NewProviderError(types.ErrUnavailable, err)
+		}
+		return err
+	}
+
+	// The ACL errors may only be reported through their message
+	if strings.Contains(err.Error(), aclError) {
+		return types.NewProviderError(types.ErrUnauthorized, err)
+	}
+
+	if errors.Is(err, context.Canceled) {
+		return err
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return types.NewProviderError(types.ErrUnavailable, err)
+	}
+
+	return err
+}
@@ -0,0 +1,46 @@
+//
+// Copyright (C) 2022 IOTech Ltd
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package consul
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"testing"
+
+	consulapi "github.com/hashicorp/consul/api"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/edgexfoundry/go-mod-configuration/v2/pkg/types"
+)
+
+func TestWrapError(t *testing.T) {
+	testCases := []struct {
+		Name     string
+		Err      error
+		Expected error
+	}{
+		{Name: "Forbidden", Err: consulapi.StatusError{Code: 403}, Expected: types.ErrUnauthorized},
+		{Name: "Unauthorized", Err: consulapi.StatusError{Code: 401}, Expected: types.ErrUnauthorized},
+		{Name: "ACL message", Err: errors.New("Unexpected response code: 403 (ACL not found)"), Expected: types.ErrUnauthorized},
+		{Name: "Not Found", Err: consulapi.StatusError{Code: 404}, Expected: types.ErrNotFound},
+		{Name: "Service Unavailable", Err: consulapi.StatusError{Code: 503}, Expected: types.ErrUnavailable},
+		{Name: "Connection refused", Err: &url.Error{Op: "Get", URL: "http://localhost:8500", Err: errors.New("connection refused")}, Expected: types.ErrUnavailable},
+		{Name: "Canceled", Err: &url.Error{Op: "Get", URL: "http://localhost:8500", Err: context.Canceled}, Expected: nil},
+		{Name: "Bad Request", Err: consulapi.StatusError{Code: 400}, Expected: nil},
+	}
+
+	sentinels := []error{types.ErrNotFound, types.ErrUnauthorized, types.ErrUnavailable, types.ErrDecode}
+	for _, test := range testCases {
+		t.Run(test.Name, func(t *testing.T) {
+			err := wrapError(test.Err)
+			assert.Equal(t, test.Err.Error(), err.Error())
+			for _, sentinel := range sentinels {
+				assert.Equal(t, sentinel == test.Expected, errors.Is(err, sentinel), "errors.Is(%v)", sentinel)
+			}
+		})
+	}
+}
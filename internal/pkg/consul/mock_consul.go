@@ -17,13 +17,17 @@
 package consul
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"io"
 	"log"
 	"net/http"
 	"net/http/httptest"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	consulapi "github.com/hashicorp/consul/api"
@@ -32,13 +36,30 @@ import (
 const (
 	verbose  = false
 	TokenKey = "X-Consul-Token" // nolint: gosec
+
+	// maxBlockingQueryWait is the longest time the blocking queries wait for a change, Consul defaults to 5 minutes
+	// but it's shortened for the unit tests
+	maxBlockingQueryWait = time.Second
 )
 
+// MockConsul is an in-process stand-in for the Consul KV store. Its blocking queries wait for the Consul index to
+// advance past the requested one, each write advancing it the same way Consul does.
 type MockConsul struct {
+	lock                sync.Mutex
 	keyValueStore       map[string]*consulapi.KVPair
 	serviceStore        map[string]consulapi.AgentService
 	serviceCheckStore   map[string]consulapi.AgentCheck
 	expectedAccessToken string
+	transientFailures   int32
+	transientStatusCode int32
+	// txnsBeforeFailure is the number of transactions which succeed before the next one fails, none fails if negative
+	txnsBeforeFailure int32
+	txnStatusCode     int32
+	requests          int32
+	// index is the Consul index, the ModifyIndex of the last write
+	index uint64
+	// changed is closed and replaced at each write, to wake up the blocking queries
+	changed chan struct{}
 }
 
 func NewMockConsul() *MockConsul {
@@ -46,33 +67,71 @@ func NewMockConsul() *MockConsul {
 		keyValueStore:     make(map[string]*consulapi.KVPair),
 		serviceStore:      make(map[string]consulapi.AgentService),
 		serviceCheckStore: make(map[string]consulapi.AgentCheck),
+		index:             1,
+		changed:           make(chan struct{}),
+		txnsBeforeFailure: -1,
 	}
 }
 
-var keyChannels map[string]chan bool
-var PrefixChannels map[string]chan bool
-
+// Reset removes all the stored values, the index keeps advancing so the running watches see the removals
 func (mock *MockConsul) Reset() {
+	mock.lock.Lock()
+	defer mock.lock.Unlock()
 	mock.keyValueStore = make(map[string]*consulapi.KVPair)
 	mock.serviceStore = make(map[string]consulapi.AgentService)
 	mock.serviceCheckStore = make(map[string]consulapi.AgentCheck)
+	mock.advance()
 }
 
 func (mock *MockConsul) Start() *httptest.Server {
-	keyChannels = make(map[string]chan bool)
-	var consulIndex = 1
-	PrefixChannels = make(map[string]chan bool)
+	return httptest.NewServer(mock.newHandler())
+}
+
+// StartTLS starts the mock Consul with https, the CA certificate is available from the returned server.
+// It shares the stored values with the server returned by Start.
+func (mock *MockConsul) StartTLS() *httptest.Server {
+	return httptest.NewTLSServer(mock.newHandler())
+}
+
+func (mock *MockConsul) newHandler() http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		atomic.AddInt32(&mock.requests, 1)
+		if mock.nextRequestFails() {
+			writer.WriteHeader(int(atomic.LoadInt32(&mock.transientStatusCode)))
+			return
+		}
 
-	testMockServer := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
-		if len(mock.expectedAccessToken) > 0 {
+		if expectedToken := mock.accessToken(); len(expectedToken) > 0 {
 			token := request.Header.Get(TokenKey)
-			if len(mock.expectedAccessToken) > 0 && token != mock.expectedAccessToken {
+			if token != expectedToken {
 				writer.WriteHeader(http.StatusForbidden)
 				return
 			}
 		}
 
-		if strings.Contains(request.URL.Path, "/v1/kv/") {
+		if strings.Contains(request.URL.Path, "/v1/txn") && request.Method == "PUT" {
+			if mock.nextTxnFails() {
+				writer.WriteHeader(int(atomic.LoadInt32(&mock.txnStatusCode)))
+				return
+			}
+
+			var ops consulapi.TxnOps
+			if err := json.NewDecoder(request.Body).Decode(&ops); err != nil {
+				writer.WriteHeader(http.StatusBadRequest)
+				return
+			}
+
+			response, ok := mock.txn(ops)
+			writer.Header().Set("Content-Type", "application/json")
+			if ok {
+				writer.WriteHeader(http.StatusOK)
+			} else {
+				writer.WriteHeader(http.StatusConflict)
+			}
+			if err := json.NewEncoder(writer).Encode(response); err != nil {
+				log.Printf("error writing data response: %s", err.Error())
+			}
+		} else if strings.Contains(request.URL.Path, "/v1/kv/") {
 			key := strings.Replace(request.URL.Path, "/v1/kv/", "", 1)
 
 			switch request.Method {
@@ -82,43 +141,46 @@ func (mock *MockConsul) Start() *httptest.Server {
 					log.Printf("error reading request body: %s", err.Error())
 				}
 
-				keyValuePair, found := mock.keyValueStore[key]
-				if found {
-					keyValuePair.ModifyIndex++
-					keyValuePair.Value = body
-				} else {
-					keyValuePair = &consulapi.KVPair{
-						Key:         key,
-						Value:       body,
-						ModifyIndex: 1,
-						CreateIndex: 1,
-						Flags:       0,
-						LockIndex:   0,
+				// the cas parameter makes the write a check-and-set on the ModifyIndex of the value
+				written := true
+				if casIndex := request.URL.Query().Get("cas"); casIndex != "" {
+					index, err := strconv.ParseUint(casIndex, 10, 64)
+					if err != nil {
+						writer.WriteHeader(http.StatusBadRequest)
+						return
 					}
+					written = mock.cas(key, body, index)
+				} else {
+					mock.put(key, body)
 				}
 
-				mock.keyValueStore[key] = keyValuePair
-
 				if verbose {
 					log.Printf("PUTing new value for %s", key)
 				}
 
-				channel, found := keyChannels[key]
-				if found {
-					channel <- true
+				writer.Header().Set("Content-Type", "application/json")
+				writer.WriteHeader(http.StatusOK)
+				if _, err := writer.Write([]byte(strconv.FormatBool(written))); err != nil {
+					log.Printf("error writing data response: %s", err.Error())
 				}
-				for prefix, channel := range PrefixChannels {
-					if strings.HasPrefix(key, prefix) {
-						consulIndex++
-						if channel != nil {
-							channel <- true
-						}
-					}
+			case "DELETE":
+				// Recurse parameter is set when deleting all keys with the prefix set in URL.
+				_, recurseFound := request.URL.Query()["recurse"]
+				mock.delete(key, recurseFound)
+
+				if verbose {
+					log.Printf("DELETEing value(s) for %s", key)
+				}
+
+				writer.Header().Set("Content-Type", "application/json")
+				writer.WriteHeader(http.StatusOK)
+				if _, err := writer.Write([]byte("true")); err != nil {
+					log.Printf("error writing data response: %s", err.Error())
 				}
 			case "GET":
-				// this is what the wait query parameters will look like "index=1&wait=600000ms"
+				// this is what the blocking query parameters look like "index=1&wait=600000ms"
 				var pairs consulapi.KVPairs
-				var prefixFound bool
+				var index uint64
 				query := request.URL.Query()
 
 				// Recurse parameters are usually set when prefix is monitored,
@@ -126,50 +188,34 @@ func (mock *MockConsul) Start() *httptest.Server {
 				_, recurseFound := query["recurse"]
 				_, allKeysRequested := query["keys"]
 				if recurseFound {
-					pairs, prefixFound = mock.checkForPrefix(key)
-					if !prefixFound {
-						http.NotFound(writer, request)
-						return
+					if waitIndex, err := strconv.ParseUint(query.Get("index"), 10, 64); err == nil && waitIndex > 0 {
+						mock.waitForChange(request.Context(), waitIndex, query.Get("wait"))
 					}
-					//Default wait time is 30 minutes, over riding it for unit test purpose
-					waitTime := "1s"
-					if waitTime != "" {
-						waitForNextPutPrefix(key, waitTime)
-					}
-					writer.Header().Set("X-Consul-Index", strconv.Itoa(consulIndex))
+					pairs, index = mock.list(key)
 				} else if allKeysRequested {
-					// Just returning array of key names
-					var keys []string
-
-					pairs, prefixFound = mock.checkForPrefix(key)
-					if !prefixFound {
-						http.NotFound(writer, request)
-						return
-					}
-
-					for _, key := range pairs {
-						keys = append(keys, key.Key)
-					}
+					pairs, index = mock.list(key)
+				} else if keyValuePair, found := mock.get(key); found {
+					pairs = consulapi.KVPairs{keyValuePair}
+				}
 
-					jsonData, _ := json.MarshalIndent(&keys, "", "  ")
+				writer.Header().Set("X-Consul-Index", strconv.FormatUint(index, 10))
+				if len(pairs) == 0 {
+					http.NotFound(writer, request)
+					return
+				}
 
-					writer.Header().Set("Content-Type", "application/json")
-					writer.WriteHeader(http.StatusOK)
-					if _, err := writer.Write(jsonData); err != nil {
-						log.Printf("error writing data response: %s", err.Error())
+				var jsonData []byte
+				if allKeysRequested {
+					// Just returning array of key names
+					keys := make([]string, 0, len(pairs))
+					for _, pair := range pairs {
+						keys = append(keys, pair.Key)
 					}
-
+					jsonData, _ = json.MarshalIndent(&keys, "", "  ")
 				} else {
-					keyValuePair, found := mock.keyValueStore[key]
-					pairs = consulapi.KVPairs{keyValuePair}
-					if !found {
-						http.NotFound(writer, request)
-						return
-					}
+					jsonData, _ = json.MarshalIndent(&pairs, "", "  ")
 				}
 
-				jsonData, _ := json.MarshalIndent(&pairs, "", "  ")
-
 				writer.Header().Set("Content-Type", "application/json")
 				writer.WriteHeader(http.StatusOK)
 				if _, err := writer.Write(jsonData); err != nil {
@@ -184,60 +230,231 @@ func (mock *MockConsul) Start() *httptest.Server {
 
 			}
 		}
-	}))
+	})
+}
 
-	return testMockServer
+// advance advances the index and wakes up the blocking queries, the lock must be held
+func (mock *MockConsul) advance() uint64 {
+	mock.index++
+	close(mock.changed)
+	mock.changed = make(chan struct{})
+	return mock.index
 }
 
-func waitForNextPutPrefix(key string, waitTime string) {
-	timeout, err := time.ParseDuration(waitTime)
-	if err != nil {
-		log.Printf("Error parsing waitTime %s into a duration: %s", waitTime, err.Error())
+func (mock *MockConsul) put(key string, value []byte) {
+	mock.lock.Lock()
+	defer mock.lock.Unlock()
+
+	mock.setLocked(key, value)
+}
+
+// cas puts the value only if the ModifyIndex of the stored value is the index, or if it doesn't exist for index 0
+func (mock *MockConsul) cas(key string, value []byte, index uint64) bool {
+	mock.lock.Lock()
+	defer mock.lock.Unlock()
+
+	stored, exists := mock.keyValueStore[key]
+	if (index == 0 && exists) || (index != 0 && (!exists || stored.ModifyIndex != index)) {
+		return false
 	}
-	channel := make(chan bool)
-	PrefixChannels[key] = channel
-	timedOut := false
-	go func() {
-		time.Sleep(timeout)
-		timedOut = true
-		if PrefixChannels[key] != nil {
-			PrefixChannels[key] <- true
-			if verbose {
-				log.Printf("Timed out watching for change on %s", key)
+	mock.setLocked(key, value)
+	return true
+}
+
+// setLocked stores the value at a new index, the lock must be held
+func (mock *MockConsul) setLocked(key string, value []byte) {
+	index := mock.advance()
+	keyValuePair, found := mock.keyValueStore[key]
+	if !found {
+		keyValuePair = &consulapi.KVPair{Key: key, CreateIndex: index}
+		mock.keyValueStore[key] = keyValuePair
+	}
+	keyValuePair.Value = value
+	keyValuePair.ModifyIndex = index
+}
+
+func (mock *MockConsul) delete(key string, recurse bool) {
+	mock.lock.Lock()
+	defer mock.lock.Unlock()
+
+	deleted := false
+	for storedKey := range mock.keyValueStore {
+		if storedKey == key || (recurse && strings.HasPrefix(storedKey, key)) {
+			delete(mock.keyValueStore, storedKey)
+			deleted = true
+		}
+	}
+	if deleted {
+		mock.advance()
+	}
+}
+
+// txn applies the KV operations of the transaction at once if all their checks pass, none of them otherwise. All the
+// written values get the same ModifyIndex, as the transaction advances the index once.
+func (mock *MockConsul) txn(ops consulapi.TxnOps) (consulapi.TxnResponse, bool) {
+	mock.lock.Lock()
+	defer mock.lock.Unlock()
+
+	var response consulapi.TxnResponse
+	for opIndex, op := range ops {
+		if op.KV == nil {
+			response.Errors = append(response.Errors, &consulapi.TxnError{OpIndex: opIndex, What: "only KV operations are supported"})
+			continue
+		}
+		stored, exists := mock.keyValueStore[op.KV.Key]
+		switch op.KV.Verb {
+		case consulapi.KVSet, consulapi.KVDelete:
+		case consulapi.KVCAS, consulapi.KVDeleteCAS:
+			// index 0 checks that the key doesn't exist
+			if (op.KV.Index == 0 && exists) || (op.KV.Index != 0 && (!exists || stored.ModifyIndex != op.KV.Index)) {
+				response.Errors = append(response.Errors, &consulapi.TxnError{
+					OpIndex: opIndex,
+					What:    fmt.Sprintf("failed to %s key %q, index is stale", op.KV.Verb, op.KV.Key),
+				})
 			}
+		default:
+			response.Errors = append(response.Errors, &consulapi.TxnError{OpIndex: opIndex, What: fmt.Sprintf("unsupported verb %s", op.KV.Verb)})
 		}
-	}()
+	}
+	if len(response.Errors) > 0 {
+		return response, false
+	}
 
-	if verbose {
-		log.Printf("Watching for change on %s", key)
+	index := mock.advance()
+	for _, op := range ops {
+		switch op.KV.Verb {
+		case consulapi.KVSet, consulapi.KVCAS:
+			keyValuePair, found := mock.keyValueStore[op.KV.Key]
+			if !found {
+				keyValuePair = &consulapi.KVPair{Key: op.KV.Key, CreateIndex: index}
+				mock.keyValueStore[op.KV.Key] = keyValuePair
+			}
+			keyValuePair.Value = op.KV.Value
+			keyValuePair.Flags = op.KV.Flags
+			keyValuePair.ModifyIndex = index
+			result := *keyValuePair
+			result.Value = nil
+			response.Results = append(response.Results, &consulapi.TxnResult{KV: &result})
+		case consulapi.KVDelete, consulapi.KVDeleteCAS:
+			delete(mock.keyValueStore, op.KV.Key)
+		}
 	}
+	return response, true
+}
+
+// get returns a copy of the stored key-value pair
+func (mock *MockConsul) get(key string) (*consulapi.KVPair, bool) {
+	mock.lock.Lock()
+	defer mock.lock.Unlock()
 
-	<-channel
-	close(channel)
-	PrefixChannels[key] = nil
-	if !timedOut {
-		log.Printf("%s changed", key)
+	keyValuePair, found := mock.keyValueStore[key]
+	if !found {
+		return nil, false
 	}
+	result := *keyValuePair
+	return &result, true
 }
 
-func (mock *MockConsul) checkForPrefix(prefix string) (consulapi.KVPairs, bool) {
+// list returns a copy of the stored key-value pairs with the prefix, along with the current index
+func (mock *MockConsul) list(prefix string) (consulapi.KVPairs, uint64) {
+	mock.lock.Lock()
+	defer mock.lock.Unlock()
+
 	var pairs consulapi.KVPairs
-	for k, v := range mock.keyValueStore {
-		if strings.HasPrefix(k, prefix) {
-			pairs = append(pairs, v)
+	for key, keyValuePair := range mock.keyValueStore {
+		if strings.HasPrefix(key, prefix) {
+			pair := *keyValuePair
+			pairs = append(pairs, &pair)
 		}
 	}
-	if len(pairs) == 0 {
-		return nil, false
+	return pairs, mock.index
+}
+
+// waitForChange blocks until the index advances past the wait index, the wait time elapses or the request is canceled
+func (mock *MockConsul) waitForChange(ctx context.Context, waitIndex uint64, waitTime string) {
+	timeout := maxBlockingQueryWait
+	if wait, err := time.ParseDuration(waitTime); err == nil && wait < timeout {
+		timeout = wait
 	}
-	return pairs, true
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		mock.lock.Lock()
+		index, changed := mock.index, mock.changed
+		mock.lock.Unlock()
+		if index > waitIndex {
+			return
+		}
+
+		if verbose {
+			log.Printf("Waiting for the index to advance past %d", waitIndex)
+		}
 
+		select {
+		case <-changed:
+		case <-timer.C:
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
 }
 
 func (mock *MockConsul) SetExpectedAccessToken(token string) {
+	mock.lock.Lock()
+	defer mock.lock.Unlock()
 	mock.expectedAccessToken = token
 }
 
 func (mock *MockConsul) ClearExpectedAccessToken() {
-	mock.expectedAccessToken = ""
+	mock.SetExpectedAccessToken("")
+}
+
+func (mock *MockConsul) accessToken() string {
+	mock.lock.Lock()
+	defer mock.lock.Unlock()
+	return mock.expectedAccessToken
+}
+
+// InjectTransientFailures makes the mock respond to the next count requests with the statusCode, i.e. 503, before it
+// responds normally again
+func (mock *MockConsul) InjectTransientFailures(count int, statusCode int) {
+	atomic.StoreInt32(&mock.transientStatusCode, int32(statusCode))
+	atomic.StoreInt32(&mock.transientFailures, int32(count))
+}
+
+// InjectTxnFailure makes the mock respond to the transaction following the next count ones with the statusCode
+func (mock *MockConsul) InjectTxnFailure(count int, statusCode int) {
+	atomic.StoreInt32(&mock.txnStatusCode, int32(statusCode))
+	atomic.StoreInt32(&mock.txnsBeforeFailure, int32(count))
+}
+
+func (mock *MockConsul) nextTxnFails() bool {
+	for {
+		count := atomic.LoadInt32(&mock.txnsBeforeFailure)
+		if count < 0 {
+			return false
+		}
+		if atomic.CompareAndSwapInt32(&mock.txnsBeforeFailure, count, count-1) {
+			return count == 0
+		}
+	}
+}
+
+// Requests returns the number of requests the mock has received
+func (mock *MockConsul) Requests() int {
+	return int(atomic.LoadInt32(&mock.requests))
+}
+
+func (mock *MockConsul) nextRequestFails() bool {
+	for {
+		failures := atomic.LoadInt32(&mock.transientFailures)
+		if failures <= 0 {
+			return false
+		}
+		if atomic.CompareAndSwapInt32(&mock.transientFailures, failures, failures-1) {
+			return true
+		}
+	}
 }
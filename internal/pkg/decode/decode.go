@@ -0,0 +1,78 @@
+//
+// Copyright (C) 2022 IOTech Ltd
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package decode
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mitchellh/mapstructure"
+
+	"github.com/edgexfoundry/go-mod-configuration/v2/pkg/types"
+)
+
+// KeyDelimiter separates the levels of the configuration keys
+const KeyDelimiter = "/"
+
+// Decode converts the values, keyed by their full path, under the prefix to the target configuration data type. The
+// values are either raw, as stored by Consul and the memory provider, or typed, as stored by Core Keeper, and all of
+// them are weakly converted to the types of the target fields, so each provider decodes the same way.
+// The errors are returned as a ProviderError of kind ErrDecode.
+func Decode[V any](prefix string, values map[string]V, target interface{}) error {
+	// the keys are sorted so a key being both a value and a section is always reported the same way
+	keys := make([]string, 0, len(values))
+	for fullKey := range values {
+		keys = append(keys, fullKey)
+	}
+	sort.Strings(keys)
+
+	raw := make(map[string]interface{})
+	for _, fullKey := range keys {
+		// Trim the prefix off our key first
+		key := strings.TrimPrefix(strings.TrimPrefix(fullKey, prefix), KeyDelimiter)
+
+		// Determine what map we're writing the value to. We split by '/'
+		// to determine any sub-maps that need to be created.
+		m := raw
+		children := strings.Split(key, KeyDelimiter)
+		key = children[len(children)-1]
+		for _, child := range children[:len(children)-1] {
+			if m[child] == nil {
+				m[child] = make(map[string]interface{})
+			}
+
+			subm, ok := m[child].(map[string]interface{})
+			if !ok {
+				return types.NewProviderError(types.ErrDecode, fmt.Errorf("child is both a data item and dir: %s", child))
+			}
+
+			m = subm
+		}
+
+		switch value := any(values[fullKey]).(type) {
+		case []byte:
+			m[key] = string(value)
+		case string, bool, int, int8, int16, int32, int64, float32, float64:
+			m[key] = value
+		default:
+			return types.NewProviderError(types.ErrDecode, fmt.Errorf("unknown data type %T of the stored value %s", value, fullKey))
+		}
+	}
+
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		WeaklyTypedInput: true,
+		Result:           target,
+	})
+	if err != nil {
+		return types.NewProviderError(types.ErrDecode, fmt.Errorf("decoding failed, err: %w", err))
+	}
+	if err := decoder.Decode(raw); err != nil {
+		return types.NewProviderError(types.ErrDecode, fmt.Errorf("decoding failed, err: %w", err))
+	}
+
+	return nil
+}
@@ -0,0 +1,82 @@
+//
+// Copyright (C) 2022 IOTech Ltd
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package decode
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/edgexfoundry/go-mod-configuration/v2/pkg/types"
+)
+
+type testLogging struct {
+	EnableRemote bool
+	File         string
+}
+
+type testConfig struct {
+	Logging  testLogging
+	Port     int
+	Timeout  float64
+	LogLevel string
+}
+
+func TestDecode(t *testing.T) {
+	expected := testConfig{
+		Logging:  testLogging{EnableRemote: true, File: "NONE"},
+		Port:     59880,
+		Timeout:  2.5,
+		LogLevel: "INFO",
+	}
+
+	// the raw values of Consul and the memory provider
+	var raw testConfig
+	require.NoError(t, Decode("edgex/core-data/", map[string][]byte{
+		"edgex/core-data/Logging/EnableRemote": []byte("true"),
+		"edgex/core-data/Logging/File":         []byte("NONE"),
+		"edgex/core-data/Port":                 []byte("59880"),
+		"edgex/core-data/Timeout":              []byte("2.5"),
+		"edgex/core-data/LogLevel":             []byte("INFO"),
+	}, &raw))
+	assert.Equal(t, expected, raw)
+
+	// the typed values of Core Keeper, the prefix may not end with the delimiter
+	var typed testConfig
+	require.NoError(t, Decode("edgex/core-data", map[string]interface{}{
+		"edgex/core-data/Logging/EnableRemote": "true",
+		"edgex/core-data/Logging/File":         "NONE",
+		"edgex/core-data/Port":                 float64(59880),
+		"edgex/core-data/Timeout":              2.5,
+		"edgex/core-data/LogLevel":             "INFO",
+	}, &typed))
+	assert.Equal(t, expected, typed)
+}
+
+func TestDecodeErrors(t *testing.T) {
+	tests := []struct {
+		Name   string
+		Values map[string]interface{}
+	}{
+		{"Invalid value", map[string]interface{}{"Port": "not a number"}},
+		{"Value and section", map[string]interface{}{"Logging": "NONE", "Logging/File": "NONE"}},
+		{"Unknown type", map[string]interface{}{"Port": []int{59880}}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			err := Decode("", test.Values, &testConfig{})
+			require.Error(t, err)
+			assert.True(t, errors.Is(err, types.ErrDecode))
+		})
+	}
+
+	var decodeErr *mapstructure.Error
+	assert.True(t, errors.As(Decode("", map[string]string{"Port": "x"}, &testConfig{}), &decodeErr))
+}
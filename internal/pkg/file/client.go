@@ -0,0 +1,773 @@
+//
+// Copyright (C) 2026 EdgeX Foundry Contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package file
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/pelletier/go-toml"
+
+	"github.com/edgexfoundry/go-mod-configuration/v2/internal/pkg/codec"
+	"github.com/edgexfoundry/go-mod-configuration/v2/internal/pkg/listing"
+	"github.com/edgexfoundry/go-mod-configuration/v2/internal/pkg/planning"
+	"github.com/edgexfoundry/go-mod-configuration/v2/internal/pkg/watch"
+	"github.com/edgexfoundry/go-mod-configuration/v2/pkg/types"
+)
+
+const (
+	// PollIntervalKey is the ServiceConfig.Optional key of the interval at which the watched file is checked for
+	// changes. The value is either a time.Duration or a duration string, i.e. "500ms".
+	PollIntervalKey = watch.PollIntervalKey
+
+	defaultPollInterval = time.Second
+)
+
+type fileClient struct {
+	filePath       string
+	format         types.ConfigurationFormat
+	configBasePath []string
+	pollInterval   time.Duration
+	// lock serializes the read-modify-write cycles of the file within this process
+	lock            sync.Mutex
+	watchingDoneCtx context.Context
+	watchingDone    context.CancelFunc
+	watchingWait    sync.WaitGroup
+}
+
+// NewFileClient creates a new Configuration client which serves the configuration from a local TOML, YAML or JSON
+// file. BasePath selects the table of the file holding the service's configuration.
+func NewFileClient(config types.ServiceConfig) (*fileClient, error) {
+	if config.FilePath == "" {
+		return nil, errors.New("unable to create new file Configuration Client: FilePath not set")
+	}
+
+	format, err := formatFromPath(config.FilePath)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create new file Configuration Client: %v", err)
+	}
+
+	pollInterval, err := watch.PollInterval(config.Optional, defaultPollInterval)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create new file Configuration Client: %v", err)
+	}
+
+	client := fileClient{
+		filePath:       config.FilePath,
+		format:         format,
+		configBasePath: splitPath(config.BasePath),
+		pollInterval:   pollInterval,
+	}
+
+	client.watchingDoneCtx, client.watchingDone = context.WithCancel(context.Background())
+
+	return &client, nil
+}
+
+func (client *fileClient) fullPath(name string) []string {
+	return append(append([]string{}, client.configBasePath...), splitPath(name)...)
+}
+
+// readDocument reads and parses the configuration file. An empty document is returned if the file doesn't exist yet.
+func (client *fileClient) readDocument() (map[string]interface{}, []byte, error) {
+	data, err := os.ReadFile(client.filePath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return make(map[string]interface{}), nil, nil
+	}
+	if err != nil {
+		return nil, nil, types.NewProviderError(types.ErrUnavailable, fmt.Errorf("unable to read %s: %w", client.filePath, err))
+	}
+
+	document, err := codec.Unmarshal(client.format, data)
+	if err != nil {
+		return nil, nil, types.NewProviderError(types.ErrDecode, fmt.Errorf("unable to parse %s: %w", client.filePath, err))
+	}
+
+	return document, data, nil
+}
+
+// writeDocument renders the document and replaces the configuration file with it atomically, so readers never see
+// a partially written file
+func (client *fileClient) writeDocument(document map[string]interface{}) error {
+	data, err := codec.Marshal(client.format, document)
+	if err != nil {
+		return fmt.Errorf("unable to render configuration for %s: %w", client.filePath, err)
+	}
+
+	mode := fs.FileMode(0644)
+	if info, err := os.Stat(client.filePath); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	tmpFile, err := os.CreateTemp(filepath.Dir(client.filePath), "."+filepath.Base(client.filePath)+".*")
+	if err != nil {
+		return types.NewProviderError(types.ErrUnavailable, fmt.Errorf("unable to write %s: %w", client.filePath, err))
+	}
+	tmpPath := tmpFile.Name()
+	defer func() {
+		// Nothing to remove once the file has been renamed
+		_ = os.Remove(tmpPath)
+	}()
+
+	_, err = tmpFile.Write(data)
+	if err == nil {
+		err = tmpFile.Sync()
+	}
+	if closeErr := tmpFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmpPath, mode)
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, client.filePath)
+	}
+	if err != nil {
+		return types.NewProviderError(types.ErrUnavailable, fmt.Errorf("unable to write %s: %w", client.filePath, err))
+	}
+
+	return nil
+}
+
+// update applies the change to the configuration file. The file is only written if the change returns true.
+func (client *fileClient) update(change func(document map[string]interface{}) (bool, error)) error {
+	client.lock.Lock()
+	defer client.lock.Unlock()
+
+	document, _, err := client.readDocument()
+	if err != nil {
+		return err
+	}
+
+	changed, err := change(document)
+	if err != nil || !changed {
+		return err
+	}
+
+	return client.writeDocument(document)
+}
+
+// IsAlive checks if the configuration file, or the directory it will be created in, is accessible
+func (client *fileClient) IsAlive() bool {
+	return client.IsAliveCtx(context.Background())
+}
+
+// IsAliveCtx checks if the configuration file, or the directory it will be created in, is accessible.
+// The file operations are local, so the context is only used by the watches.
+func (client *fileClient) IsAliveCtx(_ context.Context) bool {
+	if _, err := os.Stat(client.filePath); err == nil {
+		return true
+	}
+
+	info, err := os.Stat(filepath.Dir(client.filePath))
+	return err == nil && info.IsDir()
+}
+
+// HasConfiguration checks to see if the configuration file contains the service's configuration.
+func (client *fileClient) HasConfiguration() (bool, error) {
+	return client.HasConfigurationCtx(context.Background())
+}
+
+// HasConfigurationCtx checks to see if the configuration file contains the service's configuration.
+func (client *fileClient) HasConfigurationCtx(ctx context.Context) (bool, error) {
+	return client.HasSubConfigurationCtx(ctx, "")
+}
+
+// HasSubConfiguration checks to see if the configuration file contains the service's sub configuration.
+func (client *fileClient) HasSubConfiguration(name string) (bool, error) {
+	return client.HasSubConfigurationCtx(context.Background(), name)
+}
+
+// HasSubConfigurationCtx checks to see if the configuration file contains the service's sub configuration.
+func (client *fileClient) HasSubConfigurationCtx(_ context.Context, name string) (bool, error) {
+	document, _, err := client.readDocument()
+	if err != nil {
+		return false, fmt.Errorf("checking configuration existence from file failed: %w", err)
+	}
+
+	node, exists := lookup(document, client.fullPath(name))
+	return exists && !isEmpty(node), nil
+}
+
+// PutConfigurationToml puts a full toml configuration into the configuration file
+func (client *fileClient) PutConfigurationToml(configuration *toml.Tree, overwrite bool) error {
+	return client.PutConfigurationTomlCtx(context.Background(), configuration, overwrite)
+}
+
+// PutConfigurationTomlCtx puts a full toml configuration into the configuration file
+func (client *fileClient) PutConfigurationTomlCtx(_ context.Context, configuration *toml.Tree, overwrite bool) error {
+	return client.putConfigurationMap(configuration.ToMap(), overwrite)
+}
+
+// putConfigurationMap merges the values of the map into the configuration under the base path
+func (client *fileClient) putConfigurationMap(values map[string]interface{}, overwrite bool) error {
+	return client.update(func(document map[string]interface{}) (bool, error) {
+		if len(client.configBasePath) == 0 {
+			mergeValues(document, values, overwrite)
+			return true, nil
+		}
+
+		node, exists := lookup(document, client.configBasePath)
+		if table, ok := node.(map[string]interface{}); exists && ok {
+			mergeValues(table, values, overwrite)
+			return true, nil
+		}
+
+		return true, setValue(document, client.configBasePath, values)
+	})
+}
+
+// PutConfiguration puts a full configuration struct into the configuration file
+func (client *fileClient) PutConfiguration(configuration interface{}, overwrite bool) error {
+	return client.PutConfigurationCtx(context.Background(), configuration, overwrite)
+}
+
+// PutConfigurationCtx puts a full configuration struct into the configuration file
+func (client *fileClient) PutConfigurationCtx(ctx context.Context, configuration interface{}, overwrite bool) error {
+	bytes, err := toml.Marshal(configuration)
+	if err != nil {
+		return err
+	}
+
+	tree, err := toml.LoadBytes(bytes)
+	if err != nil {
+		return err
+	}
+
+	return client.PutConfigurationTomlCtx(ctx, tree, overwrite)
+}
+
+// GetConfiguration gets the full configuration from the configuration file into the target configuration struct.
+// Passed in struct is only a reference for decoder, empty struct is ok
+// Returns the configuration in the target struct as interface{}, which caller must cast
+func (client *fileClient) GetConfiguration(configStruct interface{}) (interface{}, error) {
+	return client.GetConfigurationCtx(context.Background(), configStruct)
+}
+
+// GetConfigurationCtx gets the full configuration from the configuration file into the target configuration struct.
+func (client *fileClient) GetConfigurationCtx(_ context.Context, configStruct interface{}) (interface{}, error) {
+	document, _, err := client.readDocument()
+	if err != nil {
+		return nil, err
+	}
+
+	node, exists := lookup(document, client.configBasePath)
+	if !exists || isEmpty(node) {
+		return nil, fmt.Errorf("the configuration file %s doesn't contain configuration for %s: %w",
+			client.filePath, strings.Join(client.configBasePath, "/"), types.ErrNotFound)
+	}
+
+	if err := decode(node, configStruct); err != nil {
+		return nil, err
+	}
+
+	return configStruct, nil
+}
+
+func decode(node interface{}, target interface{}) error {
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		WeaklyTypedInput: true,
+		Result:           target,
+	})
+	if err != nil {
+		return types.NewProviderError(types.ErrDecode, fmt.Errorf("decoding failed, err: %v", err))
+	}
+	if err := decoder.Decode(node); err != nil {
+		return types.NewProviderError(types.ErrDecode, fmt.Errorf("decoding failed, err: %v", err))
+	}
+
+	return nil
+}
+
+// ExportConfiguration writes the configuration from the configuration file to w as a document in the format
+func (client *fileClient) ExportConfiguration(w io.Writer, format types.ConfigurationFormat) error {
+	return client.ExportConfigurationCtx(context.Background(), w, format)
+}
+
+// ExportConfigurationCtx writes the configuration from the configuration file to w as a document in the format. The
+// values keep the types they have in the file, except the dates which are written as strings.
+func (client *fileClient) ExportConfigurationCtx(_ context.Context, w io.Writer, format types.ConfigurationFormat) error {
+	document, _, err := client.readDocument()
+	if err != nil {
+		return err
+	}
+
+	node, exists := lookup(document, client.configBasePath)
+	table, ok := node.(map[string]interface{})
+	if !exists || !ok || isEmpty(table) {
+		return fmt.Errorf("the configuration file %s doesn't contain configuration for %s: %w",
+			client.filePath, strings.Join(client.configBasePath, "/"), types.ErrNotFound)
+	}
+
+	return codec.Write(w, format, codec.Normalize(table).(map[string]interface{}))
+}
+
+// ImportConfiguration merges the configuration document in the format read from r into the configuration file
+func (client *fileClient) ImportConfiguration(r io.Reader, format types.ConfigurationFormat, overwrite bool) error {
+	return client.ImportConfigurationCtx(context.Background(), r, format, overwrite)
+}
+
+// ImportConfigurationCtx merges the configuration document in the format read from r into the configuration file,
+// the same way as PutConfigurationToml
+func (client *fileClient) ImportConfigurationCtx(_ context.Context, r io.Reader, format types.ConfigurationFormat, overwrite bool) error {
+	values, err := codec.Read(r, format)
+	if err != nil {
+		return err
+	}
+
+	return client.putConfigurationMap(values, overwrite)
+}
+
+// PlanConfiguration compares the desired configuration with the configuration file and returns the plan of the
+// changes
+func (client *fileClient) PlanConfiguration(desired interface{}) (types.Plan, error) {
+	return client.PlanConfigurationCtx(context.Background(), desired)
+}
+
+// PlanConfigurationCtx compares the desired configuration, either a struct, a map or a *toml.Tree, with the
+// configuration under the base path in the configuration file and returns the plan of the values to add, modify and
+// remove. The plan is checked against the content of the values in the file.
+func (client *fileClient) PlanConfigurationCtx(_ context.Context, desired interface{}) (types.Plan, error) {
+	values, err := codec.ToMap(desired)
+	if err != nil {
+		return types.Plan{}, err
+	}
+	desiredValues := codec.ToValues("", values)
+
+	document, _, err := client.readDocument()
+	if err != nil {
+		return types.Plan{}, err
+	}
+
+	return planning.New(client.currentValues(document), desiredValues, func(_ string, value []byte) types.Revision {
+		return types.ValueRevision(value)
+	}), nil
+}
+
+// ApplyPlan executes exactly the changes of the plan in the configuration file
+func (client *fileClient) ApplyPlan(plan types.Plan) error {
+	return client.ApplyPlanCtx(context.Background(), plan)
+}
+
+// ApplyPlanCtx executes exactly the changes of the plan in the configuration file, which is written once, unless a
+// value changed by the plan has been changed since the plan was made. The modified values keep the type of the value
+// they replace if they can be converted to it, while the added values are typed as bool or numbers if they are
+// formatted as such.
+func (client *fileClient) ApplyPlanCtx(_ context.Context, plan types.Plan) error {
+	err := client.update(func(document map[string]interface{}) (bool, error) {
+		current := client.currentValues(document)
+		err := planning.Check(plan, func(key string) types.Revision {
+			if value, exists := current[key]; exists {
+				return types.ValueRevision(value)
+			}
+			return types.NoRevision
+		})
+		if err != nil {
+			return false, err
+		}
+
+		for _, change := range plan.Changes.Added {
+			if err := setValue(document, client.fullPath(change.Key), codec.TypedValue(string(change.NewValue))); err != nil {
+				return false, err
+			}
+		}
+		for _, change := range plan.Changes.Modified {
+			keys := client.fullPath(change.Key)
+			existing, _ := lookup(document, keys)
+			if err := setValue(document, keys, parseValue(existing, change.NewValue)); err != nil {
+				return false, err
+			}
+		}
+		for _, change := range plan.Changes.Removed {
+			deleteValue(document, client.fullPath(change.Key))
+		}
+
+		return !plan.IsEmpty(), nil
+	})
+	if err != nil {
+		return fmt.Errorf("unable to apply the plan to file: %w", err)
+	}
+
+	return nil
+}
+
+// currentValues returns the raw values under the base path of the document, keyed relative to the base path
+func (client *fileClient) currentValues(document map[string]interface{}) map[string][]byte {
+	if node, exists := lookup(document, client.configBasePath); exists && isContainer(node) {
+		return codec.ToValues("", node)
+	}
+	return make(map[string][]byte)
+}
+
+// WatchForChanges polls the configuration file for changes of the target key and sends back updates on the update
+// channel. Passed in struct is only a reference for decoder, empty struct is ok
+// Sends the configuration in a new struct of the same type as interface{} on updateChannel, which caller must cast
+func (client *fileClient) WatchForChanges(updateChannel chan<- interface{}, errorChannel chan<- error, configuration interface{}, waitKey string, options ...types.WatchOption) types.WatchHandle {
+	return client.WatchForChangesCtx(context.Background(), updateChannel, errorChannel, configuration, waitKey, options...)
+}
+
+// WatchForChangesCtx polls the configuration file for changes of the target key and sends back updates on the
+// update channel. The current configuration is sent first. The watch stops when either the context is done, it is
+// stopped through the returned handle or StopWatching is called.
+func (client *fileClient) WatchForChangesCtx(ctx context.Context, updateChannel chan<- interface{}, errorChannel chan<- error, configuration interface{}, waitKey string, options ...types.WatchOption) types.WatchHandle {
+	return client.watch(ctx, errorChannel, configuration, waitKey, types.NewWatchOptions(options...), func(ctx context.Context, update types.WatchUpdate) bool {
+		select {
+		case updateChannel <- update.Configuration:
+			return true
+		case <-ctx.Done():
+		case <-client.watchingDoneCtx.Done():
+		}
+		return false
+	})
+}
+
+// WatchForChangeSets polls the configuration file for changes of the target key and sends back an update on the
+// update channel each time it changes, along with the values which have been added, modified or removed.
+// Passed in struct is only a reference for decoder, empty struct is ok
+func (client *fileClient) WatchForChangeSets(updateChannel chan<- types.WatchUpdate, errorChannel chan<- error, configuration interface{}, waitKey string, options ...types.WatchOption) types.WatchHandle {
+	return client.WatchForChangeSetsCtx(context.Background(), updateChannel, errorChannel, configuration, waitKey, options...)
+}
+
+// WatchForChangeSetsCtx polls the configuration file for changes of the target key and sends back an update on the
+// update channel each time it changes, along with the values which have been added, modified or removed. The current
+// configuration is sent first, with all its values added. The watch stops when either the context is done, it is
+// stopped through the returned handle or StopWatching is called.
+func (client *fileClient) WatchForChangeSetsCtx(ctx context.Context, updateChannel chan<- types.WatchUpdate, errorChannel chan<- error, configuration interface{}, waitKey string, options ...types.WatchOption) types.WatchHandle {
+	return client.watch(ctx, errorChannel, configuration, waitKey, types.NewWatchOptions(options...), func(ctx context.Context, update types.WatchUpdate) bool {
+		select {
+		case updateChannel <- update:
+			return true
+		case <-ctx.Done():
+		case <-client.watchingDoneCtx.Done():
+		}
+		return false
+	})
+}
+
+// watch runs a watch polling the target key, which passes the updates to deliver until it returns false
+func (client *fileClient) watch(ctx context.Context, errorChannel chan<- error, configuration interface{}, waitKey string, options types.WatchOptions, deliver watch.DeliverFunc) types.WatchHandle {
+	ctx, handle := watch.NewHandle(ctx)
+	keys := client.fullPath(waitKey)
+	targetType := reflect.TypeOf(configuration)
+	if targetType.Kind() == reflect.Ptr {
+		targetType = targetType.Elem()
+	}
+
+	client.watchingWait.Add(1)
+	go func() {
+		defer client.watchingWait.Done()
+		defer handle.SetStopped()
+
+		// the updates are coalesced over the debounce window of the watch, if set
+		deliver, stopDelivering := watch.Debounce(ctx, options.Debounce, deliver)
+		defer stopDelivering()
+
+		var lastFileHash, lastValueHash [sha256.Size]byte
+		var lastErr string
+		var previous map[string][]byte
+		first := true
+
+		sendError := func(err error) bool {
+			// Only report an error once until the file changes
+			if err.Error() == lastErr {
+				return true
+			}
+			lastErr = err.Error()
+			select {
+			case errorChannel <- err:
+				return true
+			case <-ctx.Done():
+			case <-client.watchingDoneCtx.Done():
+			}
+			return false
+		}
+
+		ticker := time.NewTicker(client.pollInterval)
+		defer ticker.Stop()
+
+		for {
+			document, data, err := client.readDocument()
+			// the file is compared with this read from now on, so the watch is ready before the update is delivered
+			handle.SetReady()
+			fileHash := sha256.Sum256(data)
+			if err != nil {
+				if !sendError(err) {
+					return
+				}
+			} else if first || fileHash != lastFileHash {
+				lastFileHash = fileHash
+				node, exists := lookup(document, keys)
+				valueJson, _ := json.Marshal(node)
+				valueHash := sha256.Sum256(valueJson)
+
+				if exists && (first || valueHash != lastValueHash) {
+					first = false
+					lastValueHash = valueHash
+					update := types.WatchUpdate{Configuration: reflect.New(targetType).Interface()}
+					if err := decode(node, update.Configuration); err != nil {
+						if !sendError(err) {
+							return
+						}
+					} else {
+						current := codec.ToValues("", node)
+						update.Changes = watch.NewChangeSet(previous, current, nil)
+						previous = current
+						lastErr = ""
+						if !deliver(ctx, update) {
+							return
+						}
+					}
+				}
+			}
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			case <-client.watchingDoneCtx.Done():
+				return
+			}
+		}
+	}()
+
+	return handle
+}
+
+// StopWatching causes all WatchForChanges processing to stop and waits until they have exited.
+func (client *fileClient) StopWatching() {
+	client.watchingDone()
+	client.watchingWait.Wait()
+}
+
+// StopWatchingCtx causes all WatchForChanges processing to stop and waits until they have exited
+// or the context is done.
+func (client *fileClient) StopWatchingCtx(ctx context.Context) error {
+	client.watchingDone()
+
+	stopped := make(chan struct{})
+	go func() {
+		client.watchingWait.Wait()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// ConfigurationValueExists checks if a configuration value exists in the configuration file
+func (client *fileClient) ConfigurationValueExists(name string) (bool, error) {
+	return client.ConfigurationValueExistsCtx(context.Background(), name)
+}
+
+// ConfigurationValueExistsCtx checks if a configuration value exists in the configuration file
+func (client *fileClient) ConfigurationValueExistsCtx(_ context.Context, name string) (bool, error) {
+	document, _, err := client.readDocument()
+	if err != nil {
+		return false, fmt.Errorf("unable to check existence of %s in file: %w", name, err)
+	}
+
+	node, exists := lookup(document, client.fullPath(name))
+	return exists && !isContainer(node), nil
+}
+
+// GetConfigurationValue gets a specific configuration value from the configuration file
+func (client *fileClient) GetConfigurationValue(name string) ([]byte, error) {
+	return client.GetConfigurationValueCtx(context.Background(), name)
+}
+
+// GetConfigurationValueCtx gets a specific configuration value from the configuration file.
+// Returns nil without an error if the value doesn't exist.
+func (client *fileClient) GetConfigurationValueCtx(_ context.Context, name string) ([]byte, error) {
+	document, _, err := client.readDocument()
+	if err != nil {
+		return nil, fmt.Errorf("unable to get value for %s from file: %w", name, err)
+	}
+
+	node, exists := lookup(document, client.fullPath(name))
+	if !exists || isContainer(node) {
+		return nil, nil
+	}
+
+	return codec.FormatValue(node), nil
+}
+
+// ListConfigurationKeys lists the keys of the configuration values under the prefix from the configuration file
+func (client *fileClient) ListConfigurationKeys(prefix string, options ...types.ListOption) ([]string, error) {
+	return client.ListConfigurationKeysCtx(context.Background(), prefix, options...)
+}
+
+// ListConfigurationKeysCtx lists the keys of the configuration values under the prefix from the configuration file,
+// sorted and relative to the base path. The elements of the arrays are listed by their index.
+func (client *fileClient) ListConfigurationKeysCtx(_ context.Context, prefix string, options ...types.ListOption) ([]string, error) {
+	lister, values, err := client.values(prefix, options)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list keys under %s from file: %w", prefix, err)
+	}
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	return lister.Keys(keys), nil
+}
+
+// GetConfigurationValues gets the configuration values under the prefix from the configuration file
+func (client *fileClient) GetConfigurationValues(prefix string, options ...types.ListOption) (map[string][]byte, error) {
+	return client.GetConfigurationValuesCtx(context.Background(), prefix, options...)
+}
+
+// GetConfigurationValuesCtx gets the configuration values under the prefix from the configuration file, keyed
+// relative to the base path
+func (client *fileClient) GetConfigurationValuesCtx(_ context.Context, prefix string, options ...types.ListOption) (map[string][]byte, error) {
+	lister, values, err := client.values(prefix, options)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get values under %s from file: %w", prefix, err)
+	}
+
+	return lister.Values(values), nil
+}
+
+// values returns the lister of the prefix along with the raw values under it, keyed by their full path
+func (client *fileClient) values(prefix string, options []types.ListOption) (listing.Lister, map[string][]byte, error) {
+	lister := listing.NewLister(strings.Join(client.configBasePath, "/"), prefix, types.NewListOptions(options...))
+	document, _, err := client.readDocument()
+	if err != nil {
+		return lister, nil, err
+	}
+
+	if node, exists := lookup(document, splitPath(lister.Prefix())); exists {
+		return lister, codec.ToValues(lister.Prefix(), node), nil
+	}
+	return lister, make(map[string][]byte), nil
+}
+
+// PutConfigurationValue puts a specific configuration value into the configuration file
+func (client *fileClient) PutConfigurationValue(name string, value []byte) error {
+	return client.PutConfigurationValueCtx(context.Background(), name, value)
+}
+
+// PutConfigurationValueCtx puts a specific configuration value into the configuration file. The value keeps the type
+// of the value it replaces if it can be converted to it, otherwise it is stored as a string.
+func (client *fileClient) PutConfigurationValueCtx(_ context.Context, name string, value []byte) error {
+	keys := client.fullPath(name)
+	err := client.update(func(document map[string]interface{}) (bool, error) {
+		existing, _ := lookup(document, keys)
+		return true, setValue(document, keys, parseValue(existing, value))
+	})
+	if err != nil {
+		return fmt.Errorf("unable to put value for %s into file: %w", name, err)
+	}
+
+	return nil
+}
+
+// GetConfigurationValueWithVersion gets a specific configuration value from the configuration file along with its
+// revision
+func (client *fileClient) GetConfigurationValueWithVersion(name string) ([]byte, types.Revision, error) {
+	return client.GetConfigurationValueWithVersionCtx(context.Background(), name)
+}
+
+// GetConfigurationValueWithVersionCtx gets a specific configuration value from the configuration file along with its
+// revision, which is derived from the content of the value.
+// Returns nil and types.NoRevision without an error if the value doesn't exist.
+func (client *fileClient) GetConfigurationValueWithVersionCtx(ctx context.Context, name string) ([]byte, types.Revision, error) {
+	value, err := client.GetConfigurationValueCtx(ctx, name)
+	if err != nil || value == nil {
+		return nil, types.NoRevision, err
+	}
+
+	return value, types.ValueRevision(value), nil
+}
+
+// PutConfigurationValueCAS puts a specific configuration value into the configuration file only if its revision is
+// still the given one
+func (client *fileClient) PutConfigurationValueCAS(name string, value []byte, revision types.Revision) error {
+	return client.PutConfigurationValueCASCtx(context.Background(), name, value, revision)
+}
+
+// PutConfigurationValueCASCtx puts a specific configuration value into the configuration file only if its revision is
+// still the given one. The value is compared while the file is locked, so the writes of this client can't interleave.
+func (client *fileClient) PutConfigurationValueCASCtx(_ context.Context, name string, value []byte, revision types.Revision) error {
+	keys := client.fullPath(name)
+	err := client.update(func(document map[string]interface{}) (bool, error) {
+		existing, exists := lookup(document, keys)
+		current := types.NoRevision
+		if exists && !isContainer(existing) {
+			current = types.ValueRevision(codec.FormatValue(existing))
+		}
+		if current != revision {
+			return false, types.NewProviderError(types.ErrConflict, fmt.Errorf("revision %q is stale", revision))
+		}
+		return true, setValue(document, keys, parseValue(existing, value))
+	})
+	if err != nil {
+		return fmt.Errorf("unable to put value for %s into file: %w", name, err)
+	}
+
+	return nil
+}
+
+// DeleteConfigurationValue deletes a specific configuration value from the configuration file
+func (client *fileClient) DeleteConfigurationValue(name string) error {
+	return client.DeleteConfigurationValueCtx(context.Background(), name)
+}
+
+// DeleteConfigurationValueCtx deletes a specific configuration value from the configuration file
+func (client *fileClient) DeleteConfigurationValueCtx(_ context.Context, name string) error {
+	keys := client.fullPath(name)
+	err := client.update(func(document map[string]interface{}) (bool, error) {
+		node, exists := lookup(document, keys)
+		if !exists || isContainer(node) {
+			return false, nil
+		}
+		return deleteValue(document, keys), nil
+	})
+	if err != nil {
+		return fmt.Errorf("unable to delete value for %s from file: %w", name, err)
+	}
+
+	return nil
+}
+
+// DeleteSubConfiguration deletes all configuration values under the sub configuration with the specified name
+func (client *fileClient) DeleteSubConfiguration(name string) error {
+	return client.DeleteSubConfigurationCtx(context.Background(), name)
+}
+
+// DeleteSubConfigurationCtx deletes all configuration values under the sub configuration with the specified name
+func (client *fileClient) DeleteSubConfigurationCtx(_ context.Context, name string) error {
+	keys := client.fullPath(name)
+	err := client.update(func(document map[string]interface{}) (bool, error) {
+		if len(keys) == 0 {
+			// The whole file holds the service's configuration
+			for key := range document {
+				delete(document, key)
+			}
+			return true, nil
+		}
+		return deleteValue(document, keys), nil
+	})
+	if err != nil {
+		return fmt.Errorf("unable to delete sub configuration %s from file: %w", name, err)
+	}
+
+	return nil
+}
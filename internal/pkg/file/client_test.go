@@ -0,0 +1,610 @@
+//
+// Copyright (C) 2026 EdgeX Foundry Contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package file
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/edgexfoundry/go-mod-configuration/v2/internal/pkg/codec"
+	"github.com/edgexfoundry/go-mod-configuration/v2/pkg/types"
+)
+
+const basePath = "edgex/core-data"
+
+type LoggingInfo struct {
+	EnableRemote bool
+	File         string
+}
+
+type TestConfig struct {
+	Logging  LoggingInfo
+	Port     int
+	Host     string
+	LogLevel string
+	Temp     float64
+	Topics   []string
+}
+
+var expectedConfig = TestConfig{
+	Logging:  LoggingInfo{EnableRemote: true, File: "/tmp/core-data.log"},
+	Port:     59880,
+	Host:     "localhost",
+	LogLevel: "INFO",
+	Temp:     36.6,
+	Topics:   []string{"events", "commands"},
+}
+
+var testFiles = map[string]string{
+	"configuration.toml": `
+[other]
+Port = 1
+
+[edgex.core-data]
+Port = 59880
+Host = "localhost"
+LogLevel = "INFO"
+Temp = 36.6
+Topics = ["events", "commands"]
+
+[edgex.core-data.Logging]
+EnableRemote = true
+File = "/tmp/core-data.log"
+`,
+	"configuration.yaml": `
+other:
+  Port: 1
+edgex:
+  core-data:
+    Port: 59880
+    Host: localhost
+    LogLevel: INFO
+    Temp: 36.6
+    Topics:
+      - events
+      - commands
+    Logging:
+      EnableRemote: true
+      File: /tmp/core-data.log
+`,
+	"configuration.json": `{
+  "other": {"Port": 1},
+  "edgex": {
+    "core-data": {
+      "Port": 59880,
+      "Host": "localhost",
+      "LogLevel": "INFO",
+      "Temp": 36.6,
+      "Topics": ["events", "commands"],
+      "Logging": {"EnableRemote": true, "File": "/tmp/core-data.log"}
+    }
+  }
+}`,
+}
+
+func makeFileClient(t *testing.T, fileName string, content string) *fileClient {
+	filePath := filepath.Join(t.TempDir(), fileName)
+	if content != "" {
+		require.NoError(t, os.WriteFile(filePath, []byte(content), 0600))
+	}
+
+	client, err := NewFileClient(types.ServiceConfig{
+		Type:     "file",
+		FilePath: filePath,
+		BasePath: basePath,
+		Optional: map[string]any{PollIntervalKey: "10ms"},
+	})
+	require.NoError(t, err)
+	return client
+}
+
+func TestNewFileClient(t *testing.T) {
+	testCases := []struct {
+		Name          string
+		Config        types.ServiceConfig
+		ExpectedError string
+	}{
+		{Name: "TOML", Config: types.ServiceConfig{FilePath: "configuration.toml"}},
+		{Name: "YAML", Config: types.ServiceConfig{FilePath: "configuration.yml"}},
+		{Name: "Poll interval", Config: types.ServiceConfig{FilePath: "configuration.json", Optional: map[string]any{PollIntervalKey: time.Second}}},
+		{Name: "No file path", Config: types.ServiceConfig{}, ExpectedError: "FilePath not set"},
+		{Name: "Unknown format", Config: types.ServiceConfig{FilePath: "configuration.ini"}, ExpectedError: "unsupported configuration file format"},
+		{Name: "Bad poll interval", Config: types.ServiceConfig{FilePath: "configuration.toml", Optional: map[string]any{PollIntervalKey: "soon"}}, ExpectedError: "invalid PollInterval"},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.Name, func(t *testing.T) {
+			_, err := NewFileClient(test.Config)
+			if test.ExpectedError != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), test.ExpectedError)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestGetConfiguration(t *testing.T) {
+	for fileName, content := range testFiles {
+		t.Run(fileName, func(t *testing.T) {
+			client := makeFileClient(t, fileName, content)
+
+			exists, err := client.HasConfiguration()
+			require.NoError(t, err)
+			assert.True(t, exists)
+
+			exists, err = client.HasSubConfiguration("Logging")
+			require.NoError(t, err)
+			assert.True(t, exists)
+
+			result, err := client.GetConfiguration(&TestConfig{})
+			require.NoError(t, err)
+			actual, ok := result.(*TestConfig)
+			require.True(t, ok)
+			assert.Equal(t, expectedConfig, *actual)
+		})
+	}
+}
+
+func TestGetConfigurationNotFound(t *testing.T) {
+	client := makeFileClient(t, "configuration.toml", "")
+
+	exists, err := client.HasConfiguration()
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	_, err = client.GetConfiguration(&TestConfig{})
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, types.ErrNotFound))
+}
+
+func TestGetConfigurationDecodeError(t *testing.T) {
+	client := makeFileClient(t, "configuration.toml", "[edgex.core-data]\nPort = \"not a number\"\n")
+
+	_, err := client.GetConfiguration(&TestConfig{})
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, types.ErrDecode))
+
+	client = makeFileClient(t, "configuration.json", "{ not json")
+	_, err = client.GetConfiguration(&TestConfig{})
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, types.ErrDecode))
+}
+
+func TestGetConfigurationValue(t *testing.T) {
+	testCases := []struct {
+		Name     string
+		Key      string
+		Expected []byte
+	}{
+		{Name: "String", Key: "Host", Expected: []byte("localhost")},
+		{Name: "Integer", Key: "Port", Expected: []byte("59880")},
+		{Name: "Float", Key: "Temp", Expected: []byte("36.6")},
+		{Name: "Nested", Key: "Logging/EnableRemote", Expected: []byte("true")},
+		{Name: "Array element", Key: "Topics/1", Expected: []byte("commands")},
+		{Name: "Table", Key: "Logging", Expected: nil},
+		{Name: "Missing", Key: "Missing", Expected: nil},
+	}
+
+	for fileName, content := range testFiles {
+		client := makeFileClient(t, fileName, content)
+		for _, test := range testCases {
+			t.Run(fileName+"/"+test.Name, func(t *testing.T) {
+				actual, err := client.GetConfigurationValue(test.Key)
+				require.NoError(t, err)
+				assert.Equal(t, test.Expected, actual)
+
+				exists, err := client.ConfigurationValueExists(test.Key)
+				require.NoError(t, err)
+				assert.Equal(t, test.Expected != nil, exists)
+			})
+		}
+	}
+}
+
+func TestPutConfigurationValue(t *testing.T) {
+	for fileName, content := range testFiles {
+		t.Run(fileName, func(t *testing.T) {
+			client := makeFileClient(t, fileName, content)
+
+			require.NoError(t, client.PutConfigurationValue("Port", []byte("59881")))
+			require.NoError(t, client.PutConfigurationValue("Logging/File", []byte("/var/log/core-data.log")))
+			require.NoError(t, client.PutConfigurationValue("Writable/LogLevel", []byte("DEBUG")))
+
+			// Re-read the file with a new client to make sure the values have been written
+			reader, err := NewFileClient(types.ServiceConfig{FilePath: client.filePath, BasePath: basePath})
+			require.NoError(t, err)
+
+			result, err := reader.GetConfiguration(&TestConfig{})
+			require.NoError(t, err)
+			actual := result.(*TestConfig)
+			assert.Equal(t, 59881, actual.Port)
+			assert.Equal(t, "/var/log/core-data.log", actual.Logging.File)
+			assert.Equal(t, "INFO", actual.LogLevel)
+
+			value, err := reader.GetConfigurationValue("Writable/LogLevel")
+			require.NoError(t, err)
+			assert.Equal(t, []byte("DEBUG"), value)
+
+			// The values outside the base path are kept and no temporary file is left behind
+			document, _, err := reader.readDocument()
+			require.NoError(t, err)
+			other, _ := lookup(document, []string{"other", "Port"})
+			assert.EqualValues(t, "1", codec.FormatValue(other))
+			entries, err := os.ReadDir(filepath.Dir(client.filePath))
+			require.NoError(t, err)
+			assert.Len(t, entries, 1)
+		})
+	}
+}
+
+func TestPutConfigurationValueKeepsType(t *testing.T) {
+	client := makeFileClient(t, "configuration.toml", testFiles["configuration.toml"])
+
+	require.NoError(t, client.PutConfigurationValue("Port", []byte("59881")))
+	require.NoError(t, client.PutConfigurationValue("Host", []byte("12")))
+
+	document, _, err := client.readDocument()
+	require.NoError(t, err)
+	port, _ := lookup(document, client.fullPath("Port"))
+	assert.Equal(t, int64(59881), port)
+	host, _ := lookup(document, client.fullPath("Host"))
+	assert.Equal(t, "12", host)
+}
+
+func TestPutConfigurationValueCAS(t *testing.T) {
+	client := makeFileClient(t, "configuration.toml", testFiles["configuration.toml"])
+
+	value, revision, err := client.GetConfigurationValueWithVersion("Port")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("59880"), value)
+
+	// a write from another client makes the revision stale
+	other, err := NewFileClient(types.ServiceConfig{FilePath: client.filePath, BasePath: basePath})
+	require.NoError(t, err)
+	require.NoError(t, other.PutConfigurationValue("Port", []byte("59881")))
+	err = client.PutConfigurationValueCAS("Port", []byte("59882"), revision)
+	assert.True(t, errors.Is(err, types.ErrConflict), "unexpected error: %v", err)
+
+	_, revision, err = client.GetConfigurationValueWithVersion("Port")
+	require.NoError(t, err)
+	require.NoError(t, client.PutConfigurationValueCAS("Port", []byte("59882"), revision))
+	value, err = other.GetConfigurationValue("Port")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("59882"), value)
+
+	// no revision only creates the value
+	_, revision, err = client.GetConfigurationValueWithVersion("Writable/LogLevel")
+	require.NoError(t, err)
+	assert.Equal(t, types.NoRevision, revision)
+	require.NoError(t, client.PutConfigurationValueCAS("Writable/LogLevel", []byte("DEBUG"), types.NoRevision))
+	err = client.PutConfigurationValueCAS("Writable/LogLevel", []byte("TRACE"), types.NoRevision)
+	assert.True(t, errors.Is(err, types.ErrConflict), "unexpected error: %v", err)
+}
+
+func TestListConfigurationKeys(t *testing.T) {
+	for fileName, content := range testFiles {
+		t.Run(fileName, func(t *testing.T) {
+			client := makeFileClient(t, fileName, content)
+
+			keys, err := client.ListConfigurationKeys("")
+			require.NoError(t, err)
+			assert.Equal(t, []string{
+				"Host",
+				"LogLevel",
+				"Logging/EnableRemote",
+				"Logging/File",
+				"Port",
+				"Temp",
+				"Topics/0",
+				"Topics/1",
+			}, keys)
+
+			keys, err = client.ListConfigurationKeys("", types.WithDepth(1))
+			require.NoError(t, err)
+			assert.Equal(t, []string{"Host", "LogLevel", "Logging/", "Port", "Temp", "Topics/"}, keys)
+
+			values, err := client.GetConfigurationValues("Logging", types.WithSeparator("."))
+			require.NoError(t, err)
+			assert.Equal(t, map[string][]byte{
+				"Logging.EnableRemote": []byte("true"),
+				"Logging.File":         []byte("/tmp/core-data.log"),
+			}, values)
+
+			values, err = client.GetConfigurationValues("Clients")
+			require.NoError(t, err)
+			assert.Empty(t, values)
+		})
+	}
+}
+
+func TestExportImportConfiguration(t *testing.T) {
+	for fileName, content := range testFiles {
+		t.Run(fileName, func(t *testing.T) {
+			client := makeFileClient(t, fileName, content)
+
+			var exported bytes.Buffer
+			require.NoError(t, client.ExportConfiguration(&exported, types.FormatTOML))
+
+			// the values keep their types across the formats
+			target := makeFileClient(t, "configuration.yaml", "")
+			require.NoError(t, target.ImportConfiguration(bytes.NewReader(exported.Bytes()), types.FormatTOML, false))
+			result, err := target.GetConfiguration(&TestConfig{})
+			require.NoError(t, err)
+			assert.Equal(t, expectedConfig, *result.(*TestConfig))
+
+			var restored bytes.Buffer
+			require.NoError(t, target.ExportConfiguration(&restored, types.FormatTOML))
+			assert.Equal(t, exported.String(), restored.String())
+		})
+	}
+
+	client := makeFileClient(t, "configuration.json", "")
+	err := client.ExportConfiguration(&bytes.Buffer{}, types.FormatJSON)
+	assert.True(t, errors.Is(err, types.ErrNotFound), "unexpected error: %v", err)
+}
+
+func TestPlanConfiguration(t *testing.T) {
+	client := makeFileClient(t, "configuration.toml", testFiles["configuration.toml"])
+
+	desired := map[string]interface{}{
+		"Port":     59881,
+		"Host":     "localhost",
+		"LogLevel": "INFO",
+		"Topics":   []interface{}{"events", "commands"},
+		"Logging":  map[string]interface{}{"EnableRemote": true, "File": "NONE"},
+		"Writable": map[string]interface{}{"Insecure": true},
+	}
+	plan, err := client.PlanConfiguration(desired)
+	require.NoError(t, err)
+	assert.Equal(t, []types.KeyChange{{Key: "Writable/Insecure", NewValue: []byte("true")}}, plan.Changes.Added)
+	assert.Equal(t, []types.KeyChange{
+		{Key: "Logging/File", OldValue: []byte("/tmp/core-data.log"), NewValue: []byte("NONE")},
+		{Key: "Port", OldValue: []byte("59880"), NewValue: []byte("59881")},
+	}, plan.Changes.Modified)
+	assert.Equal(t, []types.KeyChange{{Key: "Temp", OldValue: []byte("36.6")}}, plan.Changes.Removed)
+
+	// nothing is changed until the plan is applied
+	value, err := client.GetConfigurationValue("Port")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("59880"), value)
+
+	plan, err = client.PlanConfiguration(expectedConfig)
+	require.NoError(t, err)
+	assert.True(t, plan.IsEmpty())
+}
+
+func TestApplyPlan(t *testing.T) {
+	client := makeFileClient(t, "configuration.toml", testFiles["configuration.toml"])
+
+	desired := map[string]interface{}{
+		"Port":     59881,
+		"Host":     "localhost",
+		"LogLevel": "INFO",
+		"Topics":   []interface{}{"events", "commands"},
+		"Logging":  map[string]interface{}{"EnableRemote": true, "File": "/tmp/core-data.log"},
+		"Writable": map[string]interface{}{"Insecure": true},
+	}
+	plan, err := client.PlanConfiguration(desired)
+	require.NoError(t, err)
+	require.NoError(t, client.ApplyPlan(plan))
+
+	plan, err = client.PlanConfiguration(desired)
+	require.NoError(t, err)
+	assert.True(t, plan.IsEmpty())
+
+	// the modified values keep their type and the added ones are typed
+	document, _, err := client.readDocument()
+	require.NoError(t, err)
+	port, _ := lookup(document, client.fullPath("Port"))
+	assert.Equal(t, int64(59881), port)
+	insecure, _ := lookup(document, client.fullPath("Writable/Insecure"))
+	assert.Equal(t, true, insecure)
+	_, exists := lookup(document, client.fullPath("Temp"))
+	assert.False(t, exists)
+	otherPort, _ := lookup(document, []string{"other", "Port"})
+	assert.Equal(t, int64(1), otherPort)
+
+	// a write from another client since the plan was made prevents applying any of it
+	desired["Port"] = 59882
+	desired["Host"] = "127.0.0.1"
+	plan, err = client.PlanConfiguration(desired)
+	require.NoError(t, err)
+	other, err := NewFileClient(types.ServiceConfig{FilePath: client.filePath, BasePath: basePath})
+	require.NoError(t, err)
+	require.NoError(t, other.PutConfigurationValue("Host", []byte("edgex-core-data")))
+	err = client.ApplyPlan(plan)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, types.ErrConflict), "unexpected error: %v", err)
+
+	value, err := client.GetConfigurationValue("Port")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("59881"), value)
+}
+
+func TestPutConfiguration(t *testing.T) {
+	client := makeFileClient(t, "configuration.yaml", "")
+
+	require.NoError(t, client.PutConfiguration(&expectedConfig, false))
+
+	result, err := client.GetConfiguration(&TestConfig{})
+	require.NoError(t, err)
+	assert.Equal(t, expectedConfig, *result.(*TestConfig))
+
+	// Existing values are only replaced when overwrite is set
+	changed := expectedConfig
+	changed.Port = 1234
+	changed.Logging.File = "changed.log"
+
+	require.NoError(t, client.PutConfiguration(&changed, false))
+	result, err = client.GetConfiguration(&TestConfig{})
+	require.NoError(t, err)
+	assert.Equal(t, expectedConfig, *result.(*TestConfig))
+
+	require.NoError(t, client.PutConfiguration(&changed, true))
+	result, err = client.GetConfiguration(&TestConfig{})
+	require.NoError(t, err)
+	assert.Equal(t, changed, *result.(*TestConfig))
+}
+
+func TestDeleteConfigurationValue(t *testing.T) {
+	client := makeFileClient(t, "configuration.json", testFiles["configuration.json"])
+
+	require.NoError(t, client.DeleteConfigurationValue("Logging/File"))
+	exists, err := client.ConfigurationValueExists("Logging/File")
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	// Deleting a missing value or a table is a no-op
+	require.NoError(t, client.DeleteConfigurationValue("Missing"))
+	require.NoError(t, client.DeleteConfigurationValue("Logging"))
+	exists, err = client.ConfigurationValueExists("Logging/EnableRemote")
+	require.NoError(t, err)
+	assert.True(t, exists)
+}
+
+func TestDeleteSubConfiguration(t *testing.T) {
+	client := makeFileClient(t, "configuration.toml", testFiles["configuration.toml"])
+
+	require.NoError(t, client.DeleteSubConfiguration("Logging"))
+	exists, err := client.HasSubConfiguration("Logging")
+	require.NoError(t, err)
+	assert.False(t, exists)
+	exists, err = client.ConfigurationValueExists("Port")
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	require.NoError(t, client.DeleteSubConfiguration(""))
+	exists, err = client.HasConfiguration()
+	require.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func TestWatchForChanges(t *testing.T) {
+	client := makeFileClient(t, "configuration.toml", testFiles["configuration.toml"])
+
+	updateChannel := make(chan interface{})
+	errorChannel := make(chan error)
+	client.WatchForChanges(updateChannel, errorChannel, &LoggingInfo{}, "/Logging")
+	defer client.StopWatching()
+
+	receive := func() *LoggingInfo {
+		select {
+		case update := <-updateChannel:
+			actual, ok := update.(*LoggingInfo)
+			require.True(t, ok)
+			return actual
+		case err := <-errorChannel:
+			require.NoError(t, err)
+		case <-time.After(5 * time.Second):
+			require.Fail(t, "timed out waiting for update")
+		}
+		return nil
+	}
+
+	// The current configuration is sent first
+	assert.Equal(t, expectedConfig.Logging, *receive())
+
+	// Changes outside of the watched key aren't sent
+	require.NoError(t, client.PutConfigurationValue("Port", []byte("1")))
+	require.NoError(t, client.PutConfigurationValue("Logging/File", []byte("changed.log")))
+	assert.Equal(t, LoggingInfo{EnableRemote: true, File: "changed.log"}, *receive())
+
+	// Changes written by other processes are picked up as well
+	require.NoError(t, os.WriteFile(client.filePath, []byte("[edgex.core-data.Logging]\nEnableRemote = \"yes\"\n"), 0600))
+	select {
+	case err := <-errorChannel:
+		assert.True(t, errors.Is(err, types.ErrDecode))
+	case <-updateChannel:
+		require.Fail(t, "update not expected for invalid configuration")
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "timed out waiting for error")
+	}
+
+	require.NoError(t, os.WriteFile(client.filePath, []byte("[edgex.core-data.Logging]\nEnableRemote = false\n"), 0600))
+	assert.Equal(t, LoggingInfo{}, *receive())
+}
+
+func TestWatchForChangeSets(t *testing.T) {
+	for fileName, content := range testFiles {
+		t.Run(fileName, func(t *testing.T) {
+			client := makeFileClient(t, fileName, content)
+
+			updateChannel := make(chan types.WatchUpdate)
+			errorChannel := make(chan error)
+			handle := client.WatchForChangeSets(updateChannel, errorChannel, &LoggingInfo{}, "Logging")
+			defer handle.Stop()
+
+			receive := func() types.WatchUpdate {
+				select {
+				case update := <-updateChannel:
+					return update
+				case err := <-errorChannel:
+					require.NoError(t, err)
+				case <-time.After(5 * time.Second):
+					require.Fail(t, "timed out waiting for update")
+				}
+				return types.WatchUpdate{}
+			}
+
+			// All the values are added in the first update
+			update := receive()
+			select {
+			case <-handle.Ready():
+			default:
+				require.Fail(t, "watch expected to be ready once the current configuration is sent")
+			}
+			assert.Equal(t, &expectedConfig.Logging, update.Configuration)
+			assert.Equal(t, types.ChangeSet{Added: []types.KeyChange{
+				{Key: "EnableRemote", NewValue: []byte("true")},
+				{Key: "File", NewValue: []byte("/tmp/core-data.log")},
+			}}, update.Changes)
+
+			require.NoError(t, client.PutConfigurationValue("Logging/File", []byte("changed.log")))
+			update = receive()
+			assert.Equal(t, &LoggingInfo{EnableRemote: true, File: "changed.log"}, update.Configuration)
+			assert.Equal(t, types.ChangeSet{Modified: []types.KeyChange{
+				{Key: "File", OldValue: []byte("/tmp/core-data.log"), NewValue: []byte("changed.log")},
+			}}, update.Changes)
+
+			require.NoError(t, client.DeleteConfigurationValue("Logging/EnableRemote"))
+			update = receive()
+			assert.Equal(t, &LoggingInfo{File: "changed.log"}, update.Configuration)
+			assert.Equal(t, types.ChangeSet{Removed: []types.KeyChange{
+				{Key: "EnableRemote", OldValue: []byte("true")},
+			}}, update.Changes)
+		})
+	}
+}
+
+func TestStopWatching(t *testing.T) {
+	client := makeFileClient(t, "configuration.toml", testFiles["configuration.toml"])
+
+	updateChannel := make(chan interface{})
+	errorChannel := make(chan error)
+	client.WatchForChanges(updateChannel, errorChannel, &TestConfig{}, "")
+
+	stopped := make(chan struct{})
+	go func() {
+		// The watch is blocked sending the first update, which must not prevent it from stopping
+		client.StopWatching()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "watch not stopped")
+	}
+}
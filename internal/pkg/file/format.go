@@ -0,0 +1,28 @@
+//
+// Copyright (C) 2026 EdgeX Foundry Contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package file
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/edgexfoundry/go-mod-configuration/v2/pkg/types"
+)
+
+// formatFromPath returns the format of the configuration file from its extension
+func formatFromPath(filePath string) (types.ConfigurationFormat, error) {
+	switch strings.ToLower(filepath.Ext(filePath)) {
+	case ".toml":
+		return types.FormatTOML, nil
+	case ".yaml", ".yml":
+		return types.FormatYAML, nil
+	case ".json":
+		return types.FormatJSON, nil
+	default:
+		return "", fmt.Errorf("unsupported configuration file format for %s, expected .toml, .yaml, .yml or .json", filePath)
+	}
+}
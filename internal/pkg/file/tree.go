@@ -0,0 +1,194 @@
+//
+// Copyright (C) 2026 EdgeX Foundry Contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package file
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// splitPath splits the '/' separated key path into its elements, ignoring empty elements
+func splitPath(keyPath string) []string {
+	var keys []string
+	for _, key := range strings.Split(keyPath, "/") {
+		if key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// lookup returns the node found at the keys, the elements of slices are addressed by their index
+func lookup(node interface{}, keys []string) (interface{}, bool) {
+	for _, key := range keys {
+		switch current := node.(type) {
+		case map[string]interface{}:
+			child, ok := current[key]
+			if !ok {
+				return nil, false
+			}
+			node = child
+		case []interface{}:
+			index, err := strconv.Atoi(key)
+			if err != nil || index < 0 || index >= len(current) {
+				return nil, false
+			}
+			node = current[index]
+		default:
+			return nil, false
+		}
+	}
+
+	return node, true
+}
+
+// isContainer returns true if the node holds other nodes rather than a value
+func isContainer(node interface{}) bool {
+	switch node.(type) {
+	case map[string]interface{}, []interface{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// isEmpty returns true if the node doesn't hold any value
+func isEmpty(node interface{}) bool {
+	switch current := node.(type) {
+	case map[string]interface{}:
+		return len(current) == 0
+	case []interface{}:
+		return len(current) == 0
+	default:
+		return false
+	}
+}
+
+// setValue sets the value at the keys, creating the missing tables on the way
+func setValue(document map[string]interface{}, keys []string, value interface{}) error {
+	if len(keys) == 0 {
+		return fmt.Errorf("unable to set a value without a key")
+	}
+
+	var node interface{} = document
+	for i, key := range keys {
+		last := i == len(keys)-1
+
+		switch current := node.(type) {
+		case map[string]interface{}:
+			if last {
+				current[key] = value
+				return nil
+			}
+			child, ok := current[key]
+			if !ok || !isContainer(child) {
+				child = make(map[string]interface{})
+				current[key] = child
+			}
+			node = child
+		case []interface{}:
+			index, err := strconv.Atoi(key)
+			if err != nil || index < 0 || index >= len(current) {
+				return fmt.Errorf("index %s is out of range for %s", key, strings.Join(keys[:i], "/"))
+			}
+			if last {
+				current[index] = value
+				return nil
+			}
+			if !isContainer(current[index]) {
+				current[index] = make(map[string]interface{})
+			}
+			node = current[index]
+		}
+	}
+
+	return nil
+}
+
+// deleteValue removes the node at the keys. Returns false if there was no such node.
+// Elements can't be removed from the middle of slices, so they are only cleared.
+func deleteValue(document map[string]interface{}, keys []string) bool {
+	if len(keys) == 0 {
+		return false
+	}
+
+	parent, ok := lookup(document, keys[:len(keys)-1])
+	if !ok {
+		return false
+	}
+
+	key := keys[len(keys)-1]
+	switch current := parent.(type) {
+	case map[string]interface{}:
+		if _, ok := current[key]; !ok {
+			return false
+		}
+		delete(current, key)
+		return true
+	case []interface{}:
+		index, err := strconv.Atoi(key)
+		if err != nil || index < 0 || index >= len(current) {
+			return false
+		}
+		current[index] = nil
+		return true
+	default:
+		return false
+	}
+}
+
+// mergeValues copies the values from src into dst. Existing values are only replaced when overwrite is true.
+func mergeValues(dst map[string]interface{}, src map[string]interface{}, overwrite bool) {
+	for key, value := range src {
+		existing, exists := dst[key]
+		srcTable, srcIsTable := value.(map[string]interface{})
+		dstTable, dstIsTable := existing.(map[string]interface{})
+
+		switch {
+		case srcIsTable && dstIsTable:
+			mergeValues(dstTable, srcTable, overwrite)
+		case !exists || overwrite:
+			dst[key] = value
+		}
+	}
+}
+
+// parseValue converts the raw value to the type of the value it replaces, so the file keeps its types.
+// The raw value is stored as a string if it can't be converted or there is no value to replace.
+func parseValue(existing interface{}, raw []byte) interface{} {
+	text := string(raw)
+
+	switch existing.(type) {
+	case int:
+		if value, err := strconv.Atoi(text); err == nil {
+			return value
+		}
+	case int64:
+		if value, err := strconv.ParseInt(text, 10, 64); err == nil {
+			return value
+		}
+	case uint64:
+		if value, err := strconv.ParseUint(text, 10, 64); err == nil {
+			return value
+		}
+	case float64:
+		if value, err := strconv.ParseFloat(text, 64); err == nil {
+			return value
+		}
+	case bool:
+		if value, err := strconv.ParseBool(text); err == nil {
+			return value
+		}
+	case json.Number:
+		if _, err := strconv.ParseFloat(text, 64); err == nil {
+			return json.Number(text)
+		}
+	}
+
+	return text
+}
@@ -6,26 +6,33 @@
 package api
 
 import (
-	"errors"
+	"context"
+	netHttp "net/http"
 
 	"github.com/edgexfoundry/go-mod-configuration/v2/internal/pkg/keeper/utils/http"
+	"github.com/edgexfoundry/go-mod-configuration/v2/pkg/types"
 )
 
 type Caller struct {
-	baseUrl string
+	baseUrl      string
+	retryPolicy  types.RetryPolicy
+	httpClient   *netHttp.Client
+	authInjector http.AuthenticationInjector
 }
 
-// NewCaller creates an instance of Caller
-func NewCaller(baseUrl string) *Caller {
+// NewCaller creates an instance of Caller. The requests which fail with a retryable error are sent again according
+// to the retryPolicy. All the requests are sent with the httpClient, i.e. configured for TLS, or the default client if
+// nil. The authInjector is optional and adds the authentication data to each request sent to Core Keeper.
+func NewCaller(baseUrl string, retryPolicy types.RetryPolicy, httpClient *netHttp.Client, authInjector http.AuthenticationInjector) *Caller {
 	return &Caller{
-		baseUrl: baseUrl,
+		baseUrl:      baseUrl,
+		retryPolicy:  retryPolicy,
+		httpClient:   httpClient,
+		authInjector: authInjector,
 	}
 }
 
-func (c *Caller) Ping() error {
-	errResp := http.GetRequest(nil, c.baseUrl, ApiPingRoute, nil)
-	if errResp.StatusCode != 0 {
-		return errors.New(errResp.Message)
-	}
-	return nil
+// Ping checks if Core Keeper is reachable and responds. The ping isn't retried so that it reports the current state.
+func (c *Caller) Ping(ctx context.Context) error {
+	return http.GetRequest(ctx, nil, c.baseUrl, ApiPingRoute, nil, types.RetryPolicy{}, c.httpClient, c.authInjector)
 }
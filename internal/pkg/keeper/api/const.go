@@ -19,4 +19,6 @@ const (
 	KeyOnly     = "keyOnly"
 	Plaintext   = "plaintext"
 	PrefixMatch = "prefixMatch"
+	// Revision is the revision the stored value must still have to be deleted
+	Revision = "revision"
 )
@@ -6,13 +6,15 @@
 package api
 
 import (
+	"context"
 	"errors"
-	"net/http"
 	"net/url"
 	"path"
+	"strings"
 
 	"github.com/edgexfoundry/go-mod-configuration/v2/internal/pkg/keeper/dtos"
 	httpUtils "github.com/edgexfoundry/go-mod-configuration/v2/internal/pkg/keeper/utils/http"
+	"github.com/edgexfoundry/go-mod-configuration/v2/pkg/types"
 )
 
 // KV is used to manipulate the K/V API
@@ -25,58 +27,58 @@ func (c *Caller) KV() *KV {
 	return &KV{c}
 }
 
-// Get is used to lookup a single key. The returned pointer
-// to the KVPair will be nil if the key does not exist.
-func (k *KV) Get(key string) (res dtos.MultiKVResponse, err error) {
+// Get is used to lookup all the keys starting with the key. An error matching types.ErrNotFound
+// is returned if no such key exists.
+func (k *KV) Get(ctx context.Context, key string) (res dtos.MultiKVResponse, err error) {
 	pathParams := url.Values{}
 	pathParams.Add(Plaintext, "true")
 
 	url := path.Join(ApiKVRoute, key)
-	errResp := httpUtils.GetRequest(&res, k.c.baseUrl, url, pathParams)
-	if errResp.StatusCode != 0 {
-		return res, errors.New(errResp.Message)
-	}
-	return res, nil
+	err = httpUtils.GetRequest(ctx, &res, k.c.baseUrl, url, pathParams, k.c.retryPolicy, k.c.httpClient, k.c.authInjector)
+	return res, err
 }
 
-func (k *KV) Keys(key string) (res dtos.MultiKeyResponse, err error) {
+// Keys is used to list all the keys starting with the key. No keys and no error are returned if no such key exists.
+func (k *KV) Keys(ctx context.Context, key string) (res dtos.MultiKeyResponse, err error) {
 	pathParams := url.Values{}
 	pathParams.Add(KeyOnly, "true")
 
 	url := path.Join(ApiKVRoute, key)
-	errResp := httpUtils.GetRequest(&res, k.c.baseUrl, url, pathParams)
-	if errResp.StatusCode == http.StatusNotFound {
+	err = httpUtils.GetRequest(ctx, &res, k.c.baseUrl, url, pathParams, k.c.retryPolicy, k.c.httpClient, k.c.authInjector)
+	if errors.Is(err, types.ErrNotFound) {
 		return res, nil
 	}
-	if errResp.StatusCode != 0 {
-		return res, errors.New(errResp.Message)
-	}
-	return res, nil
+	return res, err
 }
 
 // Put create/update a single key with value
-func (k *KV) Put(key string, data interface{}) error {
-	keyPath := path.Join(ApiKVRoute, key)
+func (k *KV) Put(ctx context.Context, key string, data interface{}) error {
+	return k.put(ctx, key, nil, data, nil)
+}
 
-	value := data
-	if byteArray, ok := value.([]byte); ok {
-		value = string(byteArray)
-	}
-	request := dtos.AddKeysRequest{
-		Value: value,
-	}
-	errResp := httpUtils.PutRequest(nil, k.c.baseUrl, keyPath, nil, request)
-	if errResp.StatusCode != 0 {
-		return errors.New(errResp.Message)
-	}
-	return nil
+// PutCAS create/update a single key with value only if the stored value still has the revision, types.NoRevision
+// only creates it. Core Keeper checks the revision with the update and rejects it with an error matching
+// types.ErrConflict otherwise.
+func (k *KV) PutCAS(ctx context.Context, key string, data interface{}, revision types.Revision) error {
+	return k.put(ctx, key, nil, data, map[string]types.Revision{key: revision})
 }
 
 // PutKeys create/update all keys under a prefix with value
-func (k *KV) PutKeys(key string, data interface{}) error {
-	keyPath := path.Join(ApiKVRoute, key)
+func (k *KV) PutKeys(ctx context.Context, key string, data interface{}) error {
+	return k.PutKeysCAS(ctx, key, data, nil)
+}
+
+// PutKeysCAS create/update all keys under a prefix with value only if the stored values of the full keys of the
+// revisions still have them. None of the keys is updated and an error matching types.ErrConflict is returned otherwise.
+func (k *KV) PutKeysCAS(ctx context.Context, key string, data interface{}, revisions map[string]types.Revision) error {
 	urlParams := url.Values{}
 	urlParams.Add(Flatten, "true")
+	return k.put(ctx, key, urlParams, data, revisions)
+}
+
+func (k *KV) put(ctx context.Context, key string, urlParams url.Values, data interface{}, revisions map[string]types.Revision) error {
+	keyPath := path.Join(ApiKVRoute, key)
+
 	value := data
 	if byteArray, ok := value.([]byte); ok {
 		value = string(byteArray)
@@ -84,22 +86,51 @@ func (k *KV) PutKeys(key string, data interface{}) error {
 	request := dtos.AddKeysRequest{
 		Value: value,
 	}
-	errResp := httpUtils.PutRequest(nil, k.c.baseUrl, keyPath, urlParams, request)
-	if errResp.StatusCode != 0 {
-		return errors.New(errResp.Message)
+	if revisions != nil {
+		request.Revisions = make(map[string]string, len(revisions))
+		for revisionKey, revision := range revisions {
+			request.Revisions[revisionKey] = string(revision)
+		}
 	}
-	return nil
+	return httpUtils.PutRequest(ctx, nil, k.c.baseUrl, keyPath, urlParams, request, k.c.retryPolicy, k.c.httpClient, k.c.authInjector)
 }
 
-// DeleteKeys delete all keys under a prefix with value
-func (k *KV) DeleteKeys(key string) error {
+// Delete deletes a single key. Deleting a key that doesn't exist is not an error.
+func (k *KV) Delete(ctx context.Context, key string) error {
 	keyPath := path.Join(ApiKVRoute, key)
+
+	err := httpUtils.DeleteRequest(ctx, nil, k.c.baseUrl, keyPath, nil, k.c.retryPolicy, k.c.httpClient, k.c.authInjector)
+	if errors.Is(err, types.ErrNotFound) {
+		return nil
+	}
+	return err
+}
+
+// DeleteCAS deletes a single key only if the stored value still has the revision. Core Keeper checks the revision
+// with the deletion and rejects it with an error matching types.ErrConflict otherwise, including when the key doesn't
+// exist anymore.
+func (k *KV) DeleteCAS(ctx context.Context, key string, revision types.Revision) error {
+	keyPath := path.Join(ApiKVRoute, key)
+	urlParams := url.Values{}
+	urlParams.Add(Revision, string(revision))
+
+	return httpUtils.DeleteRequest(ctx, nil, k.c.baseUrl, keyPath, urlParams, k.c.retryPolicy, k.c.httpClient, k.c.authInjector)
+}
+
+// DeleteKeys delete all keys under a prefix with value. Deleting a prefix without any keys is not an error.
+// The prefix is matched as a plain string, so it must end with KeyDelimiter to only delete the keys of a section.
+func (k *KV) DeleteKeys(ctx context.Context, key string) error {
+	keyPath := path.Join(ApiKVRoute, key)
+	// path.Join drops the trailing delimiter which limits the prefix to the section
+	if strings.HasSuffix(key, KeyDelimiter) {
+		keyPath += KeyDelimiter
+	}
 	urlParams := url.Values{}
 	urlParams.Add(PrefixMatch, "true")
 
-	errResp := httpUtils.DeleteRequest(nil, k.c.baseUrl, keyPath, urlParams)
-	if errResp.StatusCode != 0 {
-		return errors.New(errResp.Message)
+	err := httpUtils.DeleteRequest(ctx, nil, k.c.baseUrl, keyPath, urlParams, k.c.retryPolicy, k.c.httpClient, k.c.authInjector)
+	if errors.Is(err, types.ErrNotFound) {
+		return nil
 	}
-	return nil
+	return err
 }
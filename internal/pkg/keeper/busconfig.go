@@ -0,0 +1,223 @@
+//
+// Copyright (C) 2022 IOTech Ltd
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package keeper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mitchellh/mapstructure"
+
+	"github.com/edgexfoundry/go-mod-configuration/v2/internal/pkg/keeper/models"
+	"github.com/edgexfoundry/go-mod-configuration/v2/pkg/types"
+	msgTypes "github.com/edgexfoundry/go-mod-messaging/v2/pkg/types"
+)
+
+// the message bus implementation whose topic wildcards differ from the MQTT ones
+const (
+	busTypeZeroMQ = "zero"
+)
+
+// the optional properties the message bus implementations read the credentials and certificates from
+const (
+	optionUsername       = "Username"
+	optionPassword       = "Password"
+	optionCertFile       = "CertFile"
+	optionKeyFile        = "KeyFile"
+	optionCaFile         = "CaFile"
+	optionCertPEMBlock   = "CertPEMBlock"
+	optionKeyPEMBlock    = "KeyPEMBlock"
+	optionCaPEMBlock     = "CaPEMBlock"
+	optionSkipCertVerify = "SkipCertVerify"
+)
+
+// messageBusFromConfig returns the message bus connection injected through the ServiceConfig, either as the
+// MessageBus field or the MessageBusKey optional property. It's empty if none is injected.
+func messageBusFromConfig(config types.ServiceConfig) (types.MessageBusConfig, error) {
+	result := config.MessageBus
+	if result.IsEmpty() {
+		switch value := config.Optional[types.MessageBusKey].(type) {
+		case nil:
+		case types.MessageBusConfig:
+			result = value
+		case *types.MessageBusConfig:
+			result = *value
+		default:
+			decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+				WeaklyTypedInput: true,
+				Result:           &result,
+			})
+			if err != nil {
+				return types.MessageBusConfig{}, err
+			}
+			if err := decoder.Decode(value); err != nil {
+				return types.MessageBusConfig{}, fmt.Errorf("invalid %s: %v", types.MessageBusKey, err)
+			}
+		}
+	}
+
+	if !result.IsEmpty() && (result.Host == "" || result.Port == 0 || result.Type == "") {
+		return types.MessageBusConfig{}, errors.New("host, port or type of the message bus is not defined")
+	}
+	return result, nil
+}
+
+// topicRootFromConfig returns the topic root the configuration changes are published under, taken from the injected
+// message bus connection, then from the TopicRootKey optional property. The default one is returned if none is set.
+func topicRootFromConfig(config types.ServiceConfig, messageBus types.MessageBusConfig) (string, error) {
+	if messageBus.TopicRoot != "" {
+		return topicRoot(messageBus.TopicRoot), nil
+	}
+
+	value, ok := config.Optional[TopicRootKey]
+	if !ok {
+		return topicRoot(""), nil
+	}
+	root, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("invalid %s type %T, expected a string", TopicRootKey, value)
+	}
+	return topicRoot(root), nil
+}
+
+// messageBusConfig returns the configuration of the message bus connection injected through the ServiceConfig, or
+// read from the MessageQueue section of the service configuration if none is injected, along with the topic root the
+// configuration changes are published under. The topics of the MessageQueue section are the service's own data
+// topics, so the topic root is never taken from them.
+func (client *keeperClient) messageBusConfig(ctx context.Context) (msgTypes.MessageBusConfig, string, error) {
+	if !client.messageBus.IsEmpty() {
+		result, err := messagingConfig(client.messageBus)
+		return result, client.topicRoot, err
+	}
+
+	config, err := client.GetConfigurationCtx(ctx, &models.ConfigurationStruct{})
+	if err != nil {
+		return msgTypes.MessageBusConfig{}, "", err
+	}
+	configStruct, ok := config.(*models.ConfigurationStruct)
+	if !ok {
+		return msgTypes.MessageBusConfig{}, "", errors.New("configuration data conversion failed")
+	}
+
+	stored := configStruct.MessageQueue
+	if stored.Host == "" || stored.Port == 0 || stored.Type == "" {
+		return msgTypes.MessageBusConfig{}, "", errNoMessageBus
+	}
+	result, err := messagingConfig(types.MessageBusConfig{
+		Type:     stored.Type,
+		Protocol: stored.Protocol,
+		Host:     stored.Host,
+		Port:     stored.Port,
+		AuthMode: stored.AuthMode,
+		Optional: stored.Optional,
+	})
+	if err != nil && stored.SecretName != "" {
+		// the secret store isn't available here to load the credentials from
+		return msgTypes.MessageBusConfig{}, "", fmt.Errorf("%v, the credentials of the secret %s must be passed through ServiceConfig.MessageBus", err, stored.SecretName)
+	}
+	return result, client.topicRoot, err
+}
+
+// topicRoot returns the topic root without any trailing wildcard or level separator, or the default one if not set
+func topicRoot(topic string) string {
+	root := strings.TrimRight(topic, "#*/.")
+	if root == "" {
+		return keeperTopicPrefix
+	}
+	return root
+}
+
+// watchTopic returns the topic matching the changes of all the keys under the base path, published under the topic
+// root, with the wildcard semantics of the message bus implementation
+func watchTopic(busType string, root string, basePath string) string {
+	topic := path.Join(root, basePath)
+	switch strings.ToLower(busType) {
+	case busTypeZeroMQ:
+		// ZeroMQ subscriptions match the topics by prefix, there is no wildcard
+		return topic + "/"
+	default:
+		// MQTT multi level wildcard, also used by the other implementations, i.e. the Redis one converts it to its own
+		// pattern
+		return topic + "/#"
+	}
+}
+
+// messagingConfig converts the message bus connection to the configuration of the message bus client, with the
+// credentials and certificates set as the optional properties the implementations read them from
+func messagingConfig(config types.MessageBusConfig) (msgTypes.MessageBusConfig, error) {
+	optional := make(map[string]string, len(config.Optional))
+	for key, value := range config.Optional {
+		optional[key] = value
+	}
+
+	setOption := func(key string, value string) {
+		if value != "" {
+			optional[key] = value
+		}
+	}
+	setOption(optionUsername, config.Username)
+	setOption(optionPassword, config.Password)
+	setOption(optionCertFile, config.TLS.CertFile)
+	setOption(optionKeyFile, config.TLS.KeyFile)
+	setOption(optionCaFile, config.TLS.CAFile)
+	setOption(optionCertPEMBlock, string(config.TLS.CertPem))
+	setOption(optionKeyPEMBlock, string(config.TLS.KeyPem))
+	setOption(optionCaPEMBlock, string(config.TLS.CAPem))
+	if config.TLS.InsecureSkipVerify {
+		optional[optionSkipCertVerify] = "true"
+	}
+
+	// requireOption checks that one of the alternative properties is set
+	requireOption := func(alternatives ...string) error {
+		for _, key := range alternatives {
+			if optional[key] != "" {
+				return nil
+			}
+		}
+		return fmt.Errorf("message bus AuthMode %s requires %s", config.AuthMode, strings.Join(alternatives, " or "))
+	}
+	var err error
+	switch strings.ToLower(config.AuthMode) {
+	case "", types.AuthModeNone:
+	case types.AuthModeUsernamePassword:
+		if err = requireOption(optionUsername); err == nil {
+			err = requireOption(optionPassword)
+		}
+	case types.AuthModeClientCert:
+		if err = requireOption(optionCertFile, optionCertPEMBlock); err == nil {
+			err = requireOption(optionKeyFile, optionKeyPEMBlock)
+		}
+	case types.AuthModeCACert:
+		err = requireOption(optionCaFile, optionCaPEMBlock)
+	default:
+		err = fmt.Errorf("invalid message bus AuthMode '%s'", config.AuthMode)
+	}
+	if err != nil {
+		return msgTypes.MessageBusConfig{}, err
+	}
+
+	if clientId, ok := optional[clientID]; ok {
+		// create unique mqtt client id to prevent missing events during subscription
+		randomSuffix := strconv.Itoa(rand.New(rand.NewSource(time.Now().UnixNano())).Intn(clientIDSuffixRandomInterval)) // nolint:gosec
+		optional[clientID] = clientId + "-" + randomSuffix
+	}
+
+	return msgTypes.MessageBusConfig{
+		SubscribeHost: msgTypes.HostInfo{
+			Host:     config.Host,
+			Port:     config.Port,
+			Protocol: config.Protocol,
+		},
+		Type:     config.Type,
+		Optional: optional,
+	}, nil
+}
@@ -6,21 +6,27 @@
 package keeper
 
 import (
-	"encoding/json"
+	"context"
 	"errors"
 	"fmt"
-	"math/rand"
+	"io"
+	netHttp "net/http"
 	"path"
-	"strconv"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/edgexfoundry/go-mod-configuration/v2/internal/pkg/codec"
+	"github.com/edgexfoundry/go-mod-configuration/v2/internal/pkg/decode"
 	"github.com/edgexfoundry/go-mod-configuration/v2/internal/pkg/keeper/api"
 	"github.com/edgexfoundry/go-mod-configuration/v2/internal/pkg/keeper/dtos"
-	"github.com/edgexfoundry/go-mod-configuration/v2/internal/pkg/keeper/models"
 	"github.com/edgexfoundry/go-mod-configuration/v2/internal/pkg/keeper/utils/http"
+	"github.com/edgexfoundry/go-mod-configuration/v2/internal/pkg/listing"
+	"github.com/edgexfoundry/go-mod-configuration/v2/internal/pkg/planning"
+	"github.com/edgexfoundry/go-mod-configuration/v2/internal/pkg/watch"
 	"github.com/edgexfoundry/go-mod-configuration/v2/pkg/types"
-	"github.com/edgexfoundry/go-mod-messaging/v2/messaging"
-	msgTypes "github.com/edgexfoundry/go-mod-messaging/v2/pkg/types"
 
 	"github.com/pelletier/go-toml"
 )
@@ -29,38 +35,125 @@ const (
 	keeperTopicPrefix            = "edgex/configs"
 	clientID                     = "ClientId"
 	clientIDSuffixRandomInterval = 99999
+
+	// WatchModeKey is the ServiceConfig.Optional key selecting how the watches detect the configuration changes, either
+	// WatchModeMessageBus or WatchModePoll. When it isn't set, the changes are received from the message bus if one is
+	// injected through the ServiceConfig or defined in the MessageQueue section of the service configuration, otherwise
+	// they are polled.
+	WatchModeKey = "WatchMode"
+	// WatchModeMessageBus receives the changes Core Keeper publishes on the message bus
+	WatchModeMessageBus = "messagebus"
+	// WatchModePoll reads the watched configuration from Core Keeper at the PollInterval and compares it with the
+	// previous read
+	WatchModePoll = "poll"
+	// PollIntervalKey is the ServiceConfig.Optional key of the interval at which the watched configuration is read when
+	// polling. The value is either a time.Duration or a duration string, i.e. "500ms".
+	PollIntervalKey = watch.PollIntervalKey
+	// TopicRootKey is the ServiceConfig.Optional key of the topic the configuration changes are published under, used
+	// when the TopicRoot of the injected message bus connection isn't set. edgex/configs is used if neither is set.
+	TopicRootKey = "TopicRoot"
+
+	defaultPollInterval = 5 * time.Second
+
+	// putBatchSize is the maximum number of values created by a single request when seeding the configuration
+	putBatchSize = 64
 )
 
+// defaultReconnectPolicy is the backoff between the attempts to reconnect to the message bus once the connection of
+// the watches is lost
+var defaultReconnectPolicy = types.RetryPolicy{
+	InitialBackoff: 500 * time.Millisecond,
+	MaxBackoff:     30 * time.Second,
+	Jitter:         0.2,
+}
+
 type keeperClient struct {
 	keeperUrl      string
 	keeperClient   *api.Caller
 	configBasePath string
-	watchingDone   chan bool
+	messageBus     types.MessageBusConfig
+	topicRoot      string
+	watchMode      string
+	pollInterval   time.Duration
+	// reconnectPolicy only uses the backoff of the policy, the message bus is reconnected until the watches stop
+	reconnectPolicy types.RetryPolicy
+
+	watchLock sync.Mutex
+	watchBus  *messageBus
+	watchers  map[*watcher]struct{}
+
+	watchingDoneCtx context.Context
+	watchingDone    context.CancelFunc
+	watchingWait    sync.WaitGroup
 }
 
 // NewKeeperClient creates a new Keeper Client.
-func NewKeeperClient(config types.ServiceConfig) *keeperClient {
+func NewKeeperClient(config types.ServiceConfig) (*keeperClient, error) {
 	client := keeperClient{
-		keeperUrl:      config.GetUrl(),
-		configBasePath: config.BasePath,
-		watchingDone:   make(chan bool, 1),
+		keeperUrl:       config.GetUrl(),
+		configBasePath:  config.BasePath,
+		watchers:        make(map[*watcher]struct{}),
+		reconnectPolicy: defaultReconnectPolicy,
+	}
+	client.watchingDoneCtx, client.watchingDone = context.WithCancel(context.Background())
+
+	var err error
+	if client.messageBus, err = messageBusFromConfig(config); err != nil {
+		return nil, fmt.Errorf("unable to create new Core Keeper Client for %s: %v", client.keeperUrl, err)
+	}
+	if client.topicRoot, err = topicRootFromConfig(config, client.messageBus); err != nil {
+		return nil, fmt.Errorf("unable to create new Core Keeper Client for %s: %v", client.keeperUrl, err)
+	}
+	if client.watchMode, err = watchModeFromConfig(config); err != nil {
+		return nil, fmt.Errorf("unable to create new Core Keeper Client for %s: %v", client.keeperUrl, err)
+	}
+	if client.pollInterval, err = watch.PollInterval(config.Optional, defaultPollInterval); err != nil {
+		return nil, fmt.Errorf("unable to create new Core Keeper Client for %s: %v", client.keeperUrl, err)
+	}
+
+	httpClient, err := http.NewClient(config.TLS)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create new Core Keeper Client for %s: %v", client.keeperUrl, err)
 	}
 
-	client.createKeeperClient(client.keeperUrl)
-	return &client
+	client.createKeeperClient(client.keeperUrl, config.Retry, httpClient, http.NewBearerTokenInjector(config.AccessToken, config.GetAccessToken))
+	return &client, nil
+}
+
+func watchModeFromConfig(config types.ServiceConfig) (string, error) {
+	value, ok := config.Optional[WatchModeKey]
+	if !ok {
+		return "", nil
+	}
+
+	mode, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("invalid %s type %T, expected a string", WatchModeKey, value)
+	}
+	switch mode = strings.ToLower(mode); mode {
+	case "", WatchModeMessageBus, WatchModePoll:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("invalid %s '%s', expected %s or %s", WatchModeKey, mode, WatchModeMessageBus, WatchModePoll)
+	}
 }
 
 func (client *keeperClient) fullPath(name string) string {
 	return path.Join(client.configBasePath, name)
 }
 
-func (client *keeperClient) createKeeperClient(url string) {
-	client.keeperClient = api.NewCaller(url)
+func (client *keeperClient) createKeeperClient(url string, retryPolicy types.RetryPolicy, httpClient *netHttp.Client, authInjector http.AuthenticationInjector) {
+	client.keeperClient = api.NewCaller(url, retryPolicy, httpClient, authInjector)
 }
 
 // IsAlive simply checks if Core Keeper is up and running at the configured URL
 func (client *keeperClient) IsAlive() bool {
-	err := client.keeperClient.Ping()
+	return client.IsAliveCtx(context.Background())
+}
+
+// IsAliveCtx simply checks if Core Keeper is up and running at the configured URL
+func (client *keeperClient) IsAliveCtx(ctx context.Context) bool {
+	err := client.keeperClient.Ping(ctx)
 	if err != nil {
 		return false
 	}
@@ -70,9 +163,14 @@ func (client *keeperClient) IsAlive() bool {
 
 // HasConfiguration checks to see if Consul contains the service's configuration.
 func (client *keeperClient) HasConfiguration() (bool, error) {
-	resp, err := client.keeperClient.KV().Keys(client.configBasePath)
+	return client.HasConfigurationCtx(context.Background())
+}
+
+// HasConfigurationCtx checks to see if Core Keeper contains the service's configuration.
+func (client *keeperClient) HasConfigurationCtx(ctx context.Context) (bool, error) {
+	resp, err := client.keeperClient.KV().Keys(ctx, client.configBasePath)
 	if err != nil {
-		return false, fmt.Errorf("checking configuration existence from Core Keeper failed: %v", err)
+		return false, fmt.Errorf("checking configuration existence from Core Keeper failed: %w", err)
 	}
 	if len(resp.Keys) == 0 {
 		return false, nil
@@ -81,10 +179,15 @@ func (client *keeperClient) HasConfiguration() (bool, error) {
 }
 
 func (client *keeperClient) HasSubConfiguration(name string) (bool, error) {
+	return client.HasSubConfigurationCtx(context.Background(), name)
+}
+
+// HasSubConfigurationCtx checks to see if Core Keeper contains the service's sub configuration.
+func (client *keeperClient) HasSubConfigurationCtx(ctx context.Context, name string) (bool, error) {
 	keyPath := client.fullPath(name)
-	resp, err := client.keeperClient.KV().Keys(keyPath)
+	resp, err := client.keeperClient.KV().Keys(ctx, keyPath)
 	if err != nil {
-		return false, fmt.Errorf("checking configuration existence from Core Keeper failed: %v", err)
+		return false, fmt.Errorf("checking configuration existence from Core Keeper failed: %w", err)
 	}
 	if len(resp.Keys) == 0 {
 		return false, nil
@@ -94,8 +197,13 @@ func (client *keeperClient) HasSubConfiguration(name string) (bool, error) {
 
 // PutConfigurationToml puts a full toml configuration into Core Keeper
 func (client *keeperClient) PutConfigurationToml(configuration *toml.Tree, overwrite bool) error {
+	return client.PutConfigurationTomlCtx(context.Background(), configuration, overwrite)
+}
+
+// PutConfigurationTomlCtx puts a full toml configuration into Core Keeper
+func (client *keeperClient) PutConfigurationTomlCtx(ctx context.Context, configuration *toml.Tree, overwrite bool) error {
 	configurationMap := configuration.ToMap()
-	err := client.PutConfiguration(configurationMap, overwrite)
+	err := client.PutConfigurationCtx(ctx, configurationMap, overwrite)
 	if err != nil {
 		return err
 	}
@@ -103,226 +211,657 @@ func (client *keeperClient) PutConfigurationToml(configuration *toml.Tree, overw
 }
 
 func (client *keeperClient) PutConfiguration(config interface{}, overwrite bool) error {
+	return client.PutConfigurationCtx(context.Background(), config, overwrite)
+}
+
+// PutConfigurationCtx puts a full configuration struct into Core Keeper. Unless overwrite is set, only the values
+// which don't exist yet are created, in batches. If a batch fails, the values created by the previous ones are
+// deleted again, so the configuration is either fully seeded or left unchanged.
+func (client *keeperClient) PutConfigurationCtx(ctx context.Context, config interface{}, overwrite bool) error {
 	var err error
 	if overwrite {
-		err = client.keeperClient.KV().PutKeys(client.configBasePath, config)
+		err = client.keeperClient.KV().PutKeys(ctx, client.configBasePath, config)
 	} else {
-		kvPairs := convertMapToKVPairs("", config)
-		for _, kv := range kvPairs {
-			exists, err := client.ConfigurationValueExists(kv.Key)
-			if err != nil {
-				return err
-			}
-			if !exists {
-				// Only create the key if not exists in core keeper
-				if err = client.PutConfigurationValue(kv.Key, []byte(kv.Value)); err != nil {
-					return err
-				}
-			}
-		}
+		err = client.putMissingKeys(ctx, config)
 	}
 	if err != nil {
-		return fmt.Errorf("error occurred while creating/updating configuration, error: %v", err)
+		return fmt.Errorf("error occurred while creating/updating configuration, error: %w", err)
+	}
+	return nil
+}
+
+// putMissingKeys creates the values of the configuration which don't exist yet, putBatchSize values per request
+func (client *keeperClient) putMissingKeys(ctx context.Context, config interface{}) error {
+	configMap, err := toConfigMap(config)
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.keeperClient.KV().Keys(ctx, client.configBasePath)
+	if err != nil {
+		return err
 	}
+	existing := make(map[string]bool, len(resp.Keys))
+	for _, key := range resp.Keys {
+		existing[string(key)] = true
+	}
+
+	values := codec.ToValues("", configMap)
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var missing []*pair
+	for _, key := range keys {
+		if !existing[client.fullPath(key)] {
+			missing = append(missing, &pair{Key: key, Value: string(values[key])})
+		}
+	}
+
+	var created []string
+	for start := 0; start < len(missing); start += putBatchSize {
+		end := start + putBatchSize
+		if end > len(missing) {
+			end = len(missing)
+		}
+
+		batch := missing[start:end]
+		if err := client.keeperClient.KV().PutKeys(ctx, client.configBasePath, nestKVPairs(batch)); err != nil {
+			if rollbackErr := client.deleteKeys(ctx, created); rollbackErr != nil {
+				return fmt.Errorf("%w, deleting the values already created failed: %v", err, rollbackErr)
+			}
+			return err
+		}
+		for _, kv := range batch {
+			created = append(created, kv.Key)
+		}
+	}
+
 	return nil
 }
 
+// deleteKeys deletes the values, it keeps deleting the remaining ones when one fails and returns the first error
+func (client *keeperClient) deleteKeys(ctx context.Context, keys []string) error {
+	var firstErr error
+	for _, key := range keys {
+		if err := client.keeperClient.KV().Delete(ctx, client.fullPath(key)); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
 func (client *keeperClient) GetConfiguration(configStruct interface{}) (interface{}, error) {
-	exists, err := client.HasConfiguration()
+	return client.GetConfigurationCtx(context.Background(), configStruct)
+}
+
+// GetConfigurationCtx gets the full configuration from Core Keeper into the target configuration struct.
+func (client *keeperClient) GetConfigurationCtx(ctx context.Context, configStruct interface{}) (interface{}, error) {
+	exists, err := client.HasConfigurationCtx(ctx)
 	if err != nil {
 		return nil, err
 	}
 
 	if !exists {
-		return nil, fmt.Errorf("the Configuration service (EdgeX Keeper) doesn't contain configuration for %s", client.configBasePath)
+		return nil, fmt.Errorf("the Configuration service (EdgeX Keeper) doesn't contain configuration for %s: %w", client.configBasePath, types.ErrNotFound)
 	}
 
-	resp, err := client.keeperClient.KV().Get(client.configBasePath)
+	resp, err := client.keeperClient.KV().Get(ctx, client.configBasePath)
 	if err != nil {
 		return nil, err
 	}
 
-	err = decode(client.configBasePath+api.KeyDelimiter, resp.KVs, configStruct)
-	if err != nil {
+	if err := decode.Decode(client.configBasePath, kvValues(resp.KVs), configStruct); err != nil {
 		return nil, err
 	}
 	return configStruct, nil
 }
 
-func (client *keeperClient) WatchForChanges(updateChannel chan<- interface{}, errorChannel chan<- error, configuration interface{}, waitKey string) {
-	// get the service configuration
-	config, err := client.GetConfiguration(&models.ConfigurationStruct{})
+func (client *keeperClient) ExportConfiguration(w io.Writer, format types.ConfigurationFormat) error {
+	return client.ExportConfigurationCtx(context.Background(), w, format)
+}
+
+// ExportConfigurationCtx writes the configuration from Core Keeper to w as a document in the format. Core Keeper
+// stores the values as strings, which are typed back by the types stored along with the last imported document, so
+// it's exported exactly as it was imported.
+func (client *keeperClient) ExportConfigurationCtx(ctx context.Context, w io.Writer, format types.ConfigurationFormat) error {
+	values, err := client.GetConfigurationValuesCtx(ctx, "")
 	if err != nil {
-		errorChannel <- err
-		return
+		return err
+	}
+	if len(values) == 0 {
+		return fmt.Errorf("the Configuration service (EdgeX Keeper) doesn't contain configuration for %s: %w", client.configBasePath, types.ErrNotFound)
 	}
 
-	messages := make(chan msgTypes.MessageEnvelope)
-	topic := path.Join(keeperTopicPrefix, client.configBasePath, waitKey, "#")
-	topics := []msgTypes.TopicChannel{
-		{
-			Topic:    topic,
-			Messages: messages,
-		},
+	typesKey := codec.TypesKey(client.configBasePath)
+	resp, err := client.keeperClient.KV().Get(ctx, typesKey)
+	if err != nil && !errors.Is(err, types.ErrNotFound) {
+		return fmt.Errorf("unable to get the types of the configuration from Core Keeper, err: %w", err)
 	}
-	var msgBusConfig models.MessageBusInfo
-	configStruct, ok := config.(*models.ConfigurationStruct)
-	if !ok {
-		configErr := errors.New("configuration data conversion failed")
-		close(messages)
-		errorChannel <- configErr
-		return
-	}
-
-	msgBusConfig = configStruct.MessageQueue
-	if msgBusConfig.Host == "" || msgBusConfig.Port == 0 || msgBusConfig.Type == "" {
-		configErr := errors.New("host, port or type from MessageQueue section is not defined in the configuration")
-		close(messages)
-		errorChannel <- configErr
-		return
-	}
-	if msgBusConfig.Optional != nil {
-		if clientId, ok := msgBusConfig.Optional[clientID]; ok {
-			// create unique mqtt client id to prevent missing events during subscription
-			randomSuffix := strconv.Itoa(rand.New(rand.NewSource(time.Now().UnixNano())).Intn(clientIDSuffixRandomInterval)) // nolint:gosec
-			msgBusConfig.Optional[clientID] = clientId + "-" + randomSuffix
+	var stored []byte
+	for _, kv := range resp.KVs {
+		// the key is matched as a prefix, so the types of the sibling base paths are returned as well
+		if kv.Key == typesKey {
+			stored = codec.FormatValue(kv.Value)
 		}
 	}
+	valueTypes, err := codec.UnmarshalTypes(stored)
+	if err != nil {
+		return err
+	}
 
-	messageBus, err := messaging.NewMessageClient(msgTypes.MessageBusConfig{
-		SubscribeHost: msgTypes.HostInfo{
-			Host:     msgBusConfig.Host,
-			Port:     msgBusConfig.Port,
-			Protocol: msgBusConfig.Protocol,
-		},
-		Type:     msgBusConfig.Type,
-		Optional: msgBusConfig.Optional,
-	})
+	return codec.Write(w, format, codec.FromValues(values, valueTypes))
+}
+
+func (client *keeperClient) ImportConfiguration(r io.Reader, format types.ConfigurationFormat, overwrite bool) error {
+	return client.ImportConfigurationCtx(context.Background(), r, format, overwrite)
+}
+
+// ImportConfigurationCtx puts the configuration document in the format read from r into Core Keeper, the same way as
+// PutConfiguration, then stores the types its raw values don't tell for ExportConfiguration
+func (client *keeperClient) ImportConfigurationCtx(ctx context.Context, r io.Reader, format types.ConfigurationFormat, overwrite bool) error {
+	configMap, err := codec.Read(r, format)
 	if err != nil {
-		close(messages)
-		errorChannel <- err
-		return
-	}
-	// connect to the message bus
-	if conErr := messageBus.Connect(); conErr != nil {
-		close(messages)
-		errorChannel <- conErr
-		return
-	}
-	watchErrors := make(chan error)
-	err = messageBus.Subscribe(topics, watchErrors)
+		return err
+	}
+
+	if err := client.PutConfigurationCtx(ctx, configMap, overwrite); err != nil {
+		return err
+	}
+
+	stored, err := codec.MarshalTypes(codec.TypesOf(configMap))
+	if err != nil {
+		return err
+	}
+	if err := client.keeperClient.KV().Put(ctx, codec.TypesKey(client.configBasePath), stored); err != nil {
+		return fmt.Errorf("unable to put the types of the configuration into Core Keeper, err: %w", err)
+	}
+	return nil
+}
+
+func (client *keeperClient) WatchForChanges(updateChannel chan<- interface{}, errorChannel chan<- error, configuration interface{}, waitKey string, options ...types.WatchOption) types.WatchHandle {
+	return client.WatchForChangesCtx(context.Background(), updateChannel, errorChannel, configuration, waitKey, options...)
+}
+
+// WatchForChangesCtx sets up a Core Keeper watch for the target key and send back updates on the update channel.
+// On each change the whole configuration under the key is read again and decoded into a new struct of the same type
+// as the passed in struct. All the watches of the client share a single message bus connection, or poll Core Keeper
+// when no message bus is configured, see WatchModeKey. The connection is renewed with backoff when it fails, and the
+// changes missed meanwhile are then delivered as a single update.
+// The current configuration is sent first, as by the other providers, and the returned handle's Ready channel is
+// closed once the changes are watched.
+// The watch stops when either the context is done, it is stopped through the returned handle or StopWatching is
+// called.
+func (client *keeperClient) WatchForChangesCtx(ctx context.Context, updateChannel chan<- interface{}, errorChannel chan<- error, configuration interface{}, waitKey string, options ...types.WatchOption) types.WatchHandle {
+	return client.watch(ctx, errorChannel, configuration, waitKey, types.NewWatchOptions(options...), func(ctx context.Context, update types.WatchUpdate) bool {
+		select {
+		case updateChannel <- update.Configuration:
+			return true
+		case <-ctx.Done():
+		case <-client.watchingDoneCtx.Done():
+		}
+		return false
+	})
+}
+
+// WatchForChangeSets sets up a Core Keeper watch for the target key and sends back an update on the update channel
+// each time the configuration under it changes, along with the values which have been added, modified or removed.
+// Passed in struct is only a reference for decoder, empty struct is ok
+func (client *keeperClient) WatchForChangeSets(updateChannel chan<- types.WatchUpdate, errorChannel chan<- error, configuration interface{}, waitKey string, options ...types.WatchOption) types.WatchHandle {
+	return client.WatchForChangeSetsCtx(context.Background(), updateChannel, errorChannel, configuration, waitKey, options...)
+}
+
+// WatchForChangeSetsCtx sets up a Core Keeper watch for the target key and sends back an update on the update
+// channel each time the configuration under it changes, along with the values which have been added, modified or
+// removed. The current configuration is sent first, with all its values added. The keys published on the message bus
+// are reported as modified even when their value is unchanged.
+// The watch stops when either the context is done, it is stopped through the returned handle or StopWatching is
+// called.
+func (client *keeperClient) WatchForChangeSetsCtx(ctx context.Context, updateChannel chan<- types.WatchUpdate, errorChannel chan<- error, configuration interface{}, waitKey string, options ...types.WatchOption) types.WatchHandle {
+	return client.watch(ctx, errorChannel, configuration, waitKey, types.NewWatchOptions(options...), func(ctx context.Context, update types.WatchUpdate) bool {
+		select {
+		case updateChannel <- update:
+			return true
+		case <-ctx.Done():
+		case <-client.watchingDoneCtx.Done():
+		}
+		return false
+	})
+}
+
+// watch runs a watch of the target key, which passes the updates to deliver until it returns false. The current
+// configuration is delivered first, with all its values added.
+// On each change the whole configuration under the key is read again and decoded into a new struct of the same type
+// as the passed in struct. The changes are received from the message bus connection shared by all the watches of the
+// client, or polled, depending on the watch mode.
+func (client *keeperClient) watch(ctx context.Context, errorChannel chan<- error, configuration interface{}, waitKey string, options types.WatchOptions, deliver watch.DeliverFunc) types.WatchHandle {
+	ctx, handle := watch.NewHandle(ctx)
+	targetType := reflect.TypeOf(configuration)
+	if targetType.Kind() == reflect.Ptr {
+		targetType = targetType.Elem()
+	}
+
+	w := newWatcher(path.Join(client.configBasePath, waitKey))
+
+	client.watchingWait.Add(1)
+	poll, err := client.startWatcher(ctx, w)
 	if err != nil {
-		_ = messageBus.Disconnect()
-		errorChannel <- err
-		return
+		select {
+		case errorChannel <- err:
+		case <-ctx.Done():
+		case <-client.watchingDoneCtx.Done():
+		}
+		handle.SetStopped()
+		client.watchingWait.Done()
+		return handle
+	}
+	if !poll {
+		// the changes are received from now on, while the polling watch is ready once it has read the configuration
+		handle.SetReady()
 	}
 
 	go func() {
 		defer func() {
-			_ = messageBus.Disconnect()
+			if !poll {
+				client.removeWatcher(w)
+			}
+			handle.SetStopped()
+			client.watchingWait.Done()
 		}()
 
-		isFirstUpdate := true
+		// the updates are coalesced over the debounce window of the watch, if set
+		deliver, stopDelivering := watch.Debounce(ctx, options.Debounce, deliver)
+		defer stopDelivering()
+
+		sendError := func(err error) bool {
+			select {
+			case errorChannel <- err:
+				return true
+			case <-ctx.Done():
+			case <-client.watchingDoneCtx.Done():
+			}
+			return false
+		}
+
+		if poll {
+			client.poll(ctx, w, targetType, handle.SetReady, sendError, deliver)
+			return
+		}
+
+		var previous map[string][]byte
+		update := func(touched map[string]bool) bool {
+			configuration, current, err := client.readWatched(ctx, w, targetType)
+			if err != nil {
+				return sendError(err)
+			}
+			changes := watch.NewChangeSet(previous, current, touched)
+			previous = current
+			return deliver(ctx, types.WatchUpdate{Configuration: configuration, Changes: changes})
+		}
+
+		// resync reads the configuration again after the message bus is reconnected, and delivers it once with all the
+		// changes missed meanwhile
+		resync := func() bool {
+			configuration, current, err := client.readWatched(ctx, w, targetType)
+			if err != nil {
+				return sendError(err)
+			}
+			changes := watch.NewChangeSet(previous, current, nil)
+			if changes.IsEmpty() {
+				return true
+			}
+			previous = current
+			return deliver(ctx, types.WatchUpdate{Configuration: configuration, Changes: changes})
+		}
+
+		if !update(nil) {
+			return
+		}
 
 		for {
 			select {
-			case <-client.watchingDone:
+			case <-ctx.Done():
 				return
-			case e := <-watchErrors:
-				errorChannel <- e
-			case msgEnvelope := <-messages:
-				if isFirstUpdate {
-					// send message to channel once the watcher connection is established
-					// for go-mod-bootstrap to ignore the first change event
-					// refer to https://github.com/edgexfoundry/go-mod-bootstrap/blob/main/bootstrap/config/config.go#L478-L484
-					isFirstUpdate = false
-					updateChannel <- "watch config change subscription established"
-					continue
+			case <-client.watchingDoneCtx.Done():
+				return
+			case <-w.failed:
+				if err := w.takeError(); err != nil && !sendError(err) {
+					return
 				}
-				if msgEnvelope.ContentType != http.ContentTypeJSON {
-					continue
+			case <-w.changed:
+				// all the changes recorded meanwhile are covered by reading the whole configuration under the key
+				touched := w.takeChanges()
+				if len(touched) > 0 && !update(touched) {
+					return
 				}
-				var respKV dtos.KV
-				err := json.Unmarshal(msgEnvelope.Payload, &respKV)
-				if err != nil {
-					continue
+			case <-w.resync:
+				if !resync() {
+					return
 				}
-				keyPrefix := path.Join(client.configBasePath, waitKey)
-				err = decode(keyPrefix, []dtos.KV{respKV}, configuration)
-				updateChannel <- configuration
 			}
 		}
 	}()
 
-	// send empty message to the channel when the watch key change subscription established
-	messages <- msgTypes.MessageEnvelope{}
+	return handle
 }
 
+// readWatched reads the whole configuration under the watched key and decodes it into a new struct of the target
+// type. The fields of the keys which have been deleted are left empty. The raw values are returned as well, keyed
+// relative to the watched key.
+func (client *keeperClient) readWatched(ctx context.Context, w *watcher, targetType reflect.Type) (interface{}, map[string][]byte, error) {
+	var pairs []dtos.KV
+	resp, err := client.keeperClient.KV().Get(ctx, w.keyPrefix)
+	if err != nil && !errors.Is(err, types.ErrNotFound) {
+		return nil, nil, fmt.Errorf("unable to get the watched configuration %s from Core Keeper, err: %w", w.keyPrefix, err)
+	}
+	if err == nil {
+		// Core Keeper matches the key as a prefix, so the response may also contain keys of other sections
+		for _, kv := range resp.KVs {
+			if w.matches(kv.Key) {
+				pairs = append(pairs, kv)
+			}
+		}
+	}
+
+	configuration := reflect.New(targetType).Interface()
+	if err := decode.Decode(w.keyPrefix, kvValues(pairs), configuration); err != nil {
+		return nil, nil, err
+	}
+
+	values := make(map[string][]byte, len(pairs))
+	for _, kv := range pairs {
+		values[w.relativeKey(kv.Key)] = codec.FormatValue(kv.Value)
+	}
+	return configuration, values, nil
+}
+
+// StopWatching causes all WatchForChanges processing to stop and waits until they have exited and the message bus
+// connection is closed.
 func (client *keeperClient) StopWatching() {
-	client.watchingDone <- true
+	client.watchingDone()
+	client.watchingWait.Wait()
+}
+
+// StopWatchingCtx causes all WatchForChanges processing to stop and waits until they have exited and the message bus
+// connection is closed, or the context is done.
+func (client *keeperClient) StopWatchingCtx(ctx context.Context) error {
+	client.watchingDone()
+
+	stopped := make(chan struct{})
+	go func() {
+		client.watchingWait.Wait()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
 
 func (client *keeperClient) ConfigurationValueExists(name string) (bool, error) {
+	return client.ConfigurationValueExistsCtx(context.Background(), name)
+}
+
+// ConfigurationValueExistsCtx checks if a configuration value exists in Core Keeper
+func (client *keeperClient) ConfigurationValueExistsCtx(ctx context.Context, name string) (bool, error) {
 	keyPath := client.fullPath(name)
-	res, err := client.keeperClient.KV().Keys(keyPath)
+	res, err := client.keeperClient.KV().Keys(ctx, keyPath)
 	if err != nil {
-		return false, fmt.Errorf("checking configuration existence from Core Keeper failed: %v", err)
+		return false, fmt.Errorf("checking configuration existence from Core Keeper failed: %w", err)
 	}
-	if len(res.Keys) == 0 {
-		return false, nil
+	// Core Keeper returns all the keys starting with the key path, so look for the exact key
+	for _, key := range res.Keys {
+		if string(key) == keyPath {
+			return true, nil
+		}
 	}
-	return true, nil
+	return false, nil
 }
 
 func (client *keeperClient) GetConfigurationValue(name string) ([]byte, error) {
+	return client.GetConfigurationValueCtx(context.Background(), name)
+}
+
+// GetConfigurationValueCtx gets a specific configuration value from Core Keeper.
+// Returns nil without an error if the value doesn't exist, the same as the other providers.
+func (client *keeperClient) GetConfigurationValueCtx(ctx context.Context, name string) ([]byte, error) {
 	keyPath := client.fullPath(name)
-	resp, err := client.keeperClient.KV().Get(keyPath)
+	resp, err := client.keeperClient.KV().Get(ctx, keyPath)
+	if errors.Is(err, types.ErrNotFound) {
+		return nil, nil
+	}
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("unable to get value for %s from Core Keeper, err: %w", keyPath, err)
 	}
-	if len(resp.KVs) == 0 {
-		return nil, fmt.Errorf("%s configuration not found", name)
-	}
-
-	var valueStr string
-	switch value := resp.KVs[0].Value.(type) {
-	case string:
-		valueStr = value
-	case int:
-		valueStr = strconv.Itoa(value)
-	case int8:
-		value8 := int(value)
-		valueStr = strconv.Itoa(value8)
-	case int16:
-		value16 := int(value)
-		valueStr = strconv.Itoa(value16)
-	case int32:
-		value32 := int(value)
-		valueStr = strconv.Itoa(value32)
-	case int64:
-		value64 := int(value)
-		valueStr = strconv.Itoa(value64)
-	case float32:
-		valueF64 := float64(value)
-		valueStr = strconv.FormatFloat(valueF64, 'g', -1, 32)
-	case float64:
-		valueStr = strconv.FormatFloat(value, 'g', -1, 64)
-	case bool:
-		valueStr = strconv.FormatBool(value)
-	case nil:
-		valueStr = ""
-	default:
-		valueStr = fmt.Sprintf("%v", value)
+
+	// Core Keeper matches the key as a prefix, so the response may also contain other keys starting with it
+	var kv *dtos.KV
+	for i := range resp.KVs {
+		if resp.KVs[i].Key == keyPath {
+			kv = &resp.KVs[i]
+			break
+		}
+	}
+	if kv == nil {
+		return nil, nil
+	}
+
+	return codec.FormatValue(kv.Value), nil
+}
+
+func (client *keeperClient) ListConfigurationKeys(prefix string, options ...types.ListOption) ([]string, error) {
+	return client.ListConfigurationKeysCtx(context.Background(), prefix, options...)
+}
+
+// ListConfigurationKeysCtx lists the keys of the configuration values under the prefix from Core Keeper, sorted and
+// relative to the base path
+func (client *keeperClient) ListConfigurationKeysCtx(ctx context.Context, prefix string, options ...types.ListOption) ([]string, error) {
+	lister := listing.NewLister(client.configBasePath, prefix, types.NewListOptions(options...))
+	resp, err := client.keeperClient.KV().Keys(ctx, lister.Prefix())
+	if err != nil {
+		return nil, fmt.Errorf("unable to list keys under %s from Core Keeper, err: %w", lister.Prefix(), err)
 	}
 
-	return []byte(valueStr), nil
+	keys := make([]string, 0, len(resp.Keys))
+	for _, key := range resp.Keys {
+		keys = append(keys, string(key))
+	}
+	return lister.Keys(keys), nil
+}
+
+func (client *keeperClient) GetConfigurationValues(prefix string, options ...types.ListOption) (map[string][]byte, error) {
+	return client.GetConfigurationValuesCtx(context.Background(), prefix, options...)
+}
+
+// GetConfigurationValuesCtx gets the configuration values under the prefix from Core Keeper, keyed relative to the
+// base path
+func (client *keeperClient) GetConfigurationValuesCtx(ctx context.Context, prefix string, options ...types.ListOption) (map[string][]byte, error) {
+	lister := listing.NewLister(client.configBasePath, prefix, types.NewListOptions(options...))
+	resp, err := client.keeperClient.KV().Get(ctx, lister.Prefix())
+	if errors.Is(err, types.ErrNotFound) {
+		return make(map[string][]byte), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to get values under %s from Core Keeper, err: %w", lister.Prefix(), err)
+	}
+
+	values := make(map[string][]byte, len(resp.KVs))
+	for _, kv := range resp.KVs {
+		values[kv.Key] = codec.FormatValue(kv.Value)
+	}
+	return lister.Values(values), nil
 }
 
 func (client *keeperClient) PutConfigurationValue(name string, value []byte) error {
+	return client.PutConfigurationValueCtx(context.Background(), name, value)
+}
+
+// PutConfigurationValueCtx puts a specific configuration value into Core Keeper
+func (client *keeperClient) PutConfigurationValueCtx(ctx context.Context, name string, value []byte) error {
 	keyPath := client.fullPath(name)
-	err := client.keeperClient.KV().Put(keyPath, value)
+	err := client.keeperClient.KV().Put(ctx, keyPath, value)
+	if err != nil {
+		return fmt.Errorf("unable to put value for %s into Core Keeper, err: %w", keyPath, err)
+	}
+	return nil
+}
+
+func (client *keeperClient) GetConfigurationValueWithVersion(name string) ([]byte, types.Revision, error) {
+	return client.GetConfigurationValueWithVersionCtx(context.Background(), name)
+}
+
+// GetConfigurationValueWithVersionCtx gets a specific configuration value from Core Keeper along with its revision.
+// Core Keeper doesn't version the values, so the revision is derived from the content of the value.
+// Returns nil and types.NoRevision without an error if the value doesn't exist.
+func (client *keeperClient) GetConfigurationValueWithVersionCtx(ctx context.Context, name string) ([]byte, types.Revision, error) {
+	value, err := client.GetConfigurationValueCtx(ctx, name)
+	if err != nil || value == nil {
+		return nil, types.NoRevision, err
+	}
+	return value, types.ValueRevision(value), nil
+}
+
+func (client *keeperClient) PutConfigurationValueCAS(name string, value []byte, revision types.Revision) error {
+	return client.PutConfigurationValueCASCtx(context.Background(), name, value, revision)
+}
+
+// PutConfigurationValueCASCtx puts a specific configuration value into Core Keeper only if its revision is still the
+// given one. The revision is sent along with the value, so Core Keeper checks it atomically with the write.
+func (client *keeperClient) PutConfigurationValueCASCtx(ctx context.Context, name string, value []byte, revision types.Revision) error {
+	keyPath := client.fullPath(name)
+	err := client.keeperClient.KV().PutCAS(ctx, keyPath, value, revision)
+	if errors.Is(err, types.ErrConflict) {
+		return types.NewProviderError(types.ErrConflict, fmt.Errorf("unable to put value for %s into Core Keeper: revision %q is stale", keyPath, revision))
+	}
+	if err != nil {
+		return fmt.Errorf("unable to put value for %s into Core Keeper, err: %w", keyPath, err)
+	}
+	return nil
+}
+
+func (client *keeperClient) PlanConfiguration(desired interface{}) (types.Plan, error) {
+	return client.PlanConfigurationCtx(context.Background(), desired)
+}
+
+// PlanConfigurationCtx compares the desired configuration, either a struct, a map or a *toml.Tree, with the
+// configuration stored in Core Keeper and returns the plan of the values to add, modify and remove. Core Keeper
+// doesn't version the values, so the plan is checked against the content of the stored values.
+func (client *keeperClient) PlanConfigurationCtx(ctx context.Context, desired interface{}) (types.Plan, error) {
+	configMap, err := toConfigMap(desired)
+	if err != nil {
+		return types.Plan{}, err
+	}
+	desiredValues := codec.ToValues("", configMap)
+
+	storedValues, err := client.GetConfigurationValuesCtx(ctx, "")
+	if err != nil {
+		return types.Plan{}, err
+	}
+
+	return planning.New(storedValues, desiredValues, func(_ string, value []byte) types.Revision {
+		return types.ValueRevision(value)
+	}), nil
+}
+
+func (client *keeperClient) ApplyPlan(plan types.Plan) error {
+	return client.ApplyPlanCtx(context.Background(), plan)
+}
+
+// ApplyPlanCtx executes exactly the changes of the plan in Core Keeper. The values are put in batches of putBatchSize
+// values, then the removed ones are deleted. Each request carries the revisions of the values it changes, so Core
+// Keeper rejects it if any of them has been changed since the plan was made; if a request fails, the changes already
+// made are reverted.
+func (client *keeperClient) ApplyPlanCtx(ctx context.Context, plan types.Plan) error {
+	changes := append(append([]types.KeyChange{}, plan.Changes.Added...), plan.Changes.Modified...)
+	var added []string
+	var restored []*pair
+	revert := func(err error) error {
+		if revertErr := client.revertChanges(ctx, added, restored); revertErr != nil {
+			return fmt.Errorf("unable to apply the plan to Core Keeper: %w, reverting the changes already made failed: %v", err, revertErr)
+		}
+		return fmt.Errorf("unable to apply the plan to Core Keeper: %w", err)
+	}
+
+	for start := 0; start < len(changes); start += putBatchSize {
+		end := start + putBatchSize
+		if end > len(changes) {
+			end = len(changes)
+		}
+
+		batch := make([]*pair, 0, end-start)
+		revisions := make(map[string]types.Revision, end-start)
+		for _, change := range changes[start:end] {
+			batch = append(batch, &pair{Key: change.Key, Value: string(change.NewValue)})
+			// the added values have no revision, so they must still not exist
+			revisions[client.fullPath(change.Key)] = plan.Revisions[change.Key]
+		}
+		if err := client.keeperClient.KV().PutKeysCAS(ctx, client.configBasePath, nestKVPairs(batch), revisions); err != nil {
+			return revert(err)
+		}
+		for index, change := range changes[start:end] {
+			if start+index < len(plan.Changes.Added) {
+				added = append(added, change.Key)
+			} else {
+				restored = append(restored, &pair{Key: change.Key, Value: string(change.OldValue)})
+			}
+		}
+	}
+	for _, change := range plan.Changes.Removed {
+		if err := client.keeperClient.KV().DeleteCAS(ctx, client.fullPath(change.Key), plan.Revisions[change.Key]); err != nil {
+			return revert(err)
+		}
+		restored = append(restored, &pair{Key: change.Key, Value: string(change.OldValue)})
+	}
+
+	return nil
+}
+
+// revertChanges deletes the added values and puts the old values back, it keeps reverting the remaining ones when a
+// request fails and returns the first error
+func (client *keeperClient) revertChanges(ctx context.Context, added []string, restored []*pair) error {
+	firstErr := client.deleteKeys(ctx, added)
+	if len(restored) > 0 {
+		if err := client.keeperClient.KV().PutKeys(ctx, client.configBasePath, nestKVPairs(restored)); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// DeleteConfigurationValue deletes a specific configuration value from Core Keeper
+func (client *keeperClient) DeleteConfigurationValue(name string) error {
+	return client.DeleteConfigurationValueCtx(context.Background(), name)
+}
+
+// DeleteConfigurationValueCtx deletes a specific configuration value from Core Keeper
+func (client *keeperClient) DeleteConfigurationValueCtx(ctx context.Context, name string) error {
+	keyPath := client.fullPath(name)
+	err := client.keeperClient.KV().Delete(ctx, keyPath)
+	if err != nil {
+		return fmt.Errorf("unable to delete value for %s from Core Keeper, err: %w", keyPath, err)
+	}
+	return nil
+}
+
+// DeleteSubConfiguration deletes all configuration values under the sub configuration from Core Keeper
+func (client *keeperClient) DeleteSubConfiguration(name string) error {
+	return client.DeleteSubConfigurationCtx(context.Background(), name)
+}
+
+// DeleteSubConfigurationCtx deletes all configuration values under the sub configuration from Core Keeper
+func (client *keeperClient) DeleteSubConfigurationCtx(ctx context.Context, name string) error {
+	keyPath := client.fullPath(name)
+	// only delete the keys of the section, i.e. Writable must not delete WritableX
+	if len(keyPath) > 0 && !strings.HasSuffix(keyPath, api.KeyDelimiter) {
+		keyPath = keyPath + api.KeyDelimiter
+	}
+	err := client.keeperClient.KV().DeleteKeys(ctx, keyPath)
 	if err != nil {
-		return fmt.Errorf("unable to JSON marshal configStruct, err: %v", err)
+		return fmt.Errorf("unable to delete sub configuration %s from Core Keeper, err: %w", keyPath, err)
 	}
 	return nil
 }
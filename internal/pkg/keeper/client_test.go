@@ -6,14 +6,35 @@
 package keeper
 
 import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"os"
+	"path/filepath"
 	"strconv"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/edgexfoundry/go-mod-configuration/v2/internal/pkg/keeper/api"
+	"github.com/edgexfoundry/go-mod-configuration/v2/internal/pkg/keeper/dtos"
+	"github.com/edgexfoundry/go-mod-configuration/v2/internal/pkg/keeper/models"
+	httpUtils "github.com/edgexfoundry/go-mod-configuration/v2/internal/pkg/keeper/utils/http"
 	"github.com/edgexfoundry/go-mod-configuration/v2/pkg/types"
+	msgTypes "github.com/edgexfoundry/go-mod-messaging/v2/pkg/types"
 
 	"github.com/pelletier/go-toml"
 	"github.com/stretchr/testify/assert"
@@ -44,14 +65,21 @@ type TestConfig struct {
 	Temp     float64
 }
 
-func makeCoreKeeperClient(serviceName string) *keeperClient {
+func makeCoreKeeperClient(t *testing.T, serviceName string) *keeperClient {
+	return makeCoreKeeperClientWithToken(t, serviceName, "", nil)
+}
+
+func makeCoreKeeperClientWithToken(t *testing.T, serviceName string, accessToken string, tokenCallback types.GetAccessTokenCallback) *keeperClient {
 	config := types.ServiceConfig{
-		Host:     testHost,
-		Port:     port,
-		BasePath: serviceName,
+		Host:           testHost,
+		Port:           port,
+		BasePath:       serviceName,
+		AccessToken:    accessToken,
+		GetAccessToken: tokenCallback,
 	}
 
-	client := NewKeeperClient(config)
+	client, err := NewKeeperClient(config)
+	require.NoError(t, err)
 	return client
 }
 
@@ -72,8 +100,8 @@ func reset(t *testing.T, client *keeperClient) {
 		mockCoreKeeper.Reset()
 	} else {
 		// delete the key(s) created in each test if testing on real Keeper service
-		key := client.configBasePath
-		err := client.keeperClient.KV().DeleteKeys(key)
+		key := client.configBasePath + api.KeyDelimiter
+		err := client.keeperClient.KV().DeleteKeys(context.Background(), key)
 		if !assert.NoError(t, err) {
 			t.Fatal()
 		}
@@ -98,14 +126,14 @@ func TestMain(m *testing.M) {
 }
 
 func TestIsAlive(t *testing.T) {
-	client := makeCoreKeeperClient(getUniqueServiceName())
+	client := makeCoreKeeperClient(t, getUniqueServiceName())
 	if !client.IsAlive() {
 		t.Fatal("Core Keeper is not running")
 	}
 }
 
 func TestHasConfigurationFalse(t *testing.T) {
-	client := makeCoreKeeperClient(getUniqueServiceName())
+	client := makeCoreKeeperClient(t, getUniqueServiceName())
 
 	actual, err := client.HasConfiguration()
 	if !assert.NoError(t, err) {
@@ -115,7 +143,7 @@ func TestHasConfigurationFalse(t *testing.T) {
 }
 
 func TestHasConfigurationTrue(t *testing.T) {
-	client := makeCoreKeeperClient(getUniqueServiceName())
+	client := makeCoreKeeperClient(t, getUniqueServiceName())
 
 	// delete the configuration created
 	defer reset(t, client)
@@ -133,7 +161,7 @@ func TestHasConfigurationTrue(t *testing.T) {
 }
 
 func TestHasSubConfigurationFalse(t *testing.T) {
-	client := makeCoreKeeperClient(getUniqueServiceName())
+	client := makeCoreKeeperClient(t, getUniqueServiceName())
 
 	actual, err := client.HasSubConfiguration(dummyConfig)
 	if !assert.NoError(t, err) {
@@ -143,7 +171,7 @@ func TestHasSubConfigurationFalse(t *testing.T) {
 }
 
 func TestHasSubConfigurationTrue(t *testing.T) {
-	client := makeCoreKeeperClient(getUniqueServiceName())
+	client := makeCoreKeeperClient(t, getUniqueServiceName())
 
 	// delete the configuration created
 	defer reset(t, client)
@@ -171,7 +199,7 @@ func createConfigMap() map[string]interface{} {
 }
 
 func TestPutConfigurationTomlNoPreValues(t *testing.T) {
-	client := makeCoreKeeperClient(getUniqueServiceName())
+	client := makeCoreKeeperClient(t, getUniqueServiceName())
 
 	// delete the configuration created
 	defer reset(t, client)
@@ -189,7 +217,7 @@ func TestPutConfigurationTomlNoPreValues(t *testing.T) {
 }
 
 func TestPutConfigurationTomlWithoutOverwrite(t *testing.T) {
-	client := makeCoreKeeperClient(getUniqueServiceName())
+	client := makeCoreKeeperClient(t, getUniqueServiceName())
 
 	// delete the configuration created
 	defer reset(t, client)
@@ -231,7 +259,7 @@ func TestPutConfigurationTomlWithoutOverwrite(t *testing.T) {
 }
 
 func TestPutConfigurationTomlWithOverwrite(t *testing.T) {
-	client := makeCoreKeeperClient(getUniqueServiceName())
+	client := makeCoreKeeperClient(t, getUniqueServiceName())
 
 	// delete the configuration created
 	defer reset(t, client)
@@ -272,8 +300,96 @@ func TestPutConfigurationTomlWithOverwrite(t *testing.T) {
 	}
 }
 
+// makeDedicatedCoreKeeperClient makes a client of a dedicated mock Core Keeper, so the requests of the watches left
+// running by other tests aren't counted and don't receive the injected failures
+func makeDedicatedCoreKeeperClient(t *testing.T) (*keeperClient, *MockCoreKeeper) {
+	if mockCoreKeeper == nil {
+		t.Skip("test requires the mock Core Keeper")
+	}
+
+	mock := NewMockCoreKeeper()
+	server := mock.Start()
+	t.Cleanup(server.Close)
+	URL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	serverPort, err := strconv.Atoi(URL.Port())
+	require.NoError(t, err)
+
+	client, err := NewKeeperClient(types.ServiceConfig{
+		Host:     URL.Hostname(),
+		Port:     serverPort,
+		BasePath: getUniqueServiceName(),
+	})
+	require.NoError(t, err)
+	return client, mock
+}
+
+// createLargeConfigMap creates a configuration of count values, spread over several sections
+func createLargeConfigMap(count int) map[string]interface{} {
+	configMap := make(map[string]interface{})
+	for i := 0; i < count; i++ {
+		section, ok := configMap[fmt.Sprintf("Section%d", i%10)].(map[string]interface{})
+		if !ok {
+			section = make(map[string]interface{})
+			configMap[fmt.Sprintf("Section%d", i%10)] = section
+		}
+		section[fmt.Sprintf("Key%d", i)] = fmt.Sprintf("value%d", i)
+	}
+	return configMap
+}
+
+func TestPutConfigurationBatches(t *testing.T) {
+	client, mock := makeDedicatedCoreKeeperClient(t)
+	require.NoError(t, client.PutConfigurationValue("Section3/Key123", []byte("seeded")))
+	requests := mock.Requests()
+
+	require.NoError(t, client.PutConfiguration(createLargeConfigMap(200), false))
+
+	// a single read of the stored keys, then a request per 64 missing values
+	assert.Equal(t, 1+4, mock.Requests()-requests)
+	keys, err := client.keeperClient.KV().Keys(context.Background(), client.configBasePath)
+	require.NoError(t, err)
+	assert.Len(t, keys.Keys, 200)
+	value, err := client.GetConfigurationValue("Section3/Key123")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("seeded"), value)
+	value, err = client.GetConfigurationValue("Section4/Key124")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("value124"), value)
+}
+
+func TestPutConfigurationStruct(t *testing.T) {
+	client, _ := makeDedicatedCoreKeeperClient(t)
+	require.NoError(t, client.PutConfigurationValue("Writable/LogLevel", []byte("DEBUG")))
+
+	require.NoError(t, client.PutConfiguration(&models.ConfigurationStruct{
+		Writable:     models.WritableInfo{LogLevel: "INFO"},
+		MessageQueue: models.MessageBusInfo{Host: "localhost", Port: 1883},
+	}, false))
+
+	value, err := client.GetConfigurationValue("Writable/LogLevel")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("DEBUG"), value)
+	value, err = client.GetConfigurationValue("MessageQueue/Port")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("1883"), value)
+}
+
+func TestPutConfigurationRollback(t *testing.T) {
+	client, mock := makeDedicatedCoreKeeperClient(t)
+	require.NoError(t, client.PutConfigurationValue("Section0/Key0", []byte("seeded")))
+
+	// the third batch fails, the values created by the first two must be deleted
+	mock.InjectPutFailure(2, http.StatusInternalServerError)
+	require.Error(t, client.PutConfiguration(createLargeConfigMap(200), false))
+
+	keys, err := client.keeperClient.KV().Keys(context.Background(), client.configBasePath)
+	require.NoError(t, err)
+	assert.Equal(t, []dtos.KeyOnly{dtos.KeyOnly(client.fullPath("Section0/Key0"))}, keys.Keys)
+}
+
 func TestPutConfiguration(t *testing.T) {
-	client := makeCoreKeeperClient(getUniqueServiceName())
+	client := makeCoreKeeperClient(t, getUniqueServiceName())
 
 	// delete the configuration created
 	defer reset(t, client)
@@ -308,7 +424,7 @@ func TestPutConfiguration(t *testing.T) {
 }
 
 func TestGetConfiguration(t *testing.T) {
-	client := makeCoreKeeperClient(getUniqueServiceName())
+	client := makeCoreKeeperClient(t, getUniqueServiceName())
 
 	// delete the configuration created
 	defer reset(t, client)
@@ -349,7 +465,7 @@ func TestGetConfiguration(t *testing.T) {
 }
 
 func TestConfigurationValueExists(t *testing.T) {
-	client := makeCoreKeeperClient(getUniqueServiceName())
+	client := makeCoreKeeperClient(t, getUniqueServiceName())
 
 	// delete the configuration created
 	defer reset(t, client)
@@ -379,7 +495,7 @@ func TestConfigurationValueExists(t *testing.T) {
 }
 
 func TestGetConfigurationValue(t *testing.T) {
-	client := makeCoreKeeperClient(getUniqueServiceName())
+	client := makeCoreKeeperClient(t, getUniqueServiceName())
 
 	// delete the configuration created
 	defer reset(t, client)
@@ -399,7 +515,7 @@ func TestGetConfigurationValue(t *testing.T) {
 }
 
 func TestPutConfigurationValue(t *testing.T) {
-	client := makeCoreKeeperClient(getUniqueServiceName())
+	client := makeCoreKeeperClient(t, getUniqueServiceName())
 
 	// delete the configuration created
 	defer reset(t, client)
@@ -409,7 +525,7 @@ func TestPutConfigurationValue(t *testing.T) {
 	err := client.PutConfigurationValue(key, expected)
 	assert.NoError(t, err)
 
-	resp, err := client.keeperClient.KV().Get(client.fullPath(key))
+	resp, err := client.keeperClient.KV().Get(context.Background(), client.fullPath(key))
 	if !assert.NoError(t, err) {
 		t.Fatal()
 	}
@@ -421,3 +537,1559 @@ func TestPutConfigurationValue(t *testing.T) {
 
 	assert.Equal(t, expected, actual)
 }
+
+func TestPutConfigurationValueCAS(t *testing.T) {
+	client := makeCoreKeeperClient(t, getUniqueServiceName())
+	other := makeCoreKeeperClient(t, client.configBasePath)
+	defer reset(t, client)
+
+	key := "Writable/LogLevel"
+	value, revision, err := client.GetConfigurationValueWithVersion(key)
+	require.NoError(t, err)
+	assert.Nil(t, value)
+	assert.Equal(t, types.NoRevision, revision)
+
+	// no revision only creates the value
+	require.NoError(t, client.PutConfigurationValueCAS(key, []byte("INFO"), types.NoRevision))
+	err = other.PutConfigurationValueCAS(key, []byte("TRACE"), types.NoRevision)
+	assert.True(t, errors.Is(err, types.ErrConflict), "unexpected error: %v", err)
+
+	value, revision, err = client.GetConfigurationValueWithVersion(key)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("INFO"), value)
+
+	// the write of the other client makes the revision stale
+	require.NoError(t, other.PutConfigurationValue(key, []byte("WARN")))
+	err = client.PutConfigurationValueCAS(key, []byte("DEBUG"), revision)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, types.ErrConflict), "unexpected error: %v", err)
+
+	_, revision, err = client.GetConfigurationValueWithVersion(key)
+	require.NoError(t, err)
+	require.NoError(t, client.PutConfigurationValueCAS(key, []byte("DEBUG"), revision))
+	value, err = other.GetConfigurationValue(key)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("DEBUG"), value)
+}
+
+func TestPutConfigurationValueCASConcurrent(t *testing.T) {
+	client := makeCoreKeeperClient(t, getUniqueServiceName())
+	defer reset(t, client)
+
+	key := "Writable/LogLevel"
+	require.NoError(t, client.PutConfigurationValue(key, []byte("INFO")))
+	_, revision, err := client.GetConfigurationValueWithVersion(key)
+	require.NoError(t, err)
+
+	// Core Keeper checks the revision, so only one of the clients writing concurrently against it succeeds
+	levels := []string{"TRACE", "DEBUG", "WARN", "ERROR"}
+	errs := make([]error, len(levels))
+	var wait sync.WaitGroup
+	for index, level := range levels {
+		other := makeCoreKeeperClient(t, client.configBasePath)
+		wait.Add(1)
+		go func(index int, level string) {
+			defer wait.Done()
+			errs[index] = other.PutConfigurationValueCAS(key, []byte(level), revision)
+		}(index, level)
+	}
+	wait.Wait()
+
+	var written []byte
+	for index, err := range errs {
+		if err == nil {
+			require.Nil(t, written, "more than one write succeeded")
+			written = []byte(levels[index])
+			continue
+		}
+		assert.True(t, errors.Is(err, types.ErrConflict), "unexpected error: %v", err)
+	}
+	value, err := client.GetConfigurationValue(key)
+	require.NoError(t, err)
+	assert.Equal(t, written, value)
+}
+
+func TestListConfigurationKeys(t *testing.T) {
+	client := makeCoreKeeperClient(t, getUniqueServiceName())
+	defer reset(t, client)
+
+	keys, err := client.ListConfigurationKeys("Writable")
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+	values, err := client.GetConfigurationValues("Writable")
+	require.NoError(t, err)
+	assert.Empty(t, values)
+
+	require.NoError(t, client.PutConfiguration(map[string]interface{}{
+		"Writable": map[string]interface{}{
+			"LogLevel":        "INFO",
+			"InsecureSecrets": map[string]interface{}{"DB": map[string]interface{}{"Path": "redisdb"}},
+		},
+		"WritableX": map[string]interface{}{"LogLevel": "DEBUG"},
+		"Service":   map[string]interface{}{"Port": 59880},
+	}, true))
+
+	keys, err = client.ListConfigurationKeys("Writable")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Writable/InsecureSecrets/DB/Path", "Writable/LogLevel"}, keys)
+
+	keys, err = client.ListConfigurationKeys("", types.WithDepth(1))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Service/", "Writable/", "WritableX/"}, keys)
+
+	values, err = client.GetConfigurationValues("", types.WithDepth(2), types.WithSeparator("."))
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]byte{
+		"Writable.LogLevel":  []byte("INFO"),
+		"WritableX.LogLevel": []byte("DEBUG"),
+		"Service.Port":       []byte("59880"),
+	}, values)
+}
+
+const importedToml = `
+Host = "localhost"
+Port = 59880
+Temp = 36.6
+Enabled = true
+Empty = ""
+Topics = ["events", "commands"]
+
+[[Routes]]
+  Path = "/api"
+  Port = 1
+
+[Writable]
+  LogLevel = "INFO"
+`
+
+func TestExportImportConfiguration(t *testing.T) {
+	source := makeCoreKeeperClient(t, getUniqueServiceName())
+	defer reset(t, source)
+	target := makeCoreKeeperClient(t, getUniqueServiceName())
+	defer reset(t, target)
+
+	err := source.ExportConfiguration(&bytes.Buffer{}, types.FormatTOML)
+	assert.True(t, errors.Is(err, types.ErrNotFound), "unexpected error: %v", err)
+
+	require.NoError(t, source.ImportConfiguration(strings.NewReader(importedToml), types.FormatTOML, false))
+	var exported bytes.Buffer
+	require.NoError(t, source.ExportConfiguration(&exported, types.FormatYAML))
+
+	// the exported document is restored exactly, with the same raw values
+	require.NoError(t, target.ImportConfiguration(bytes.NewReader(exported.Bytes()), types.FormatYAML, false))
+	var restored bytes.Buffer
+	require.NoError(t, target.ExportConfiguration(&restored, types.FormatYAML))
+	assert.Equal(t, exported.String(), restored.String())
+	sourceValues, err := source.GetConfigurationValues("")
+	require.NoError(t, err)
+	targetValues, err := target.GetConfigurationValues("")
+	require.NoError(t, err)
+	assert.Equal(t, sourceValues, targetValues)
+	assert.Equal(t, []byte("events"), targetValues["Topics/0"])
+	assert.Equal(t, []byte(""), targetValues["Empty"])
+
+	// the existing values are only replaced when overwrite is set
+	changed := strings.NewReader(`{"Port": 1234, "Writable": {"LogLevel": "DEBUG"}}`)
+	require.NoError(t, target.ImportConfiguration(changed, types.FormatJSON, false))
+	value, err := target.GetConfigurationValue("Port")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("59880"), value)
+	changed = strings.NewReader(`{"Port": 1234, "Writable": {"LogLevel": "DEBUG"}}`)
+	require.NoError(t, target.ImportConfiguration(changed, types.FormatJSON, true))
+	value, err = target.GetConfigurationValue("Port")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("1234"), value)
+}
+
+func TestExportImportEmptyAndNumbers(t *testing.T) {
+	client := makeCoreKeeperClient(t, getUniqueServiceName())
+	defer reset(t, client)
+	target := makeCoreKeeperClient(t, getUniqueServiceName())
+	defer reset(t, target)
+
+	document := `{
+  "Code": "01",
+  "Codes": {
+    "0": "a",
+    "1": "b"
+  },
+  "Empty": {},
+  "Enabled": "true",
+  "Port": "8000",
+  "Ratio": 1.0,
+  "Topics": [],
+  "Version": "1.0",
+  "Writable": {
+    "Labels": {},
+    "LogLevel": "INFO"
+  }
+}
+`
+	require.NoError(t, client.ImportConfiguration(strings.NewReader(document), types.FormatJSON, false))
+
+	// the services read the raw values, formatted as Go formats them
+	values, err := client.GetConfigurationValues("")
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]byte{
+		"Code":              []byte("01"),
+		"Codes/0":           []byte("a"),
+		"Codes/1":           []byte("b"),
+		"Enabled":           []byte("true"),
+		"Port":              []byte("8000"),
+		"Ratio":             []byte("1"),
+		"Version":           []byte("1.0"),
+		"Writable/LogLevel": []byte("INFO"),
+	}, values)
+
+	// while the document is exported exactly, with its types and its empty arrays and sections
+	var exported bytes.Buffer
+	require.NoError(t, client.ExportConfiguration(&exported, types.FormatJSON))
+	assert.Equal(t, document, exported.String())
+
+	// and restored exactly under another base path
+	require.NoError(t, target.ImportConfiguration(&exported, types.FormatJSON, false))
+	var restored bytes.Buffer
+	require.NoError(t, target.ExportConfiguration(&restored, types.FormatJSON))
+	assert.Equal(t, document, restored.String())
+}
+
+func TestPlanConfiguration(t *testing.T) {
+	client := makeCoreKeeperClient(t, getUniqueServiceName())
+	defer reset(t, client)
+
+	require.NoError(t, client.PutConfigurationValue("Port", []byte("8000")))
+	require.NoError(t, client.PutConfigurationValue("Host", []byte("localhost")))
+	require.NoError(t, client.PutConfigurationValue("Writable/LogLevel", []byte("INFO")))
+
+	plan, err := client.PlanConfiguration(TestConfig{
+		Logging:  LoggingInfo{EnableRemote: true, File: "NONE"},
+		Port:     8001,
+		Host:     "localhost",
+		LogLevel: "DEBUG",
+		Temp:     36.5,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []types.KeyChange{
+		{Key: "LogLevel", NewValue: []byte("DEBUG")},
+		{Key: "Logging/EnableRemote", NewValue: []byte("true")},
+		{Key: "Logging/File", NewValue: []byte("NONE")},
+		{Key: "Temp", NewValue: []byte("36.5")},
+	}, plan.Changes.Added)
+	assert.Equal(t, []types.KeyChange{{Key: "Port", OldValue: []byte("8000"), NewValue: []byte("8001")}}, plan.Changes.Modified)
+	assert.Equal(t, []types.KeyChange{{Key: "Writable/LogLevel", OldValue: []byte("INFO")}}, plan.Changes.Removed)
+
+	// nothing is changed until the plan is applied
+	value, err := client.GetConfigurationValue("Port")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("8000"), value)
+
+	tree, err := toml.Load(`Port = 8000
+Host = "localhost"
+[Writable]
+  LogLevel = "INFO"
+`)
+	require.NoError(t, err)
+	plan, err = client.PlanConfiguration(tree)
+	require.NoError(t, err)
+	assert.True(t, plan.IsEmpty())
+}
+
+func TestApplyPlan(t *testing.T) {
+	client := makeCoreKeeperClient(t, getUniqueServiceName())
+	other := makeCoreKeeperClient(t, client.configBasePath)
+	defer reset(t, client)
+
+	require.NoError(t, client.PutConfigurationValue("Port", []byte("8000")))
+	require.NoError(t, client.PutConfigurationValue("Writable/LogLevel", []byte("INFO")))
+	desired := map[string]interface{}{"Port": 8001, "Host": "localhost"}
+
+	plan, err := client.PlanConfiguration(desired)
+	require.NoError(t, err)
+	require.NoError(t, client.ApplyPlan(plan))
+
+	values, err := client.GetConfigurationValues("")
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]byte{"Port": []byte("8001"), "Host": []byte("localhost")}, values)
+	plan, err = client.PlanConfiguration(desired)
+	require.NoError(t, err)
+	assert.True(t, plan.IsEmpty())
+
+	// the write of the other client since the plan was made prevents applying any of it
+	plan, err = client.PlanConfiguration(map[string]interface{}{"Port": 8002, "Host": "127.0.0.1"})
+	require.NoError(t, err)
+	require.NoError(t, other.PutConfigurationValue("Host", []byte("edgex-core-data")))
+	err = client.ApplyPlan(plan)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, types.ErrConflict), "unexpected error: %v", err)
+
+	values, err = client.GetConfigurationValues("")
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]byte{"Port": []byte("8001"), "Host": []byte("edgex-core-data")}, values)
+}
+
+func TestApplyPlanRollback(t *testing.T) {
+	client, mock := makeDedicatedCoreKeeperClient(t)
+	require.NoError(t, client.PutConfiguration(createLargeConfigMap(100), false))
+	before, err := client.GetConfigurationValues("")
+	require.NoError(t, err)
+
+	// the values are added and modified in batches of 64 values, then Section0 is removed
+	desired := createLargeConfigMap(110)
+	delete(desired, "Section0")
+	for _, section := range desired {
+		for key, value := range section.(map[string]interface{}) {
+			section.(map[string]interface{})[key] = value.(string) + "-changed"
+		}
+	}
+	plan, err := client.PlanConfiguration(desired)
+	require.NoError(t, err)
+	require.Len(t, plan.Changes.Added, 9)
+	require.Len(t, plan.Changes.Modified, 90)
+	require.Len(t, plan.Changes.Removed, 10)
+
+	// the second batch fails, the values added and modified by the first one must be reverted
+	mock.InjectPutFailure(1, http.StatusInternalServerError)
+	require.Error(t, client.ApplyPlan(plan))
+
+	after, err := client.GetConfigurationValues("")
+	require.NoError(t, err)
+	assert.Equal(t, before, after)
+}
+
+func TestContextCanceled(t *testing.T) {
+	client := makeCoreKeeperClient(t, getUniqueServiceName())
+
+	// delete the configuration created
+	defer reset(t, client)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := client.PutConfigurationValueCtx(ctx, "Foo", []byte("bar"))
+	require.Error(t, err)
+
+	_, err = client.GetConfigurationValueCtx(ctx, "Foo")
+	require.Error(t, err)
+
+	_, err = client.GetConfigurationCtx(ctx, &TestConfig{})
+	require.Error(t, err)
+
+	assert.False(t, client.IsAliveCtx(ctx))
+}
+
+func TestContextDeadline(t *testing.T) {
+	client := makeCoreKeeperClient(t, getUniqueServiceName())
+
+	// delete the configuration created
+	defer reset(t, client)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	key := "Foo"
+	expected := []byte("bar")
+	err := client.PutConfigurationValueCtx(ctx, key, expected)
+	require.NoError(t, err)
+
+	actual, err := client.GetConfigurationValueCtx(ctx, key)
+	require.NoError(t, err)
+	assert.Equal(t, expected, actual)
+
+	exists, err := client.ConfigurationValueExistsCtx(ctx, key)
+	require.NoError(t, err)
+	assert.True(t, exists)
+}
+
+func TestDeleteConfigurationValue(t *testing.T) {
+	client := makeCoreKeeperClient(t, getUniqueServiceName())
+
+	// delete the configuration created
+	defer reset(t, client)
+
+	key := "Foo"
+	err := client.PutConfigurationValue(key, []byte("bar"))
+	require.NoError(t, err)
+	err = client.PutConfigurationValue("FooBar", []byte("bar"))
+	require.NoError(t, err)
+
+	err = client.DeleteConfigurationValue(key)
+	require.NoError(t, err)
+
+	assert.False(t, configValueExists(key, client))
+	assert.True(t, configValueExists("FooBar", client))
+
+	// deleting a value which doesn't exist is not an error
+	err = client.DeleteConfigurationValue(key)
+	require.NoError(t, err)
+}
+
+func TestDeleteSubConfiguration(t *testing.T) {
+	client := makeCoreKeeperClient(t, getUniqueServiceName())
+
+	// delete the configuration created
+	defer reset(t, client)
+
+	configMap := createConfigMap()
+	configMap["nestedNodeSibling"] = "sibling"
+	err := client.PutConfiguration(configMap, true)
+	require.NoError(t, err)
+
+	err = client.DeleteSubConfiguration("nestedNode")
+	require.NoError(t, err)
+
+	assert.False(t, configValueExists("nestedNode/field1", client))
+	assert.False(t, configValueExists("nestedNode/field2", client))
+	assert.True(t, configValueExists("nestedNodeSibling", client))
+	assert.True(t, configValueExists("string", client))
+
+	// deleting a sub configuration which doesn't exist is not an error
+	err = client.DeleteSubConfiguration("nestedNode")
+	require.NoError(t, err)
+}
+
+func TestDeleteSubConfigurationSiblingPrefix(t *testing.T) {
+	client := makeCoreKeeperClient(t, getUniqueServiceName())
+	sibling := makeCoreKeeperClient(t, client.configBasePath+"-2")
+	defer reset(t, client)
+	defer reset(t, sibling)
+
+	require.NoError(t, client.PutConfigurationValue("Writable/LogLevel", []byte("INFO")))
+	require.NoError(t, client.PutConfigurationValue("WritableX/LogLevel", []byte("INFO")))
+	require.NoError(t, sibling.PutConfigurationValue("Writable/LogLevel", []byte("INFO")))
+
+	// the section is deleted, not the sections whose name starts with the same string
+	require.NoError(t, client.DeleteSubConfiguration("Writable"))
+	assert.False(t, configValueExists("Writable/LogLevel", client))
+	assert.True(t, configValueExists("WritableX/LogLevel", client))
+
+	// the service's configuration is deleted, not the one of the services whose name starts with the same string
+	require.NoError(t, client.DeleteSubConfiguration(""))
+	assert.False(t, configValueExists("WritableX/LogLevel", client))
+	assert.True(t, configValueExists("Writable/LogLevel", sibling))
+}
+
+func TestAccessToken(t *testing.T) {
+	if mockCoreKeeper == nil {
+		t.Skip("access token test requires the mock Core Keeper")
+	}
+
+	client := makeCoreKeeperClient(t, getUniqueServiceName())
+	valueName := "testAccess"
+
+	// Test if have access to endpoint w/o access token set
+	_, err := client.ConfigurationValueExists(valueName)
+	require.NoError(t, err)
+
+	expectedToken := "MyAccessToken"
+	mockCoreKeeper.SetExpectedAccessToken(expectedToken)
+	defer mockCoreKeeper.ClearExpectedAccessToken()
+
+	// Now verify get error w/o providing the expected access token
+	_, err = client.ConfigurationValueExists(valueName)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), http.StatusText(http.StatusUnauthorized))
+	assert.True(t, errors.Is(err, types.ErrUnauthorized))
+	assert.False(t, client.IsAlive())
+
+	// and no error when providing it
+	client = makeCoreKeeperClientWithToken(t, getUniqueServiceName(), expectedToken, nil)
+	_, err = client.ConfigurationValueExists(valueName)
+	require.NoError(t, err)
+	assert.True(t, client.IsAlive())
+}
+
+func TestRenewAccessToken(t *testing.T) {
+	if mockCoreKeeper == nil {
+		t.Skip("access token test requires the mock Core Keeper")
+	}
+
+	goodToken := "bfb78dc5-c6a3-33d9-88b5-e3a4b63dda77" // nolint: gosec
+	badToken := "badToken-c6a3-33d9-88b5-e3a4b63dda77"  // nolint: gosec
+	serviceName := getUniqueServiceName()
+
+	renewCount := 0
+	getAccessToken := func() (string, error) {
+		renewCount++
+		return goodToken, nil
+	}
+
+	mockCoreKeeper.SetExpectedAccessToken(goodToken)
+	defer mockCoreKeeper.ClearExpectedAccessToken()
+
+	t.Run("PutConfiguration", func(t *testing.T) {
+		client := makeCoreKeeperClientWithToken(t, serviceName, badToken, getAccessToken)
+		defer reset(t, client)
+
+		err := client.PutConfiguration(createConfigMap(), true)
+		require.NoError(t, err)
+	})
+
+	t.Run("PutConfigurationValue", func(t *testing.T) {
+		client := makeCoreKeeperClientWithToken(t, serviceName, badToken, getAccessToken)
+		defer reset(t, client)
+
+		err := client.PutConfigurationValue("Host", []byte("Hello"))
+		require.NoError(t, err)
+
+		// token has been renewed, so this call must succeed without renewing it again
+		renewCount = 0
+		actual, err := client.GetConfigurationValue("Host")
+		require.NoError(t, err)
+		assert.Equal(t, []byte("Hello"), actual)
+		assert.Equal(t, 0, renewCount)
+	})
+
+	t.Run("HasConfiguration", func(t *testing.T) {
+		client := makeCoreKeeperClientWithToken(t, serviceName, badToken, getAccessToken)
+
+		_, err := client.HasConfiguration()
+		require.NoError(t, err)
+	})
+
+	t.Run("RenewFailed", func(t *testing.T) {
+		failingCallback := func() (string, error) {
+			return "", errors.New("secret store unavailable")
+		}
+		client := makeCoreKeeperClientWithToken(t, serviceName, badToken, failingCallback)
+
+		_, err := client.HasConfiguration()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to renew access token")
+	})
+}
+
+func makeTLSCoreKeeperClient(t *testing.T, serverUrl string, tlsConfig types.TLSConfig) (*keeperClient, error) {
+	URL, err := url.Parse(serverUrl)
+	require.NoError(t, err)
+	serverPort, err := strconv.Atoi(URL.Port())
+	require.NoError(t, err)
+
+	return NewKeeperClient(types.ServiceConfig{
+		Protocol: "https",
+		Host:     URL.Hostname(),
+		Port:     serverPort,
+		BasePath: getUniqueServiceName(),
+		TLS:      tlsConfig,
+	})
+}
+
+// createClientCertificate creates a self-signed client certificate and returns the PEM encoded certificate and key
+func createClientCertificate(t *testing.T) ([]byte, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: serviceName},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	certDer, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certPem := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDer})
+	keyPem := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})
+	return certPem, keyPem
+}
+
+func TestTLS(t *testing.T) {
+	if mockCoreKeeper == nil {
+		t.Skip("TLS test requires the mock Core Keeper")
+	}
+
+	server := mockCoreKeeper.StartTLS()
+	defer server.Close()
+
+	caPem := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	require.NoError(t, os.WriteFile(caFile, caPem, 0600))
+
+	tests := []struct {
+		name          string
+		tlsConfig     types.TLSConfig
+		expectedAlive bool
+	}{
+		{"CA PEM", types.TLSConfig{CAPem: caPem}, true},
+		{"CA file", types.TLSConfig{CAFile: caFile}, true},
+		{"CA PEM with server name", types.TLSConfig{CAPem: caPem, ServerName: "example.com"}, true},
+		{"insecure skip verify", types.TLSConfig{InsecureSkipVerify: true}, true},
+		{"unknown authority", types.TLSConfig{}, false},
+		{"wrong server name", types.TLSConfig{CAPem: caPem, ServerName: "wrong.invalid"}, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client, err := makeTLSCoreKeeperClient(t, server.URL, test.tlsConfig)
+			require.NoError(t, err)
+
+			assert.Equal(t, test.expectedAlive, client.IsAlive())
+			if !test.expectedAlive {
+				return
+			}
+
+			defer reset(t, client)
+			err = client.PutConfigurationValue("Foo", []byte("bar"))
+			require.NoError(t, err)
+			actual, err := client.GetConfigurationValue("Foo")
+			require.NoError(t, err)
+			assert.Equal(t, []byte("bar"), actual)
+		})
+	}
+
+	t.Run("bad CA file", func(t *testing.T) {
+		_, err := makeTLSCoreKeeperClient(t, server.URL, types.TLSConfig{CAFile: filepath.Join(t.TempDir(), "missing.pem")})
+		require.Error(t, err)
+	})
+
+	t.Run("CA PEM and file", func(t *testing.T) {
+		// the CA file is appended to a copy of the CA PEM, never into its spare capacity
+		backing := make([]byte, len(caPem)+4096)
+		copy(backing, caPem)
+		client, err := makeTLSCoreKeeperClient(t, server.URL, types.TLSConfig{CAPem: backing[:len(caPem)], CAFile: caFile})
+		require.NoError(t, err)
+		assert.True(t, client.IsAlive())
+		assert.Equal(t, make([]byte, 4096), backing[len(caPem):])
+	})
+}
+
+func TestMutualTLS(t *testing.T) {
+	if mockCoreKeeper == nil {
+		t.Skip("TLS test requires the mock Core Keeper")
+	}
+
+	certPem, keyPem := createClientCertificate(t)
+	clientCAs := x509.NewCertPool()
+	require.True(t, clientCAs.AppendCertsFromPEM(certPem))
+
+	server := httptest.NewUnstartedServer(mockCoreKeeper.newHandler())
+	server.TLS = &tls.Config{
+		ClientAuth: tls.RequireAndVerifyClientCert,
+		ClientCAs:  clientCAs,
+		MinVersion: tls.VersionTLS12,
+	}
+	server.StartTLS()
+	defer server.Close()
+
+	caPem := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	tempDir := t.TempDir()
+	certFile := filepath.Join(tempDir, "cert.pem")
+	keyFile := filepath.Join(tempDir, "key.pem")
+	require.NoError(t, os.WriteFile(certFile, certPem, 0600))
+	require.NoError(t, os.WriteFile(keyFile, keyPem, 0600))
+
+	t.Run("PEM", func(t *testing.T) {
+		client, err := makeTLSCoreKeeperClient(t, server.URL, types.TLSConfig{CAPem: caPem, CertPem: certPem, KeyPem: keyPem})
+		require.NoError(t, err)
+		assert.True(t, client.IsAlive())
+	})
+
+	t.Run("files", func(t *testing.T) {
+		client, err := makeTLSCoreKeeperClient(t, server.URL, types.TLSConfig{CAPem: caPem, CertFile: certFile, KeyFile: keyFile})
+		require.NoError(t, err)
+		assert.True(t, client.IsAlive())
+	})
+
+	t.Run("no client certificate", func(t *testing.T) {
+		client, err := makeTLSCoreKeeperClient(t, server.URL, types.TLSConfig{CAPem: caPem})
+		require.NoError(t, err)
+		assert.False(t, client.IsAlive())
+
+		_, err = client.HasConfiguration()
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, types.ErrUnavailable))
+	})
+
+	t.Run("missing key file", func(t *testing.T) {
+		_, err := makeTLSCoreKeeperClient(t, server.URL, types.TLSConfig{CAPem: caPem, CertFile: certFile})
+		require.Error(t, err)
+	})
+}
+
+func TestNotRunning(t *testing.T) {
+	// bind and close a listener to get a port nobody is listening on
+	server := httptest.NewServer(nil)
+	URL, _ := url.Parse(server.URL)
+	server.Close()
+	deadPort, _ := strconv.Atoi(URL.Port())
+
+	client, err := NewKeeperClient(types.ServiceConfig{
+		Host:     URL.Hostname(),
+		Port:     deadPort,
+		BasePath: getUniqueServiceName(),
+	})
+	require.NoError(t, err)
+
+	assert.False(t, client.IsAlive())
+
+	_, err = client.HasConfiguration()
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, types.ErrUnavailable))
+	var transportErr *httpUtils.TransportError
+	assert.True(t, errors.As(err, &transportErr))
+
+	_, err = client.GetConfiguration(&TestConfig{})
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, types.ErrUnavailable))
+
+	err = client.PutConfigurationValue("Foo", []byte("bar"))
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, types.ErrUnavailable))
+}
+
+func TestErrorNotFound(t *testing.T) {
+	client := makeCoreKeeperClient(t, getUniqueServiceName())
+
+	_, err := client.GetConfiguration(&TestConfig{})
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, types.ErrNotFound))
+
+	_, err = client.keeperClient.KV().Get(context.Background(), client.fullPath("Foo"))
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, types.ErrNotFound))
+	var statusErr *httpUtils.StatusError
+	require.True(t, errors.As(err, &statusErr))
+	assert.Equal(t, http.StatusNotFound, statusErr.StatusCode)
+	assert.Contains(t, statusErr.Message, "not found")
+}
+
+func TestErrorDecode(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.WriteHeader(http.StatusOK)
+		_, _ = writer.Write([]byte("<html>not a Core Keeper response</html>"))
+	}))
+	defer server.Close()
+
+	URL, _ := url.Parse(server.URL)
+	serverPort, _ := strconv.Atoi(URL.Port())
+	client, err := NewKeeperClient(types.ServiceConfig{
+		Host:     URL.Hostname(),
+		Port:     serverPort,
+		BasePath: getUniqueServiceName(),
+	})
+	require.NoError(t, err)
+
+	_, err = client.HasConfiguration()
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, types.ErrDecode))
+	var decodeErr *httpUtils.DecodeError
+	assert.True(t, errors.As(err, &decodeErr))
+}
+
+func TestRetryPolicy(t *testing.T) {
+	if mockCoreKeeper == nil {
+		t.Skip("retry test requires the mock Core Keeper")
+	}
+
+	testCases := []struct {
+		Name          string
+		Failures      int
+		StatusCode    int
+		Policy        types.RetryPolicy
+		ExpectedError error
+	}{
+		{Name: "No retry policy", Failures: 1, StatusCode: http.StatusServiceUnavailable, ExpectedError: types.ErrUnavailable},
+		{Name: "Recovers", Failures: 2, StatusCode: http.StatusServiceUnavailable, Policy: types.RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond, Jitter: 0.5}},
+		{Name: "Recovers from internal error", Failures: 1, StatusCode: http.StatusInternalServerError, Policy: types.RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond}},
+		{Name: "Attempts exhausted", Failures: 3, StatusCode: http.StatusBadGateway, Policy: types.RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond}, ExpectedError: types.ErrUnavailable},
+		{Name: "Not retryable", Failures: 1, StatusCode: http.StatusUnauthorized, Policy: types.RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond}, ExpectedError: types.ErrUnauthorized},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.Name, func(t *testing.T) {
+			client, err := NewKeeperClient(types.ServiceConfig{
+				Host:     testHost,
+				Port:     port,
+				BasePath: getUniqueServiceName(),
+				Retry:    test.Policy,
+			})
+			require.NoError(t, err)
+			defer mockCoreKeeper.InjectTransientFailures(0, 0)
+
+			mockCoreKeeper.InjectTransientFailures(test.Failures, test.StatusCode)
+			err = client.PutConfigurationValue("Foo", []byte("bar"))
+			if test.ExpectedError != nil {
+				require.Error(t, err)
+				assert.True(t, errors.Is(err, test.ExpectedError), "unexpected error: %v", err)
+				return
+			}
+			require.NoError(t, err)
+
+			mockCoreKeeper.InjectTransientFailures(test.Failures, test.StatusCode)
+			actual, err := client.GetConfigurationValue("Foo")
+			require.NoError(t, err)
+			assert.Equal(t, []byte("bar"), actual)
+		})
+	}
+}
+
+type watchTestMessageQueue struct {
+	Host               string
+	Port               int
+	Type               string
+	PublishTopicPrefix string
+	SubscribeTopic     string
+}
+
+type watchTestConfig struct {
+	Writable     models.WritableInfo
+	MessageQueue watchTestMessageQueue
+	Logging      LoggingInfo
+}
+
+func makeWatchingCoreKeeperClient(t *testing.T) (*keeperClient, *MockMessageBus) {
+	bus := NewMockMessageBus()
+	original := newMessageClient
+	newMessageClient = bus.NewMessageClient
+	t.Cleanup(func() { newMessageClient = original })
+
+	client := makeCoreKeeperClient(t, getUniqueServiceName())
+	require.NoError(t, client.PutConfiguration(&watchTestConfig{
+		Writable:     models.WritableInfo{LogLevel: "INFO"},
+		MessageQueue: watchTestMessageQueue{Host: "localhost", Port: 1883, Type: "mqtt"},
+		Logging:      LoggingInfo{File: "/tmp/keeper.log"},
+	}, true))
+	return client, bus
+}
+
+func receiveUpdate(t *testing.T, updateChannel chan interface{}) interface{} {
+	select {
+	case update := <-updateChannel:
+		return update
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "timed out waiting for update")
+	}
+	return nil
+}
+
+func waitReady(t *testing.T, handle types.WatchHandle) {
+	select {
+	case <-handle.Ready():
+	case <-handle.Done():
+		require.Fail(t, "watch stopped before being ready")
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "timed out waiting for the watch to be ready")
+	}
+}
+
+// changeValue changes the value in Core Keeper and publishes the change the same way Core Keeper does
+func changeValue(t *testing.T, client *keeperClient, bus *MockMessageBus, key string, value string) {
+	require.NoError(t, client.PutConfigurationValue(key, []byte(value)))
+	require.NoError(t, bus.PublishChange(client.fullPath(key), value))
+}
+
+func TestWatchForChangesSharedConnection(t *testing.T) {
+	client, bus := makeWatchingCoreKeeperClient(t)
+	defer client.StopWatching()
+
+	writableUpdates := make(chan interface{})
+	writableErrors := make(chan error)
+	writableHandle := client.WatchForChanges(writableUpdates, writableErrors, &models.WritableInfo{}, "Writable")
+	waitReady(t, writableHandle)
+	assert.Equal(t, &models.WritableInfo{LogLevel: "INFO"}, receiveUpdate(t, writableUpdates))
+
+	loggingUpdates := make(chan interface{})
+	loggingErrors := make(chan error)
+	loggingHandle := client.WatchForChanges(loggingUpdates, loggingErrors, &LoggingInfo{}, "Logging")
+	waitReady(t, loggingHandle)
+	assert.Equal(t, &LoggingInfo{File: "/tmp/keeper.log"}, receiveUpdate(t, loggingUpdates))
+
+	// Both watches share a single connection
+	assert.Equal(t, 1, bus.Connections())
+
+	// Each change is only sent to the watch of the changed key
+	changeValue(t, client, bus, "Writable/LogLevel", "DEBUG")
+	assert.Equal(t, &models.WritableInfo{LogLevel: "DEBUG"}, receiveUpdate(t, writableUpdates))
+	changeValue(t, client, bus, "Logging/File", "/tmp/changed.log")
+	assert.Equal(t, &LoggingInfo{File: "/tmp/changed.log"}, receiveUpdate(t, loggingUpdates))
+
+	// Stopping one watch doesn't stop the other one
+	writableHandle.Stop()
+	select {
+	case <-writableHandle.Done():
+	default:
+		require.Fail(t, "watch expected to be stopped")
+	}
+	assert.Equal(t, 1, bus.Connections())
+	changeValue(t, client, bus, "Writable/LogLevel", "WARN")
+	changeValue(t, client, bus, "Logging/EnableRemote", "true")
+	assert.Equal(t, &LoggingInfo{EnableRemote: true, File: "/tmp/changed.log"}, receiveUpdate(t, loggingUpdates))
+	select {
+	case update := <-writableUpdates:
+		require.Fail(t, "update not expected from a stopped watch", update)
+	default:
+	}
+
+	// The message bus errors are sent to the watches
+	bus.PublishError(errors.New("bus failure"))
+	select {
+	case err := <-loggingErrors:
+		assert.EqualError(t, err, "bus failure")
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "timed out waiting for error")
+	}
+
+	// The connection is closed once the last watch is stopped
+	loggingHandle.Stop()
+	assert.Equal(t, 0, bus.Connections())
+}
+
+func TestWatchForChangesSlowConsumer(t *testing.T) {
+	client, bus := makeWatchingCoreKeeperClient(t)
+	defer client.StopWatching()
+
+	stalledUpdates := make(chan interface{})
+	stalledHandle := client.WatchForChanges(stalledUpdates, make(chan error), &models.WritableInfo{}, "Writable")
+	waitReady(t, stalledHandle)
+
+	loggingUpdates := make(chan interface{})
+	loggingHandle := client.WatchForChanges(loggingUpdates, make(chan error), &LoggingInfo{}, "Logging")
+	waitReady(t, loggingHandle)
+	assert.Equal(t, &LoggingInfo{File: "/tmp/keeper.log"}, receiveUpdate(t, loggingUpdates))
+
+	// the watch whose updates aren't consumed doesn't delay the changes of the other watch, the changes are published
+	// from another goroutine so a blocked dispatch fails the test rather than hanging it
+	go func() {
+		changes := [][2]string{
+			{"Writable/LogLevel", "DEBUG"},
+			{"Writable/LogLevel", "TRACE"},
+			{"Writable/LogLevel", "WARN"},
+			{"Logging/File", "/tmp/changed.log"},
+		}
+		for _, change := range changes {
+			assert.NoError(t, client.PutConfigurationValue(change[0], []byte(change[1])))
+			assert.NoError(t, bus.PublishChange(client.fullPath(change[0]), change[1]))
+		}
+	}()
+	assert.Equal(t, &LoggingInfo{File: "/tmp/changed.log"}, receiveUpdate(t, loggingUpdates))
+
+	// the changes pending while the watch was stalled are delivered with the last value
+	var update interface{}
+	for update == nil || update.(*models.WritableInfo).LogLevel != "WARN" {
+		update = receiveUpdate(t, stalledUpdates)
+	}
+}
+
+func TestWatchForChangesResync(t *testing.T) {
+	client, bus := makeWatchingCoreKeeperClient(t)
+	defer client.StopWatching()
+
+	updateChannel := make(chan interface{})
+	errorChannel := make(chan error)
+	configuration := &LoggingInfo{}
+	waitReady(t, client.WatchForChanges(updateChannel, errorChannel, configuration, "Logging"))
+	assert.Equal(t, &LoggingInfo{File: "/tmp/keeper.log"}, receiveUpdate(t, updateChannel))
+
+	// The whole watched configuration is read again into a new struct
+	changeValue(t, client, bus, "Logging/EnableRemote", "true")
+	update := receiveUpdate(t, updateChannel)
+	assert.Equal(t, &LoggingInfo{EnableRemote: true, File: "/tmp/keeper.log"}, update)
+	assert.NotSame(t, configuration, update)
+
+	// The deleted keys are cleared
+	require.NoError(t, client.DeleteConfigurationValue("Logging/File"))
+	require.NoError(t, bus.PublishChange(client.fullPath("Logging/File"), nil))
+	assert.Equal(t, &LoggingInfo{EnableRemote: true}, receiveUpdate(t, updateChannel))
+
+	// The decoding errors are sent to the error channel instead of an update
+	changeValue(t, client, bus, "Logging/EnableRemote", "not a bool")
+	select {
+	case err := <-errorChannel:
+		assert.True(t, errors.Is(err, types.ErrDecode), "unexpected error: %v", err)
+	case update := <-updateChannel:
+		require.Fail(t, "update not expected when decoding fails", update)
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "timed out waiting for error")
+	}
+}
+
+func TestWatchForChangeSetsReconnect(t *testing.T) {
+	client, bus := makeWatchingCoreKeeperClient(t)
+	client.reconnectPolicy = types.RetryPolicy{InitialBackoff: time.Millisecond, MaxBackoff: 10 * time.Millisecond}
+	defer client.StopWatching()
+
+	updateChannel := make(chan types.WatchUpdate)
+	errorChannel := make(chan error)
+	client.WatchForChangeSets(updateChannel, errorChannel, &LoggingInfo{}, "Logging")
+
+	receive := func() types.WatchUpdate {
+		select {
+		case update := <-updateChannel:
+			return update
+		case err := <-errorChannel:
+			require.NoError(t, err)
+		case <-time.After(5 * time.Second):
+			require.Fail(t, "timed out waiting for update")
+		}
+		return types.WatchUpdate{}
+	}
+	receive()
+
+	// The changes made while the connection is lost aren't published
+	require.NoError(t, client.PutConfigurationValue("Logging/File", []byte("/tmp/changed.log")))
+	require.NoError(t, client.PutConfigurationValue("Logging/EnableRemote", []byte("true")))
+	bus.FailConnects(errors.New("connection refused"), errors.New("connection refused"))
+	bus.PublishError(errors.New("connection lost"))
+	select {
+	case err := <-errorChannel:
+		assert.EqualError(t, err, "connection lost")
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "timed out waiting for error")
+	}
+
+	// Once reconnected, the missed changes are delivered as a single update
+	update := receive()
+	assert.Equal(t, &LoggingInfo{EnableRemote: true, File: "/tmp/changed.log"}, update.Configuration)
+	assert.Equal(t, types.ChangeSet{Modified: []types.KeyChange{
+		{Key: "EnableRemote", OldValue: []byte("false"), NewValue: []byte("true")},
+		{Key: "File", OldValue: []byte("/tmp/keeper.log"), NewValue: []byte("/tmp/changed.log")},
+	}}, update.Changes)
+	assert.Equal(t, 1, bus.Connections())
+	assert.Len(t, bus.Configs(), 4)
+
+	// The changes are received from the new connection
+	changeValue(t, client, bus, "Logging/File", "/tmp/keeper.log")
+	update = receive()
+	assert.Equal(t, []string{"File"}, update.Changes.Keys())
+
+	// Nothing is delivered when nothing has been missed
+	bus.PublishError(errors.New("connection lost"))
+	<-errorChannel
+	select {
+	case update := <-updateChannel:
+		require.Fail(t, "unexpected update", "%v", update)
+	case <-time.After(100 * time.Millisecond):
+	}
+	assert.Equal(t, 1, bus.Connections())
+}
+
+func TestWatchForChangeSets(t *testing.T) {
+	client, bus := makeWatchingCoreKeeperClient(t)
+	defer client.StopWatching()
+
+	updateChannel := make(chan types.WatchUpdate)
+	errorChannel := make(chan error)
+	client.WatchForChangeSets(updateChannel, errorChannel, &LoggingInfo{}, "Logging")
+
+	receive := func() types.WatchUpdate {
+		select {
+		case update := <-updateChannel:
+			return update
+		case err := <-errorChannel:
+			require.NoError(t, err)
+		case <-time.After(5 * time.Second):
+			require.Fail(t, "timed out waiting for update")
+		}
+		return types.WatchUpdate{}
+	}
+
+	// All the values are added in the first update
+	update := receive()
+	assert.Equal(t, &LoggingInfo{File: "/tmp/keeper.log"}, update.Configuration)
+	assert.Equal(t, types.ChangeSet{Added: []types.KeyChange{
+		{Key: "EnableRemote", NewValue: []byte("false")},
+		{Key: "File", NewValue: []byte("/tmp/keeper.log")},
+	}}, update.Changes)
+
+	changeValue(t, client, bus, "Logging/File", "/tmp/changed.log")
+	update = receive()
+	assert.Equal(t, &LoggingInfo{File: "/tmp/changed.log"}, update.Configuration)
+	assert.Equal(t, types.ChangeSet{Modified: []types.KeyChange{
+		{Key: "File", OldValue: []byte("/tmp/keeper.log"), NewValue: []byte("/tmp/changed.log")},
+	}}, update.Changes)
+
+	// The keys published on the message bus are modified, even if their value is unchanged
+	changeValue(t, client, bus, "Logging/File", "/tmp/changed.log")
+	update = receive()
+	assert.Equal(t, types.ChangeSet{Modified: []types.KeyChange{
+		{Key: "File", OldValue: []byte("/tmp/changed.log"), NewValue: []byte("/tmp/changed.log")},
+	}}, update.Changes)
+
+	require.NoError(t, client.DeleteConfigurationValue("Logging/EnableRemote"))
+	require.NoError(t, bus.PublishChange(client.fullPath("Logging/EnableRemote"), nil))
+	update = receive()
+	assert.Equal(t, types.ChangeSet{Removed: []types.KeyChange{
+		{Key: "EnableRemote", OldValue: []byte("false")},
+	}}, update.Changes)
+}
+
+func TestWatchForChangeSetsDebounce(t *testing.T) {
+	client, bus := makeWatchingCoreKeeperClient(t)
+	defer client.StopWatching()
+
+	updateChannel := make(chan types.WatchUpdate)
+	errorChannel := make(chan error)
+	client.WatchForChangeSets(updateChannel, errorChannel, &LoggingInfo{}, "Logging", types.WithDebounce(200*time.Millisecond))
+
+	receive := func() types.WatchUpdate {
+		select {
+		case update := <-updateChannel:
+			return update
+		case err := <-errorChannel:
+			require.NoError(t, err)
+		case <-time.After(5 * time.Second):
+			require.Fail(t, "timed out waiting for update")
+		}
+		return types.WatchUpdate{}
+	}
+	receive()
+
+	// The changes published within the window are sent as a single update
+	changeValue(t, client, bus, "Logging/File", "/tmp/changed.log")
+	changeValue(t, client, bus, "Logging/EnableRemote", "true")
+	changeValue(t, client, bus, "Logging/File", "/tmp/final.log")
+	update := receive()
+	assert.Equal(t, &LoggingInfo{EnableRemote: true, File: "/tmp/final.log"}, update.Configuration)
+	assert.Equal(t, types.ChangeSet{Modified: []types.KeyChange{
+		{Key: "EnableRemote", OldValue: []byte("false"), NewValue: []byte("true")},
+		{Key: "File", OldValue: []byte("/tmp/keeper.log"), NewValue: []byte("/tmp/final.log")},
+	}}, update.Changes)
+
+	select {
+	case update := <-updateChannel:
+		require.Fail(t, "unexpected update", "%v", update)
+	case <-time.After(300 * time.Millisecond):
+	}
+}
+
+func TestStopWatching(t *testing.T) {
+	client, bus := makeWatchingCoreKeeperClient(t)
+
+	var handles []types.WatchHandle
+	for _, key := range []string{"Writable", "Logging", "MessageQueue"} {
+		updateChannel := make(chan interface{})
+		handle := client.WatchForChanges(updateChannel, make(chan error), &TestConfig{}, key)
+		waitReady(t, handle)
+		handles = append(handles, handle)
+	}
+	assert.Equal(t, 1, bus.Connections())
+	// the update of the last watch isn't received, so it's stopped while blocked sending it
+	changeValue(t, client, bus, "MessageQueue/Host", "broker")
+
+	stopped := make(chan struct{})
+	go func() {
+		client.StopWatching()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "watches not stopped")
+	}
+
+	for _, handle := range handles {
+		select {
+		case <-handle.Done():
+		default:
+			require.Fail(t, "watch expected to be stopped")
+		}
+	}
+	assert.Equal(t, 0, bus.Connections())
+	assert.Empty(t, client.watchers)
+}
+
+func makePollingCoreKeeperClient(t *testing.T, optional map[string]any) *keeperClient {
+	client, err := NewKeeperClient(types.ServiceConfig{
+		Host:     testHost,
+		Port:     port,
+		BasePath: getUniqueServiceName(),
+		Optional: optional,
+	})
+	require.NoError(t, err)
+	return client
+}
+
+func TestWatchForChangesNoMessageBus(t *testing.T) {
+	client := makePollingCoreKeeperClient(t, map[string]any{WatchModeKey: "MessageBus"})
+	require.NoError(t, client.PutConfigurationValue("Writable/LogLevel", []byte("INFO")))
+
+	errorChannel := make(chan error, 1)
+	handle := client.WatchForChanges(make(chan interface{}), errorChannel, &models.WritableInfo{}, "Writable")
+
+	select {
+	case err := <-errorChannel:
+		assert.Contains(t, err.Error(), "MessageQueue")
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "timed out waiting for error")
+	}
+	select {
+	case <-handle.Done():
+	default:
+		require.Fail(t, "watch expected to be stopped")
+	}
+}
+
+func TestWatchForChangesPolling(t *testing.T) {
+	// The changes are polled since no message bus is configured
+	client := makePollingCoreKeeperClient(t, map[string]any{PollIntervalKey: "10ms"})
+	defer client.StopWatching()
+	require.NoError(t, client.PutConfigurationValue("Writable/LogLevel", []byte("INFO")))
+
+	updateChannel := make(chan interface{})
+	errorChannel := make(chan error)
+	handle := client.WatchForChanges(updateChannel, errorChannel, &models.WritableInfo{}, "Writable")
+
+	receive := func() interface{} {
+		select {
+		case update := <-updateChannel:
+			return update
+		case err := <-errorChannel:
+			require.NoError(t, err)
+		case <-time.After(5 * time.Second):
+			require.Fail(t, "timed out waiting for update")
+		}
+		return nil
+	}
+
+	// The current configuration is sent first, the watch being ready before it's received
+	waitReady(t, handle)
+	assert.Equal(t, &models.WritableInfo{LogLevel: "INFO"}, receive())
+
+	require.NoError(t, client.PutConfigurationValue("Writable/LogLevel", []byte("DEBUG")))
+	assert.Equal(t, &models.WritableInfo{LogLevel: "DEBUG"}, receive())
+
+	// Writing the same value again or changing a key outside of the watched one isn't reported
+	require.NoError(t, client.PutConfigurationValue("Writable/LogLevel", []byte("DEBUG")))
+	require.NoError(t, client.PutConfigurationValue("Logging/File", []byte("/tmp/keeper.log")))
+	select {
+	case update := <-updateChannel:
+		require.Fail(t, "unexpected update", "%v", update)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	require.NoError(t, client.DeleteConfigurationValue("Writable/LogLevel"))
+	assert.Equal(t, &models.WritableInfo{}, receive())
+}
+
+func TestWatchForChangesPollingBaselineFailure(t *testing.T) {
+	if mockCoreKeeper == nil {
+		t.Skip("test requires the mock Core Keeper")
+	}
+
+	client := makePollingCoreKeeperClient(t, map[string]any{WatchModeKey: "Poll", PollIntervalKey: "10ms"})
+	defer client.StopWatching()
+	require.NoError(t, client.PutConfigurationValue("Writable/LogLevel", []byte("INFO")))
+
+	// the first read of the configuration fails
+	defer mockCoreKeeper.InjectTransientFailures(0, 0)
+	mockCoreKeeper.InjectTransientFailures(1, http.StatusServiceUnavailable)
+	updateChannel := make(chan interface{})
+	errorChannel := make(chan error)
+	handle := client.WatchForChanges(updateChannel, errorChannel, &models.WritableInfo{}, "Writable")
+
+	select {
+	case err := <-errorChannel:
+		assert.True(t, errors.Is(err, types.ErrUnavailable), "unexpected error: %v", err)
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "timed out waiting for error")
+	}
+	waitReady(t, handle)
+
+	// the first configuration read afterwards is delivered, with the change made once the watch is ready
+	require.NoError(t, client.PutConfigurationValue("Writable/LogLevel", []byte("DEBUG")))
+	select {
+	case update := <-updateChannel:
+		assert.Equal(t, &models.WritableInfo{LogLevel: "DEBUG"}, update)
+	case err := <-errorChannel:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "timed out waiting for update")
+	}
+}
+
+func TestWatchForChangeSetsPollMode(t *testing.T) {
+	client, bus := makeWatchingCoreKeeperClient(t)
+	client.watchMode = WatchModePoll
+	client.pollInterval = 10 * time.Millisecond
+	defer client.StopWatching()
+
+	updateChannel := make(chan types.WatchUpdate)
+	errorChannel := make(chan error)
+	client.WatchForChangeSets(updateChannel, errorChannel, &LoggingInfo{}, "Logging")
+
+	receive := func() types.WatchUpdate {
+		select {
+		case update := <-updateChannel:
+			return update
+		case err := <-errorChannel:
+			require.NoError(t, err)
+		case <-time.After(5 * time.Second):
+			require.Fail(t, "timed out waiting for update")
+		}
+		return types.WatchUpdate{}
+	}
+
+	// The changes are polled even though a message bus is configured
+	update := receive()
+	assert.Equal(t, &LoggingInfo{File: "/tmp/keeper.log"}, update.Configuration)
+	assert.Equal(t, []string{"EnableRemote", "File"}, update.Changes.Keys())
+	assert.Equal(t, 0, bus.Connections())
+
+	require.NoError(t, client.PutConfigurationValue("Logging/File", []byte("/tmp/changed.log")))
+	update = receive()
+	assert.Equal(t, &LoggingInfo{File: "/tmp/changed.log"}, update.Configuration)
+	assert.Equal(t, types.ChangeSet{Modified: []types.KeyChange{
+		{Key: "File", OldValue: []byte("/tmp/keeper.log"), NewValue: []byte("/tmp/changed.log")},
+	}}, update.Changes)
+}
+
+func TestWatchModeConfig(t *testing.T) {
+	testCases := []struct {
+		Name          string
+		Optional      map[string]any
+		ExpectedMode  string
+		ExpectedError string
+	}{
+		{Name: "Default", ExpectedMode: ""},
+		{Name: "Message bus", Optional: map[string]any{WatchModeKey: "messagebus"}, ExpectedMode: WatchModeMessageBus},
+		{Name: "Poll", Optional: map[string]any{WatchModeKey: "Poll"}, ExpectedMode: WatchModePoll},
+		{Name: "Bad mode", Optional: map[string]any{WatchModeKey: "push"}, ExpectedError: "invalid WatchMode"},
+		{Name: "Bad mode type", Optional: map[string]any{WatchModeKey: 1}, ExpectedError: "invalid WatchMode"},
+		{Name: "Bad poll interval", Optional: map[string]any{PollIntervalKey: "soon"}, ExpectedError: "invalid PollInterval"},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.Name, func(t *testing.T) {
+			client, err := NewKeeperClient(types.ServiceConfig{Host: testHost, Port: port, Optional: test.Optional})
+			if test.ExpectedError != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), test.ExpectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.ExpectedMode, client.watchMode)
+		})
+	}
+}
+
+func TestWatchForChangesInjectedMessageBus(t *testing.T) {
+	bus := NewMockMessageBus()
+	original := newMessageClient
+	newMessageClient = bus.NewMessageClient
+	defer func() { newMessageClient = original }()
+
+	// The stored configuration doesn't define the message bus
+	client := makePollingCoreKeeperClient(t, map[string]any{
+		WatchModeKey: WatchModeMessageBus,
+		types.MessageBusKey: map[string]any{
+			"Type":      "mqtt",
+			"Host":      "broker",
+			"Port":      "8883",
+			"AuthMode":  "usernamepassword",
+			"Username":  "edgex",
+			"Password":  "secret",
+			"TopicRoot": "gems/site1/edgex/configs",
+			"Optional":  map[string]string{"Qos": "1"},
+		},
+	})
+	defer client.StopWatching()
+	require.NoError(t, client.PutConfigurationValue("Writable/LogLevel", []byte("INFO")))
+
+	updateChannel := make(chan interface{})
+	errorChannel := make(chan error)
+	waitReady(t, client.WatchForChanges(updateChannel, errorChannel, &models.WritableInfo{}, "Writable"))
+	assert.Equal(t, &models.WritableInfo{LogLevel: "INFO"}, receiveUpdate(t, updateChannel))
+
+	require.Len(t, bus.Configs(), 1)
+	config := bus.Configs()[0]
+	assert.Equal(t, "mqtt", config.Type)
+	assert.Equal(t, msgTypes.HostInfo{Host: "broker", Port: 8883}, config.SubscribeHost)
+	assert.Equal(t, map[string]string{"Qos": "1", "Username": "edgex", "Password": "secret"}, config.Optional)
+	assert.Equal(t, []string{"gems/site1/edgex/configs/" + client.configBasePath + "/#"}, bus.Topics())
+
+	require.NoError(t, client.PutConfigurationValue("Writable/LogLevel", []byte("DEBUG")))
+	require.NoError(t, bus.PublishChangeUnder("gems/site1/edgex/configs", client.fullPath("Writable/LogLevel"), "DEBUG"))
+	assert.Equal(t, &models.WritableInfo{LogLevel: "DEBUG"}, receiveUpdate(t, updateChannel))
+}
+
+func TestWatchForChangesStoredTopicRoot(t *testing.T) {
+	tests := []struct {
+		Name         string
+		Optional     map[string]any
+		ExpectedRoot string
+	}{
+		{"Default", nil, "edgex/configs"},
+		{"TopicRoot", map[string]any{TopicRootKey: "gems/site1/edgex/configs"}, "gems/site1/edgex/configs"},
+		{"TopicRoot with wildcard", map[string]any{TopicRootKey: "gems/site1/edgex/configs/#"}, "gems/site1/edgex/configs"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			client, bus := makeWatchingCoreKeeperClient(t)
+			defer client.StopWatching()
+			var err error
+			client.topicRoot, err = topicRootFromConfig(types.ServiceConfig{Optional: test.Optional}, client.messageBus)
+			require.NoError(t, err)
+			// the topics of the MessageQueue section are the service's own data topics, not the topic root
+			require.NoError(t, client.PutConfigurationValue("MessageQueue/PublishTopicPrefix", []byte("gems/site2/edgex")))
+			require.NoError(t, client.PutConfigurationValue("MessageQueue/SubscribeTopic", []byte("gems/site2/edgex/events/#")))
+
+			updateChannel := make(chan interface{})
+			errorChannel := make(chan error)
+			waitReady(t, client.WatchForChanges(updateChannel, errorChannel, &models.WritableInfo{}, "Writable"))
+			assert.Equal(t, &models.WritableInfo{LogLevel: "INFO"}, receiveUpdate(t, updateChannel))
+			assert.Equal(t, []string{test.ExpectedRoot + "/" + client.configBasePath + "/#"}, bus.Topics())
+
+			require.NoError(t, client.PutConfigurationValue("Writable/LogLevel", []byte("DEBUG")))
+			require.NoError(t, bus.PublishChangeUnder(test.ExpectedRoot, client.fullPath("Writable/LogLevel"), "DEBUG"))
+			assert.Equal(t, &models.WritableInfo{LogLevel: "DEBUG"}, receiveUpdate(t, updateChannel))
+		})
+	}
+}
+
+func TestTopicRootFromConfig(t *testing.T) {
+	tests := []struct {
+		Name          string
+		Config        types.ServiceConfig
+		Expected      string
+		ExpectedError string
+	}{
+		{Name: "Default", Expected: "edgex/configs"},
+		{Name: "Optional", Config: types.ServiceConfig{Optional: map[string]any{TopicRootKey: "gems/site1/edgex/configs/"}}, Expected: "gems/site1/edgex/configs"},
+		{Name: "Message bus over optional", Config: types.ServiceConfig{
+			MessageBus: types.MessageBusConfig{TopicRoot: "gems/site1/edgex/configs"},
+			Optional:   map[string]any{TopicRootKey: "gems/site2/edgex/configs"},
+		}, Expected: "gems/site1/edgex/configs"},
+		{Name: "Bad optional", Config: types.ServiceConfig{Optional: map[string]any{TopicRootKey: 1}}, ExpectedError: "invalid TopicRoot type"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			actual, err := topicRootFromConfig(test.Config, test.Config.MessageBus)
+			if test.ExpectedError != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), test.ExpectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.Expected, actual)
+		})
+	}
+}
+
+func TestWatchTopic(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Type     string
+		Root     string
+		Expected string
+	}{
+		{"MQTT", "mqtt", "edgex/configs", "edgex/configs/edgex/v2/core-data/#"},
+		{"MQTT custom root", "mqtt", "gems/site1/edgex/configs", "gems/site1/edgex/configs/edgex/v2/core-data/#"},
+		{"Redis", "redis", "gems/site1/edgex/configs", "gems/site1/edgex/configs/edgex/v2/core-data/#"},
+		{"ZeroMQ", "zero", "gems/site1/edgex/configs", "gems/site1/edgex/configs/edgex/v2/core-data/"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			topic := watchTopic(test.Type, test.Root, "edgex/v2/core-data")
+			assert.Equal(t, test.Expected, topic)
+			assert.True(t, topicMatches(topic, test.Root+"/edgex/v2/core-data/Writable/LogLevel"))
+			assert.False(t, topicMatches(topic, test.Root+"/edgex/v2/core-command/Writable/LogLevel"))
+		})
+	}
+}
+
+func TestMessageBusFromConfig(t *testing.T) {
+	expected := types.MessageBusConfig{Type: "redis", Host: "localhost", Port: 6379}
+
+	testCases := []struct {
+		Name          string
+		Config        types.ServiceConfig
+		Expected      types.MessageBusConfig
+		ExpectedError string
+	}{
+		{Name: "Not injected", Config: types.ServiceConfig{}},
+		{Name: "Field", Config: types.ServiceConfig{MessageBus: expected}, Expected: expected},
+		{Name: "Field over optional", Config: types.ServiceConfig{MessageBus: expected, Optional: map[string]any{types.MessageBusKey: types.MessageBusConfig{Type: "mqtt"}}}, Expected: expected},
+		{Name: "Optional struct", Config: types.ServiceConfig{Optional: map[string]any{types.MessageBusKey: expected}}, Expected: expected},
+		{Name: "Optional pointer", Config: types.ServiceConfig{Optional: map[string]any{types.MessageBusKey: &expected}}, Expected: expected},
+		{Name: "Optional map", Config: types.ServiceConfig{Optional: map[string]any{types.MessageBusKey: map[string]any{"Type": "redis", "Host": "localhost", "Port": 6379}}}, Expected: expected},
+		{Name: "Bad optional", Config: types.ServiceConfig{Optional: map[string]any{types.MessageBusKey: "redis://localhost:6379"}}, ExpectedError: "invalid MessageBus"},
+		{Name: "Incomplete", Config: types.ServiceConfig{MessageBus: types.MessageBusConfig{Type: "redis"}}, ExpectedError: "host, port or type"},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.Name, func(t *testing.T) {
+			actual, err := messageBusFromConfig(test.Config)
+			if test.ExpectedError != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), test.ExpectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.Expected, actual)
+		})
+	}
+}
+
+func TestMessagingConfig(t *testing.T) {
+	bus := types.MessageBusConfig{Type: "mqtt", Protocol: "ssl", Host: "localhost", Port: 8883}
+
+	testCases := []struct {
+		Name          string
+		AuthMode      string
+		Username      string
+		Password      string
+		TLS           types.TLSConfig
+		Optional      map[string]string
+		Expected      map[string]string
+		ExpectedError string
+	}{
+		{Name: "None", AuthMode: "none", Expected: map[string]string{}},
+		{Name: "Username password", AuthMode: "usernamepassword", Username: "edgex", Password: "secret", Expected: map[string]string{"Username": "edgex", "Password": "secret"}},
+		{Name: "Username password from optional", AuthMode: "usernamepassword", Optional: map[string]string{"Username": "edgex", "Password": "secret"}, Expected: map[string]string{"Username": "edgex", "Password": "secret"}},
+		{Name: "Missing password", AuthMode: "usernamepassword", Username: "edgex", ExpectedError: "requires Password"},
+		{Name: "Client cert", AuthMode: "clientcert", TLS: types.TLSConfig{CertPem: []byte("cert"), KeyFile: "key.pem"}, Expected: map[string]string{"CertPEMBlock": "cert", "KeyFile": "key.pem"}},
+		{Name: "Missing key", AuthMode: "clientcert", TLS: types.TLSConfig{CertFile: "cert.pem"}, ExpectedError: "requires KeyFile or KeyPEMBlock"},
+		{Name: "CA cert", AuthMode: "CACert", TLS: types.TLSConfig{CAFile: "ca.pem", InsecureSkipVerify: true}, Expected: map[string]string{"CaFile": "ca.pem", "SkipCertVerify": "true"}},
+		{Name: "Missing CA", AuthMode: "cacert", ExpectedError: "requires CaFile or CaPEMBlock"},
+		{Name: "Bad auth mode", AuthMode: "token", ExpectedError: "invalid message bus AuthMode"},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.Name, func(t *testing.T) {
+			config := bus
+			config.AuthMode = test.AuthMode
+			config.Username = test.Username
+			config.Password = test.Password
+			config.TLS = test.TLS
+			config.Optional = test.Optional
+
+			actual, err := messagingConfig(config)
+			if test.ExpectedError != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), test.ExpectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "mqtt", actual.Type)
+			assert.Equal(t, msgTypes.HostInfo{Host: "localhost", Port: 8883, Protocol: "ssl"}, actual.SubscribeHost)
+			assert.Equal(t, test.Expected, actual.Optional)
+		})
+	}
+}
+
+func TestMessageBusStoredSecret(t *testing.T) {
+	client := makeCoreKeeperClient(t, getUniqueServiceName())
+	require.NoError(t, client.PutConfiguration(&models.ConfigurationStruct{
+		MessageQueue: models.MessageBusInfo{
+			Type:       "mqtt",
+			Host:       "localhost",
+			Port:       1883,
+			AuthMode:   "usernamepassword",
+			SecretName: "mqtt-bus",
+			Optional:   map[string]string{"ClientId": "core-data"},
+		},
+	}, true))
+
+	_, _, err := client.messageBusConfig(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "the credentials of the secret mqtt-bus must be passed through ServiceConfig.MessageBus")
+}
@@ -6,11 +6,14 @@
 package keeper
 
 import (
-	"strconv"
+	"encoding/json"
+	"fmt"
+	"strings"
 
 	"github.com/edgexfoundry/go-mod-configuration/v2/internal/pkg/keeper/api"
+	"github.com/edgexfoundry/go-mod-configuration/v2/internal/pkg/keeper/dtos"
 
-	"github.com/spf13/cast"
+	"github.com/pelletier/go-toml"
 )
 
 type pair struct {
@@ -18,28 +21,51 @@ type pair struct {
 	Value string
 }
 
-func convertMapToKVPairs(path string, interfaceMap interface{}) []*pair {
-	pairs := make([]*pair, 0)
+// toConfigMap returns the configuration as a map, a struct is converted the same way Core Keeper converts it when it's
+// put as a whole
+func toConfigMap(config interface{}) (map[string]interface{}, error) {
+	switch value := config.(type) {
+	case map[string]interface{}:
+		return value, nil
+	case *toml.Tree:
+		return value.ToMap(), nil
+	}
 
-	pathPre := ""
-	if path != "" {
-		pathPre = path + api.KeyDelimiter
+	data, err := json.Marshal(config)
+	if err != nil {
+		return nil, err
 	}
+	var configMap map[string]interface{}
+	if err := json.Unmarshal(data, &configMap); err != nil {
+		return nil, fmt.Errorf("the configuration must be a struct or a map: %v", err)
+	}
+	return configMap, nil
+}
 
-	switch value := interfaceMap.(type) {
-	case []interface{}:
-		for index, item := range value {
-			nextPairs := convertMapToKVPairs(pathPre+strconv.Itoa(index), item)
-			pairs = append(pairs, nextPairs...)
+// nestKVPairs returns the key-value pairs as nested maps, which Core Keeper flattens back to the same keys
+func nestKVPairs(pairs []*pair) map[string]interface{} {
+	result := make(map[string]interface{})
+	for _, kv := range pairs {
+		m := result
+		children := strings.Split(kv.Key, api.KeyDelimiter)
+		for _, child := range children[:len(children)-1] {
+			subm, ok := m[child].(map[string]interface{})
+			if !ok {
+				subm = make(map[string]interface{})
+				m[child] = subm
+			}
+			m = subm
 		}
-	case map[string]interface{}:
-		for index, item := range value {
-			nextPairs := convertMapToKVPairs(pathPre+index, item)
-			pairs = append(pairs, nextPairs...)
-		}
-	default:
-		pairs = append(pairs, &pair{Key: path, Value: cast.ToString(value)})
+		m[children[len(children)-1]] = kv.Value
 	}
+	return result
+}
 
-	return pairs
+// kvValues returns the values of the key-value pairs from Core Keeper keyed by their full key
+func kvValues(pairs []dtos.KV) map[string]interface{} {
+	values := make(map[string]interface{}, len(pairs))
+	for _, kv := range pairs {
+		values[kv.Key] = kv.Value
+	}
+	return values
 }
@@ -8,4 +8,7 @@ package dtos
 // AddKeysRequest defines the Request Content for POST Key DTO.
 type AddKeysRequest struct {
 	Value interface{} `json:"value,omitempty"`
+	// Revisions are the revisions the stored values must still have for the request to be applied, keyed by the full
+	// keys. An empty revision requires the key not to exist. Nothing is updated if any of them doesn't match.
+	Revisions map[string]string `json:"revisions,omitempty"`
 }
@@ -13,33 +13,76 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"sync"
+	"sync/atomic"
 
+	"github.com/edgexfoundry/go-mod-configuration/v2/internal/pkg/codec"
 	"github.com/edgexfoundry/go-mod-configuration/v2/internal/pkg/keeper/api"
 	"github.com/edgexfoundry/go-mod-configuration/v2/internal/pkg/keeper/dtos"
 	httpUtils "github.com/edgexfoundry/go-mod-configuration/v2/internal/pkg/keeper/utils/http"
+	"github.com/edgexfoundry/go-mod-configuration/v2/pkg/types"
 )
 
 type MockCoreKeeper struct {
-	keyValueStore map[string]dtos.KV
+	// lock guards the keyValueStore, which the handlers of concurrent requests access
+	lock                sync.Mutex
+	keyValueStore       map[string]dtos.KV
+	expectedAccessToken string
+	transientFailures   int32
+	transientStatusCode int
+	// putsBeforeFailure is the number of PUT requests which succeed before the next one fails, none fails if negative
+	putsBeforeFailure int32
+	putStatusCode     int
+	requests          int32
 }
 
 func NewMockCoreKeeper() *MockCoreKeeper {
 	return &MockCoreKeeper{
-		keyValueStore: make(map[string]dtos.KV),
+		keyValueStore:     make(map[string]dtos.KV),
+		putsBeforeFailure: -1,
 	}
 }
 
 func (mock *MockCoreKeeper) Reset() {
+	mock.lock.Lock()
+	defer mock.lock.Unlock()
 	mock.keyValueStore = make(map[string]dtos.KV)
 }
 
 func (mock *MockCoreKeeper) Start() *httptest.Server {
-	testMockServer := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+	return httptest.NewServer(mock.newHandler())
+}
+
+// StartTLS starts the mock Core Keeper with https, the CA certificate is available from the returned server
+func (mock *MockCoreKeeper) StartTLS() *httptest.Server {
+	return httptest.NewTLSServer(mock.newHandler())
+}
+
+func (mock *MockCoreKeeper) newHandler() http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		atomic.AddInt32(&mock.requests, 1)
+		if mock.nextRequestFails() {
+			writer.WriteHeader(mock.transientStatusCode)
+			return
+		}
+
+		if len(mock.expectedAccessToken) > 0 {
+			token := request.Header.Get(httpUtils.Authorization)
+			if token != httpUtils.BearerLabel+mock.expectedAccessToken {
+				writer.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+		}
+
 		if strings.Contains(request.URL.Path, api.ApiKVRoute) {
 			key := strings.Replace(request.URL.Path, api.ApiKVRoute+"/", "", 1)
 
 			switch request.Method {
 			case "PUT":
+				if mock.nextPutFails() {
+					writer.WriteHeader(mock.putStatusCode)
+					return
+				}
 				body, err := ioutil.ReadAll(request.Body)
 				if err != nil {
 					log.Printf("error reading request body: %s", err.Error())
@@ -51,14 +94,20 @@ func (mock *MockCoreKeeper) Start() *httptest.Server {
 				}
 				query := request.URL.Query()
 				_, isFlatten := query[api.Flatten]
+				mock.lock.Lock()
+				if !mock.revisionsMatch(addKeysRequest.Revisions) {
+					mock.lock.Unlock()
+					writeConflict(writer, key)
+					return
+				}
 				if isFlatten {
-					kvPairs := convertMapToKVPairs(key, addKeysRequest.Value)
-					for _, kvPair := range kvPairs {
-						mock.updateKVStore(kvPair.Key, kvPair.Value)
+					for valueKey, value := range codec.ToValues(key, addKeysRequest.Value) {
+						mock.updateKVStore(valueKey, string(value))
 					}
 				} else {
 					mock.updateKVStore(key, addKeysRequest.Value)
 				}
+				mock.lock.Unlock()
 			case "GET":
 				query := request.URL.Query()
 				_, allKeysRequested := query[api.KeyOnly]
@@ -96,6 +145,33 @@ func (mock *MockCoreKeeper) Start() *httptest.Server {
 				}
 				writer.Header().Set("Content-Type", "application/json")
 
+				if err := json.NewEncoder(writer).Encode(resp); err != nil {
+					log.Printf("error writing data response: %s", err.Error())
+				}
+			case "DELETE":
+				query := request.URL.Query()
+				_, isPrefixMatch := query[api.PrefixMatch]
+
+				mock.lock.Lock()
+				if revision, isChecked := query[api.Revision]; isChecked && !mock.revisionsMatch(map[string]string{key: revision[0]}) {
+					mock.lock.Unlock()
+					writeConflict(writer, key)
+					return
+				}
+				var resp interface{}
+				keys := mock.deleteFromKVStore(key, isPrefixMatch)
+				mock.lock.Unlock()
+				if len(keys) == 0 {
+					resp = httpUtils.ErrorResponse{
+						Message:    fmt.Sprintf("query key %s not found", key),
+						StatusCode: http.StatusNotFound,
+					}
+					writer.WriteHeader(http.StatusNotFound)
+				} else {
+					resp = dtos.MultiKeyResponse{Keys: keys}
+					writer.WriteHeader(http.StatusOK)
+				}
+
 				if err := json.NewEncoder(writer).Encode(resp); err != nil {
 					log.Printf("error writing data response: %s", err.Error())
 				}
@@ -107,12 +183,13 @@ func (mock *MockCoreKeeper) Start() *httptest.Server {
 
 			}
 		}
-	}))
-
-	return testMockServer
+	})
 }
 
 func (mock *MockCoreKeeper) checkForPrefix(prefix string) ([]dtos.KV, bool) {
+	mock.lock.Lock()
+	defer mock.lock.Unlock()
+
 	var pairs []dtos.KV
 	for k, v := range mock.keyValueStore {
 		if strings.HasPrefix(k, prefix) {
@@ -126,7 +203,7 @@ func (mock *MockCoreKeeper) checkForPrefix(prefix string) ([]dtos.KV, bool) {
 
 }
 
-// updateKVStore updates the value of the specified key from the mock key-value store map
+// updateKVStore updates the value of the specified key from the mock key-value store map, the lock must be held
 func (mock *MockCoreKeeper) updateKVStore(key string, value interface{}) {
 	keyValuePair, found := mock.keyValueStore[key]
 	if found {
@@ -139,3 +216,94 @@ func (mock *MockCoreKeeper) updateKVStore(key string, value interface{}) {
 	}
 	mock.keyValueStore[key] = keyValuePair
 }
+
+// revisionsMatch checks that the stored values of the keys still have the revisions, which are derived from the
+// content of the values, and that the keys with an empty revision don't exist. The lock must be held.
+func (mock *MockCoreKeeper) revisionsMatch(revisions map[string]string) bool {
+	for key, revision := range revisions {
+		current := types.NoRevision
+		if keyValuePair, found := mock.keyValueStore[key]; found {
+			current = types.ValueRevision(codec.FormatValue(keyValuePair.Value))
+		}
+		if string(current) != revision {
+			return false
+		}
+	}
+	return true
+}
+
+// writeConflict responds that the request was rejected as a stored value has been changed since it was read
+func writeConflict(writer http.ResponseWriter, key string) {
+	writer.WriteHeader(http.StatusConflict)
+	resp := httpUtils.ErrorResponse{
+		Message:    fmt.Sprintf("the revision of %s is stale", key),
+		StatusCode: http.StatusConflict,
+	}
+	if err := json.NewEncoder(writer).Encode(resp); err != nil {
+		log.Printf("error writing data response: %s", err.Error())
+	}
+}
+
+// deleteFromKVStore deletes the specified key, or all keys starting with it if prefixMatch is set, from the mock
+// key-value store map and returns the deleted keys. As for GET, the prefix is matched as a plain string like Core
+// Keeper does, so a prefix without the trailing delimiter also matches the sibling keys. The lock must be held.
+func (mock *MockCoreKeeper) deleteFromKVStore(key string, prefixMatch bool) []dtos.KeyOnly {
+	var keys []dtos.KeyOnly
+	for k := range mock.keyValueStore {
+		if k == key || (prefixMatch && strings.HasPrefix(k, key)) {
+			delete(mock.keyValueStore, k)
+			keys = append(keys, dtos.KeyOnly(k))
+		}
+	}
+	return keys
+}
+
+func (mock *MockCoreKeeper) SetExpectedAccessToken(token string) {
+	mock.expectedAccessToken = token
+}
+
+func (mock *MockCoreKeeper) ClearExpectedAccessToken() {
+	mock.expectedAccessToken = ""
+}
+
+// InjectTransientFailures makes the mock respond to the next count requests with the statusCode, i.e. 503, before it
+// responds normally again
+func (mock *MockCoreKeeper) InjectTransientFailures(count int, statusCode int) {
+	mock.transientStatusCode = statusCode
+	atomic.StoreInt32(&mock.transientFailures, int32(count))
+}
+
+// InjectPutFailure makes the mock respond to the PUT request following the next count ones with the statusCode
+func (mock *MockCoreKeeper) InjectPutFailure(count int, statusCode int) {
+	mock.putStatusCode = statusCode
+	atomic.StoreInt32(&mock.putsBeforeFailure, int32(count))
+}
+
+func (mock *MockCoreKeeper) nextPutFails() bool {
+	for {
+		count := atomic.LoadInt32(&mock.putsBeforeFailure)
+		if count < 0 {
+			return false
+		}
+		if atomic.CompareAndSwapInt32(&mock.putsBeforeFailure, count, count-1) {
+			return count == 0
+		}
+	}
+}
+
+// Requests returns the number of requests the mock has received
+func (mock *MockCoreKeeper) Requests() int {
+	return int(atomic.LoadInt32(&mock.requests))
+}
+
+func (mock *MockCoreKeeper) nextRequestFails() bool {
+	for {
+		failures := atomic.LoadInt32(&mock.transientFailures)
+		if failures <= 0 {
+			return false
+		}
+		if atomic.CompareAndSwapInt32(&mock.transientFailures, failures, failures-1) {
+			return true
+		}
+	}
+}
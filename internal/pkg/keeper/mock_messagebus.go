@@ -0,0 +1,207 @@
+//
+// Copyright (C) 2022 IOTech Ltd
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package keeper
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+
+	"github.com/edgexfoundry/go-mod-configuration/v2/internal/pkg/keeper/dtos"
+	"github.com/edgexfoundry/go-mod-configuration/v2/internal/pkg/keeper/utils/http"
+	"github.com/edgexfoundry/go-mod-messaging/v2/messaging"
+	msgTypes "github.com/edgexfoundry/go-mod-messaging/v2/pkg/types"
+)
+
+// MockMessageBus is an in-process stand-in for the message bus Core Keeper publishes the configuration changes to.
+// It implements the messaging.MessageClient of every connection made through NewMessageClient.
+type MockMessageBus struct {
+	lock          sync.Mutex
+	connections   int
+	disconnects   int
+	subscriptions []msgTypes.TopicChannel
+	errors        []chan error
+	configs       []msgTypes.MessageBusConfig
+	connectErrors []error
+}
+
+func NewMockMessageBus() *MockMessageBus {
+	return &MockMessageBus{}
+}
+
+// NewMessageClient has the signature of messaging.NewMessageClient, so it can replace it
+func (mock *MockMessageBus) NewMessageClient(config msgTypes.MessageBusConfig) (messaging.MessageClient, error) {
+	mock.lock.Lock()
+	defer mock.lock.Unlock()
+	mock.configs = append(mock.configs, config)
+	return &mockMessageClient{bus: mock}, nil
+}
+
+// Configs returns the configurations of all the clients created through NewMessageClient
+func (mock *MockMessageBus) Configs() []msgTypes.MessageBusConfig {
+	mock.lock.Lock()
+	defer mock.lock.Unlock()
+	return append([]msgTypes.MessageBusConfig{}, mock.configs...)
+}
+
+// Topics returns the topics subscribed by all the connections
+func (mock *MockMessageBus) Topics() []string {
+	mock.lock.Lock()
+	defer mock.lock.Unlock()
+	topics := make([]string, 0, len(mock.subscriptions))
+	for _, subscription := range mock.subscriptions {
+		topics = append(topics, subscription.Topic)
+	}
+	return topics
+}
+
+// Connections returns the number of connections made and not disconnected yet
+func (mock *MockMessageBus) Connections() int {
+	mock.lock.Lock()
+	defer mock.lock.Unlock()
+	return mock.connections - mock.disconnects
+}
+
+// FailConnects makes the next connections fail with the errors, one connection per error
+func (mock *MockMessageBus) FailConnects(errs ...error) {
+	mock.lock.Lock()
+	defer mock.lock.Unlock()
+	mock.connectErrors = append(mock.connectErrors, errs...)
+}
+
+// PublishChange publishes the change of the key the same way Core Keeper does, it blocks until all the subscribers
+// have received it
+func (mock *MockMessageBus) PublishChange(key string, value interface{}) error {
+	return mock.PublishChangeUnder(keeperTopicPrefix, key, value)
+}
+
+// PublishChangeUnder publishes the change of the key under the topic root, it blocks until all the subscribers have
+// received it
+func (mock *MockMessageBus) PublishChangeUnder(root string, key string, value interface{}) error {
+	payload, err := json.Marshal(dtos.KV{Key: key, Value: value})
+	if err != nil {
+		return err
+	}
+	return mock.publish(msgTypes.MessageEnvelope{
+		Payload:     payload,
+		ContentType: http.ContentTypeJSON,
+	}, root+"/"+key)
+}
+
+// PublishError sends the error to all the subscribers
+func (mock *MockMessageBus) PublishError(err error) {
+	mock.lock.Lock()
+	errs := append([]chan error{}, mock.errors...)
+	mock.lock.Unlock()
+
+	for _, errorChannel := range errs {
+		errorChannel <- err
+	}
+}
+
+func (mock *MockMessageBus) publish(message msgTypes.MessageEnvelope, topic string) error {
+	mock.lock.Lock()
+	var receivers []chan msgTypes.MessageEnvelope
+	for _, subscription := range mock.subscriptions {
+		if topicMatches(subscription.Topic, topic) {
+			receivers = append(receivers, subscription.Messages)
+		}
+	}
+	mock.lock.Unlock()
+
+	message.ReceivedTopic = topic
+	for _, receiver := range receivers {
+		receiver <- message
+	}
+	return nil
+}
+
+// topicMatches checks if the topic matches the subscribed topic, which may end with the MQTT '#' multi level wildcard
+// or is a ZeroMQ prefix ending with '/'
+func topicMatches(subscribed string, topic string) bool {
+	switch {
+	case strings.HasSuffix(subscribed, "#"):
+		prefix := strings.TrimSuffix(subscribed, "#")
+		return strings.HasPrefix(topic, prefix) || topic == strings.TrimSuffix(prefix, "/")
+	case strings.HasSuffix(subscribed, "/"):
+		return strings.HasPrefix(topic, subscribed)
+	}
+	return subscribed == topic
+}
+
+type mockMessageClient struct {
+	bus           *MockMessageBus
+	connected     bool
+	subscriptions []msgTypes.TopicChannel
+	errors        chan error
+}
+
+func (client *mockMessageClient) Connect() error {
+	client.bus.lock.Lock()
+	defer client.bus.lock.Unlock()
+	if len(client.bus.connectErrors) > 0 {
+		err := client.bus.connectErrors[0]
+		client.bus.connectErrors = client.bus.connectErrors[1:]
+		return err
+	}
+	client.bus.connections++
+	client.connected = true
+	return nil
+}
+
+func (client *mockMessageClient) Publish(message msgTypes.MessageEnvelope, topic string) error {
+	return client.bus.publish(message, topic)
+}
+
+func (client *mockMessageClient) Subscribe(topics []msgTypes.TopicChannel, messageErrors chan error) error {
+	client.bus.lock.Lock()
+	defer client.bus.lock.Unlock()
+	if !client.connected {
+		return errors.New("not connected")
+	}
+	client.subscriptions = append(client.subscriptions, topics...)
+	client.bus.subscriptions = append(client.bus.subscriptions, topics...)
+	client.errors = messageErrors
+	client.bus.errors = append(client.bus.errors, messageErrors)
+	return nil
+}
+
+func (client *mockMessageClient) Disconnect() error {
+	client.bus.lock.Lock()
+	defer client.bus.lock.Unlock()
+	if !client.connected {
+		return nil
+	}
+	client.connected = false
+	client.bus.disconnects++
+
+	// remove the subscriptions of the connection
+	var subscriptions []msgTypes.TopicChannel
+	for _, subscription := range client.bus.subscriptions {
+		if !client.owns(subscription) {
+			subscriptions = append(subscriptions, subscription)
+		}
+	}
+	client.bus.subscriptions = subscriptions
+	var errs []chan error
+	for _, errorChannel := range client.bus.errors {
+		if errorChannel != client.errors {
+			errs = append(errs, errorChannel)
+		}
+	}
+	client.bus.errors = errs
+	return nil
+}
+
+func (client *mockMessageClient) owns(subscription msgTypes.TopicChannel) bool {
+	for _, own := range client.subscriptions {
+		if own.Messages == subscription.Messages {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,82 @@
+//
+// Copyright (C) 2022 IOTech Ltd
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package keeper
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/edgexfoundry/go-mod-configuration/v2/internal/pkg/watch"
+	"github.com/edgexfoundry/go-mod-configuration/v2/pkg/types"
+)
+
+// poll reads the watched configuration at the poll interval and delivers it each time the hash of its raw values
+// changes, until deliver returns false. The first read configuration is delivered with all its values added, even
+// if it follows a failed read. ready is called once the configuration has been read, or has failed, before it's
+// delivered.
+// Since the values are compared, writing a value again without changing it isn't reported.
+func (client *keeperClient) poll(ctx context.Context, w *watcher, targetType reflect.Type, ready func(), sendError func(err error) bool, deliver watch.DeliverFunc) {
+	ticker := time.NewTicker(client.pollInterval)
+	defer ticker.Stop()
+
+	var previous map[string][]byte
+	var previousHash []byte
+	var lastErr string
+	for {
+		configuration, current, err := client.readWatched(ctx, w, targetType)
+		// ready after the first read, so the changes made from then on are detected, before the update is delivered
+		ready()
+		if err != nil {
+			// the same error is only sent once rather than at each poll, until the configuration is read again
+			if err.Error() != lastErr {
+				lastErr = err.Error()
+				if !sendError(err) {
+					return
+				}
+			}
+		} else {
+			lastErr = ""
+			hash := hashValues(current)
+			if previousHash == nil || !bytes.Equal(hash, previousHash) {
+				changes := watch.NewChangeSet(previous, current, nil)
+				previous, previousHash = current, hash
+				if !deliver(ctx, types.WatchUpdate{Configuration: configuration, Changes: changes}) {
+					return
+				}
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-client.watchingDoneCtx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// hashValues hashes the raw values along with their keys, in the order of the keys
+func hashValues(values map[string][]byte) []byte {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	hash := sha256.New()
+	for _, key := range keys {
+		hash.Write([]byte(key))
+		hash.Write([]byte{0})
+		hash.Write(values[key])
+		hash.Write([]byte{0})
+	}
+	return hash.Sum(nil)
+}
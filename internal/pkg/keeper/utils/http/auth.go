@@ -0,0 +1,66 @@
+//
+// Copyright (C) 2022 IOTech Ltd
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/edgexfoundry/go-mod-configuration/v2/pkg/types"
+)
+
+// AuthenticationInjector adds the authentication data to the requests sent to Core Keeper
+type AuthenticationInjector interface {
+	// AddAuthenticationData adds the authentication data to the request
+	AddAuthenticationData(req *http.Request) error
+	// RenewAuthenticationData renews the authentication data after a request has been rejected with
+	// 401 Unauthorized or 403 Forbidden. It returns false if the data can't be renewed, in which case
+	// the rejected request is not sent again.
+	RenewAuthenticationData() (bool, error)
+}
+
+type bearerTokenInjector struct {
+	mutex          sync.RWMutex
+	accessToken    string
+	getAccessToken types.GetAccessTokenCallback
+}
+
+// NewBearerTokenInjector creates an AuthenticationInjector which sets the access token as Bearer token in the
+// Authorization header of each request. The getAccessToken callback, if set, is used to renew the access token.
+func NewBearerTokenInjector(accessToken string, getAccessToken types.GetAccessTokenCallback) AuthenticationInjector {
+	return &bearerTokenInjector{
+		accessToken:    accessToken,
+		getAccessToken: getAccessToken,
+	}
+}
+
+func (injector *bearerTokenInjector) AddAuthenticationData(req *http.Request) error {
+	injector.mutex.RLock()
+	defer injector.mutex.RUnlock()
+
+	if len(injector.accessToken) > 0 {
+		req.Header.Set(Authorization, BearerLabel+injector.accessToken)
+	}
+	return nil
+}
+
+func (injector *bearerTokenInjector) RenewAuthenticationData() (bool, error) {
+	if injector.getAccessToken == nil {
+		return false, nil
+	}
+
+	newToken, err := injector.getAccessToken()
+	if err != nil {
+		return false, fmt.Errorf("failed to renew access token: %s", err.Error())
+	}
+
+	injector.mutex.Lock()
+	injector.accessToken = newToken
+	injector.mutex.Unlock()
+
+	return true, nil
+}
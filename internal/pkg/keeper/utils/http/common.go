@@ -7,13 +7,16 @@ package http
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io/ioutil"
-	"net"
 	"net/http"
 	"net/url"
+
+	"github.com/edgexfoundry/go-mod-configuration/v2/internal/pkg/retry"
+	"github.com/edgexfoundry/go-mod-configuration/v2/pkg/types"
 )
 
 type ErrorResponse struct {
@@ -25,30 +28,27 @@ type ErrorResponse struct {
 func getBody(resp *http.Response) ([]byte, error) {
 	body, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		return body, errors.New("failed to get the body from the response")
+		return body, fmt.Errorf("failed to get the body from the response: %w", err)
 	}
 	return body, nil
 }
 
-// Helper method to make the request and return the response
-func makeRequest(req *http.Request) (*http.Response, error) {
-	client := &http.Client{}
+// Helper method to make the request with the client and return the response
+func makeRequest(req *http.Request, client *http.Client) (*http.Response, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
 	resp, err := client.Do(req)
 	if err != nil {
-		var netErr *net.OpError
-		if errors.As(err, &netErr) {
-			return nil, errors.New(fmt.Sprintf("%s cannot be reached, this service is not available.", req.URL.Host))
-		} else {
-			return nil, errors.New("failed to send a http request")
-		}
+		return nil, &TransportError{Method: req.Method, URL: req.URL.Redacted(), Err: err}
 	}
 	if resp == nil {
-		return nil, errors.New("the response should not be a nil")
+		return nil, &TransportError{Method: req.Method, URL: req.URL.Redacted(), Err: errors.New("the response should not be a nil")}
 	}
 	return resp, nil
 }
 
-func createRequest(httpMethod string, baseUrl string, requestPath string, requestParams url.Values) (*http.Request, error) {
+func createRequest(ctx context.Context, httpMethod string, baseUrl string, requestPath string, requestParams url.Values) (*http.Request, error) {
 	u, err := url.Parse(baseUrl)
 	if err != nil {
 		return nil, err
@@ -57,7 +57,7 @@ func createRequest(httpMethod string, baseUrl string, requestPath string, reques
 	if requestParams != nil {
 		u.RawQuery = requestParams.Encode()
 	}
-	req, err := http.NewRequest(httpMethod, u.String(), nil)
+	req, err := http.NewRequestWithContext(ctx, httpMethod, u.String(), nil)
 	if err != nil {
 		return nil, err
 	}
@@ -66,12 +66,61 @@ func createRequest(httpMethod string, baseUrl string, requestPath string, reques
 
 // sendRequest will make a request with raw data to the specified URL.
 // It returns the body as a byte array if successful and an error otherwise.
-func sendRequest(req *http.Request) ([]byte, ErrorResponse) {
-	var errResponse ErrorResponse
+// The request is sent again according to the retry policy while it fails with a retryable error.
+func sendRequest(req *http.Request, retryPolicy types.RetryPolicy, client *http.Client, authInjector AuthenticationInjector) ([]byte, error) {
+	var bodyBytes []byte
+	attempts := 0
+	err := retry.Do(req.Context(), retryPolicy, func() error {
+		var err error
+		attemptReq := req
+		if attempts > 0 {
+			// the request body has been consumed by the previous attempt, so a copy has to be sent
+			if attemptReq, err = cloneRequest(req); err != nil {
+				return err
+			}
+		}
+		attempts++
+		bodyBytes, err = sendRequestWithRenewal(attemptReq, client, authInjector)
+		return err
+	})
+	return bodyBytes, err
+}
+
+// sendRequestWithRenewal sends the request and, if it is rejected with 401 or 403, renews the authentication data
+// and sends the request once more.
+func sendRequestWithRenewal(req *http.Request, client *http.Client, authInjector AuthenticationInjector) ([]byte, error) {
+	bodyBytes, err := sendAuthenticatedRequest(req, client, authInjector)
+	var statusErr *StatusError
+	if authInjector == nil || !errors.As(err, &statusErr) || !isAuthError(statusErr.StatusCode) {
+		return bodyBytes, err
+	}
 
-	resp, err := makeRequest(req)
+	renewed, renewErr := authInjector.RenewAuthenticationData()
+	if renewErr != nil {
+		return nil, fmt.Errorf("%w, %v", err, renewErr)
+	}
+	if !renewed {
+		return nil, err
+	}
+
+	// Try again with the renewed authentication data
+	retryReq, err := cloneRequest(req)
 	if err != nil {
-		return nil, errResponse
+		return nil, err
+	}
+	return sendAuthenticatedRequest(retryReq, client, authInjector)
+}
+
+func sendAuthenticatedRequest(req *http.Request, client *http.Client, authInjector AuthenticationInjector) ([]byte, error) {
+	if authInjector != nil {
+		if err := authInjector.AddAuthenticationData(req); err != nil {
+			return nil, fmt.Errorf("failed to add the authentication data to the request, err: %v", err)
+		}
+	}
+
+	resp, err := makeRequest(req, client)
+	if err != nil {
+		return nil, err
 	}
 	defer func() {
 		_ = resp.Body.Close()
@@ -79,23 +128,39 @@ func sendRequest(req *http.Request) ([]byte, ErrorResponse) {
 
 	bodyBytes, err := getBody(resp)
 	if err != nil {
-		return nil, errResponse
+		return nil, &TransportError{Method: req.Method, URL: req.URL.Redacted(), Err: err}
 	}
 
 	if resp.StatusCode <= http.StatusMultiStatus {
-		return bodyBytes, errResponse
+		return bodyBytes, nil
 	}
 
-	// Handle error response
-	e := json.Unmarshal(bodyBytes, &errResponse)
-	if e != nil {
-		return nil, errResponse
-	}
+	// Handle error response, the message is only available if the response comes from Core Keeper itself and not
+	// i.e. from an API gateway in front of it
+	var errResponse ErrorResponse
+	_ = json.Unmarshal(bodyBytes, &errResponse)
+
+	return nil, &StatusError{StatusCode: resp.StatusCode, Message: errResponse.Message}
+}
 
-	return nil, errResponse
+func isAuthError(statusCode int) bool {
+	return statusCode == http.StatusUnauthorized || statusCode == http.StatusForbidden
+}
+
+// cloneRequest creates a copy of the request which can be sent again, including the request body
+func cloneRequest(req *http.Request) (*http.Request, error) {
+	clone := req.Clone(req.Context())
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, fmt.Errorf("failed to copy the request body, err: %v", err)
+		}
+		clone.Body = body
+	}
+	return clone, nil
 }
 
-func createRequestWithRawData(httpMethod string, baseUrl string, requestPath string, requestParams url.Values, data interface{}) (*http.Request, error) {
+func createRequestWithRawData(ctx context.Context, httpMethod string, baseUrl string, requestPath string, requestParams url.Values, data interface{}) (*http.Request, error) {
 	u, err := url.Parse(baseUrl)
 	if err != nil {
 		return nil, fmt.Errorf("fail to parse baseUrl, err: %v", err)
@@ -110,7 +175,7 @@ func createRequestWithRawData(httpMethod string, baseUrl string, requestPath str
 		return nil, fmt.Errorf("failed to encode input data to JSON, err: %v", err)
 	}
 
-	req, err := http.NewRequest(httpMethod, u.String(), bytes.NewReader(jsonEncodedData))
+	req, err := http.NewRequestWithContext(ctx, httpMethod, u.String(), bytes.NewReader(jsonEncodedData))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create a http request, err: %v", err)
 	}
@@ -8,4 +8,7 @@ package http
 const (
 	ContentType     = "Content-Type"
 	ContentTypeJSON = "application/json"
+
+	Authorization = "Authorization"
+	BearerLabel   = "Bearer "
 )
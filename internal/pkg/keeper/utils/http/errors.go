@@ -0,0 +1,86 @@
+//
+// Copyright (C) 2022 IOTech Ltd
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/edgexfoundry/go-mod-configuration/v2/pkg/types"
+)
+
+// TransportError is returned when the request can't be sent to Core Keeper or no response is received.
+// It matches types.ErrUnavailable unless the request has been canceled by the caller.
+type TransportError struct {
+	Method string
+	URL    string
+	Err    error
+}
+
+func (e *TransportError) Error() string {
+	return fmt.Sprintf("%s %s failed, Core Keeper cannot be reached: %v", e.Method, e.URL, e.Err)
+}
+
+func (e *TransportError) Unwrap() error {
+	return e.Err
+}
+
+func (e *TransportError) Is(target error) bool {
+	return target == types.ErrUnavailable && !errors.Is(e.Err, context.Canceled)
+}
+
+// StatusError is returned when Core Keeper responds with an error status code. The Message is parsed from the
+// Core Keeper error response, if available.
+// It matches types.ErrNotFound, types.ErrUnauthorized, types.ErrConflict or types.ErrUnavailable depending on the
+// status code. As for
+// Consul, an internal server error is transient and matches types.ErrUnavailable, so it's retried.
+type StatusError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *StatusError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("request failed with status code %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	return fmt.Sprintf("request failed with status code %d: %s", e.StatusCode, e.Message)
+}
+
+func (e *StatusError) Is(target error) bool {
+	switch target {
+	case types.ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case types.ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden
+	case types.ErrConflict:
+		return e.StatusCode == http.StatusConflict
+	case types.ErrUnavailable:
+		return e.StatusCode == http.StatusInternalServerError || e.StatusCode == http.StatusBadGateway ||
+			e.StatusCode == http.StatusServiceUnavailable || e.StatusCode == http.StatusGatewayTimeout
+	default:
+		return false
+	}
+}
+
+// DecodeError is returned when the response body received from Core Keeper can't be decoded.
+// It matches types.ErrDecode.
+type DecodeError struct {
+	Err error
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("failed to parse the response body: %v", e.Err)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+func (e *DecodeError) Is(target error) bool {
+	return target == types.ErrDecode
+}
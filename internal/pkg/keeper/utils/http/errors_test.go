@@ -0,0 +1,54 @@
+//
+// Copyright (C) 2022 IOTech Ltd
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/edgexfoundry/go-mod-configuration/v2/pkg/types"
+)
+
+func TestErrorsIs(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		target   error
+		expected bool
+	}{
+		{"not found", &StatusError{StatusCode: http.StatusNotFound}, types.ErrNotFound, true},
+		{"unauthorized", &StatusError{StatusCode: http.StatusUnauthorized}, types.ErrUnauthorized, true},
+		{"forbidden", &StatusError{StatusCode: http.StatusForbidden}, types.ErrUnauthorized, true},
+		{"service unavailable", &StatusError{StatusCode: http.StatusServiceUnavailable}, types.ErrUnavailable, true},
+		{"bad gateway", &StatusError{StatusCode: http.StatusBadGateway}, types.ErrUnavailable, true},
+		{"bad request is not not found", &StatusError{StatusCode: http.StatusBadRequest}, types.ErrNotFound, false},
+		{"internal error", &StatusError{StatusCode: http.StatusInternalServerError}, types.ErrUnavailable, true},
+		{"bad request is not unavailable", &StatusError{StatusCode: http.StatusBadRequest}, types.ErrUnavailable, false},
+		{"transport error", &TransportError{Err: errors.New("connection refused")}, types.ErrUnavailable, true},
+		{"canceled transport error", &TransportError{Err: context.Canceled}, types.ErrUnavailable, false},
+		{"canceled transport error is canceled", &TransportError{Err: context.Canceled}, context.Canceled, true},
+		{"decode error", &DecodeError{Err: errors.New("invalid character")}, types.ErrDecode, true},
+		{"decode error is not not found", &DecodeError{Err: errors.New("invalid character")}, types.ErrNotFound, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, errors.Is(test.err, test.target))
+		})
+	}
+}
+
+func TestStatusErrorMessage(t *testing.T) {
+	err := &StatusError{StatusCode: http.StatusNotFound, Message: "query key foo not found"}
+	assert.Contains(t, err.Error(), "query key foo not found")
+
+	err = &StatusError{StatusCode: http.StatusUnauthorized}
+	assert.Contains(t, err.Error(), http.StatusText(http.StatusUnauthorized))
+}
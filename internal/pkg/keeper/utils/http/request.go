@@ -6,96 +6,83 @@
 package http
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/url"
+
+	"github.com/edgexfoundry/go-mod-configuration/v2/pkg/types"
 )
 
-// GetRequest makes the get request and return the body
-func GetRequest(returnValuePointer interface{}, baseUrl string, requestPath string, requestParams url.Values) ErrorResponse {
-	req, err := createRequest(http.MethodGet, baseUrl, requestPath, requestParams)
+// GetRequest makes the get request with the client and return the body
+func GetRequest(ctx context.Context, returnValuePointer interface{}, baseUrl string, requestPath string, requestParams url.Values, retryPolicy types.RetryPolicy, client *http.Client, authInjector AuthenticationInjector) error {
+	req, err := createRequest(ctx, http.MethodGet, baseUrl, requestPath, requestParams)
 	if err != nil {
-		return ErrorResponse{
-			StatusCode: http.StatusInternalServerError,
-			Message:    err.Error(),
-		}
+		return err
 	}
 
-	res, errResp := sendRequest(req)
-	if errResp.StatusCode != 0 {
-		return errResp
+	res, err := sendRequest(req, retryPolicy, client, authInjector)
+	if err != nil {
+		return err
 	}
 	// Check the response content length to avoid json unmarshal error
 	if returnValuePointer == nil || len(res) == 0 {
-		return errResp
+		return nil
 	}
-	//var keyResp dtos.MultiKeyResponse
 	if err := json.Unmarshal(res, &returnValuePointer); err != nil {
-		return ErrorResponse{
-			StatusCode: http.StatusInternalServerError,
-			Message:    "failed to parse the response body",
-		}
+		return &DecodeError{Err: err}
 	}
-	return errResp
+	return nil
 }
 
-// PutRequest makes the put JSON request and return the body
+// PutRequest makes the put JSON request with the client and return the body
 func PutRequest(
+	ctx context.Context,
 	returnValuePointer interface{},
 	baseUrl string, requestPath string,
 	requestParams url.Values,
-	data interface{}) ErrorResponse {
+	data interface{},
+	retryPolicy types.RetryPolicy,
+	client *http.Client,
+	authInjector AuthenticationInjector) error {
 
-	req, err := createRequestWithRawData(http.MethodPut, baseUrl, requestPath, requestParams, data)
+	req, err := createRequestWithRawData(ctx, http.MethodPut, baseUrl, requestPath, requestParams, data)
 	if err != nil {
-		return ErrorResponse{
-			StatusCode: http.StatusInternalServerError,
-			Message:    err.Error(),
-		}
+		return err
 	}
 
-	res, errResp := sendRequest(req)
-
-	if errResp.StatusCode != 0 {
-		return errResp
+	res, err := sendRequest(req, retryPolicy, client, authInjector)
+	if err != nil {
+		return err
 	}
 	// no need to unmarshal the response if returnValuePointer is nil
 	if returnValuePointer == nil {
-		return ErrorResponse{}
+		return nil
 	}
 	if err := json.Unmarshal(res, returnValuePointer); err != nil {
-		return ErrorResponse{
-			StatusCode: http.StatusInternalServerError,
-			Message:    "failed to parse the response body",
-		}
+		return &DecodeError{Err: err}
 	}
-	return ErrorResponse{}
+	return nil
 }
 
-// DeleteRequest makes the get request and return the body
-func DeleteRequest(returnValuePointer interface{}, baseUrl string, requestPath string, requestParams url.Values) ErrorResponse {
-	req, err := createRequest(http.MethodDelete, baseUrl, requestPath, requestParams)
+// DeleteRequest makes the delete request with the client and return the body
+func DeleteRequest(ctx context.Context, returnValuePointer interface{}, baseUrl string, requestPath string, requestParams url.Values, retryPolicy types.RetryPolicy, client *http.Client, authInjector AuthenticationInjector) error {
+	req, err := createRequest(ctx, http.MethodDelete, baseUrl, requestPath, requestParams)
 	if err != nil {
-		return ErrorResponse{
-			StatusCode: http.StatusInternalServerError,
-			Message:    err.Error(),
-		}
+		return err
 	}
 
-	res, errResp := sendRequest(req)
-	if errResp.StatusCode != 0 {
-		return errResp
+	res, err := sendRequest(req, retryPolicy, client, authInjector)
+	if err != nil {
+		return err
 	}
 	// Check the response content length to avoid json unmarshal error
 	if returnValuePointer == nil || len(res) == 0 {
-		return errResp
+		return nil
 	}
 
 	if err := json.Unmarshal(res, &returnValuePointer); err != nil {
-		return ErrorResponse{
-			StatusCode: http.StatusInternalServerError,
-			Message:    "failed to parse the response body",
-		}
+		return &DecodeError{Err: err}
 	}
-	return errResp
+	return nil
 }
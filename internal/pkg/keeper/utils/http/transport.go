@@ -0,0 +1,84 @@
+//
+// Copyright (C) 2022 IOTech Ltd
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package http
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/edgexfoundry/go-mod-configuration/v2/pkg/types"
+)
+
+// NewClient creates the HTTP client shared by all requests of a Core Keeper client, independently of how they are
+// authenticated. It uses the default transport when no TLS settings are provided.
+func NewClient(tlsConfig types.TLSConfig) (*http.Client, error) {
+	if tlsConfig.IsEmpty() {
+		return &http.Client{}, nil
+	}
+
+	tlsClientConfig, err := newTLSClientConfig(tlsConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsClientConfig
+	return &http.Client{Transport: transport}, nil
+}
+
+func newTLSClientConfig(tlsConfig types.TLSConfig) (*tls.Config, error) {
+	tlsClientConfig := &tls.Config{
+		ServerName:         tlsConfig.ServerName,
+		InsecureSkipVerify: tlsConfig.InsecureSkipVerify, // nolint:gosec
+		MinVersion:         tls.VersionTLS12,
+	}
+
+	caPem := tlsConfig.CAPem
+	if tlsConfig.CAFile != "" {
+		fileContent, err := os.ReadFile(tlsConfig.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file %s, err: %v", tlsConfig.CAFile, err)
+		}
+		// copied so the CAPem of the caller is never written to
+		caPem = make([]byte, 0, len(tlsConfig.CAPem)+1+len(fileContent))
+		caPem = append(append(append(caPem, tlsConfig.CAPem...), '\n'), fileContent...)
+	}
+	if len(caPem) > 0 {
+		certPool := x509.NewCertPool()
+		if !certPool.AppendCertsFromPEM(caPem) {
+			return nil, errors.New("failed to parse the PEM encoded CA certificates")
+		}
+		tlsClientConfig.RootCAs = certPool
+	}
+
+	certPem, keyPem := tlsConfig.CertPem, tlsConfig.KeyPem
+	if tlsConfig.CertFile != "" || tlsConfig.KeyFile != "" {
+		if tlsConfig.CertFile == "" || tlsConfig.KeyFile == "" {
+			return nil, errors.New("both client certificate and client key files must be provided")
+		}
+
+		var err error
+		if certPem, err = os.ReadFile(tlsConfig.CertFile); err != nil {
+			return nil, fmt.Errorf("failed to read client certificate file %s, err: %v", tlsConfig.CertFile, err)
+		}
+		if keyPem, err = os.ReadFile(tlsConfig.KeyFile); err != nil {
+			return nil, fmt.Errorf("failed to read client key file %s, err: %v", tlsConfig.KeyFile, err)
+		}
+	}
+	if len(certPem) > 0 || len(keyPem) > 0 {
+		cert, err := tls.X509KeyPair(certPem, keyPem)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load the client certificate, err: %v", err)
+		}
+		tlsClientConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsClientConfig, nil
+}
@@ -0,0 +1,304 @@
+//
+// Copyright (C) 2022 IOTech Ltd
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package keeper
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/edgexfoundry/go-mod-configuration/v2/internal/pkg/keeper/dtos"
+	"github.com/edgexfoundry/go-mod-configuration/v2/internal/pkg/keeper/utils/http"
+	"github.com/edgexfoundry/go-mod-configuration/v2/pkg/types"
+	"github.com/edgexfoundry/go-mod-messaging/v2/messaging"
+	msgTypes "github.com/edgexfoundry/go-mod-messaging/v2/pkg/types"
+)
+
+// newMessageClient creates the message bus client used by the watches, replaced by the tests
+var newMessageClient = messaging.NewMessageClient
+
+// watcher is a single watch, multiplexed with the other watches of the client over the shared message bus connection.
+// The bus only records the changes and errors in the mailbox of the watcher and signals it, so a watch slow to
+// consume its updates never delays the other watches.
+type watcher struct {
+	keyPrefix string
+	// changed is signaled once keys have been added to touched
+	changed chan struct{}
+	// failed is signaled once err has been set
+	failed chan struct{}
+	// resync is signaled once the message bus is reconnected, the changes published meanwhile have been missed
+	resync chan struct{}
+
+	lock sync.Mutex
+	// touched are the keys changed since the watch last took them, relative to the watched key
+	touched map[string]bool
+	// err is the last error reported since the watch last took it, the previous ones are dropped
+	err error
+}
+
+func newWatcher(keyPrefix string) *watcher {
+	return &watcher{
+		keyPrefix: keyPrefix,
+		changed:   make(chan struct{}, 1),
+		failed:    make(chan struct{}, 1),
+		resync:    make(chan struct{}, 1),
+		touched:   make(map[string]bool),
+	}
+}
+
+// matches checks if the key is the watched key or a key under it
+func (w *watcher) matches(key string) bool {
+	return key == w.keyPrefix || strings.HasPrefix(key, w.keyPrefix+"/")
+}
+
+// relativeKey returns the key relative to the watched key
+func (w *watcher) relativeKey(key string) string {
+	return strings.TrimPrefix(strings.TrimPrefix(key, w.keyPrefix), "/")
+}
+
+// notifyChange records the change of the key and signals the watch, without waiting for it
+func (w *watcher) notifyChange(key string) {
+	w.lock.Lock()
+	w.touched[w.relativeKey(key)] = true
+	w.lock.Unlock()
+	signal(w.changed)
+}
+
+// notifyError records the error and signals the watch, without waiting for it
+func (w *watcher) notifyError(err error) {
+	w.lock.Lock()
+	w.err = err
+	w.lock.Unlock()
+	signal(w.failed)
+}
+
+// takeChanges returns the keys changed since the last call
+func (w *watcher) takeChanges() map[string]bool {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	touched := w.touched
+	w.touched = make(map[string]bool)
+	return touched
+}
+
+// takeError returns the error reported since the last call, nil if none
+func (w *watcher) takeError() error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	err := w.err
+	w.err = nil
+	return err
+}
+
+// signal signals the channel, unless a signal is already pending
+func signal(channel chan struct{}) {
+	select {
+	case channel <- struct{}{}:
+	default:
+	}
+}
+
+// messageBus is the message bus connection shared by all the watches of a client. It subscribes once to the changes
+// of the whole service configuration and dispatches them to the watches of the changed keys. The client and its
+// channels are replaced when reconnecting.
+type messageBus struct {
+	client   messaging.MessageClient
+	messages chan msgTypes.MessageEnvelope
+	errors   chan error
+	// ctx is done once the bus is closed
+	ctx     context.Context
+	cancel  context.CancelFunc
+	stopped chan struct{}
+}
+
+// close stops the dispatching of the messages and waits until it has disconnected from the message bus
+func (bus *messageBus) close() {
+	bus.cancel()
+	<-bus.stopped
+}
+
+// errNoMessageBus is returned when the service configuration doesn't define the message bus Core Keeper publishes the
+// changes to
+var errNoMessageBus = errors.New("host, port or type from MessageQueue section is not defined in the configuration")
+
+// startWatcher registers the watcher to receive the changes from the message bus, and reports whether it has to poll
+// them instead. The changes are polled when configured so, or when the watch mode isn't set and the service
+// configuration doesn't define a message bus.
+func (client *keeperClient) startWatcher(ctx context.Context, w *watcher) (bool, error) {
+	if client.watchMode == WatchModePoll {
+		return true, nil
+	}
+
+	err := client.addWatcher(ctx, w)
+	if err != nil && client.watchMode == "" && (errors.Is(err, errNoMessageBus) || errors.Is(err, types.ErrNotFound)) {
+		return true, nil
+	}
+	return false, err
+}
+
+// addWatcher registers the watcher, connecting to the message bus first if it's the client's first watch
+func (client *keeperClient) addWatcher(ctx context.Context, w *watcher) error {
+	client.watchLock.Lock()
+	defer client.watchLock.Unlock()
+
+	if client.watchBus == nil {
+		bus, err := client.connectMessageBus(ctx)
+		if err != nil {
+			return err
+		}
+		client.watchBus = bus
+		go client.dispatch(bus)
+	}
+
+	client.watchers[w] = struct{}{}
+	return nil
+}
+
+// removeWatcher unregisters the watcher and disconnects from the message bus once no watch is left
+func (client *keeperClient) removeWatcher(w *watcher) {
+	client.watchLock.Lock()
+	delete(client.watchers, w)
+	var bus *messageBus
+	if len(client.watchers) == 0 {
+		bus = client.watchBus
+		client.watchBus = nil
+	}
+	client.watchLock.Unlock()
+
+	// closed without holding the lock, since the dispatching takes it to find the watchers of a change
+	if bus != nil {
+		bus.close()
+	}
+}
+
+// connectMessageBus connects to the message bus and subscribes to the changes of the whole service configuration
+func (client *keeperClient) connectMessageBus(ctx context.Context) (*messageBus, error) {
+	bus := &messageBus{stopped: make(chan struct{})}
+	if err := client.subscribe(ctx, bus); err != nil {
+		return nil, err
+	}
+	bus.ctx, bus.cancel = context.WithCancel(context.Background())
+	return bus, nil
+}
+
+// subscribe makes a new connection of the bus to the message bus injected through the ServiceConfig, or defined in the
+// MessageQueue section of the service configuration, and subscribes to the changes of the whole service configuration
+func (client *keeperClient) subscribe(ctx context.Context, bus *messageBus) error {
+	msgBusConfig, root, err := client.messageBusConfig(ctx)
+	if err != nil {
+		return err
+	}
+
+	messageClient, err := newMessageClient(msgBusConfig)
+	if err != nil {
+		return err
+	}
+	// connect to the message bus
+	if err := messageClient.Connect(); err != nil {
+		return err
+	}
+
+	messages := make(chan msgTypes.MessageEnvelope)
+	errs := make(chan error)
+	topics := []msgTypes.TopicChannel{
+		{
+			Topic:    watchTopic(msgBusConfig.Type, root, client.configBasePath),
+			Messages: messages,
+		},
+	}
+	if err := messageClient.Subscribe(topics, errs); err != nil {
+		_ = messageClient.Disconnect()
+		return err
+	}
+
+	bus.client, bus.messages, bus.errors = messageClient, messages, errs
+	return nil
+}
+
+// reconnect replaces the connection to the message bus, retrying with backoff until it succeeds or the bus is closed.
+// The watches are then resynchronized, since the changes published while disconnected have been missed.
+func (client *keeperClient) reconnect(bus *messageBus) bool {
+	_ = bus.client.Disconnect()
+	bus.client = nil
+
+	for attempt := 1; bus.client == nil; attempt++ {
+		timer := time.NewTimer(client.reconnectPolicy.Delay(attempt))
+		select {
+		case <-bus.ctx.Done():
+			timer.Stop()
+			return false
+		case <-timer.C:
+		}
+		// the failures aren't reported, the watches already got the error which broke the connection
+		_ = client.subscribe(bus.ctx, bus)
+	}
+
+	for _, w := range client.matchingWatchers("") {
+		signal(w.resync)
+	}
+	return true
+}
+
+// dispatch passes the changes received from the message bus to the watches of the changed keys, and the errors to
+// all the watches, until the bus is closed. It never waits for the watches to consume them. The message bus implementations report the loss of the connection as
+// errors which can't be told apart from the other failures, so the bus reconnects after any error.
+func (client *keeperClient) dispatch(bus *messageBus) {
+	defer close(bus.stopped)
+	defer func() {
+		if bus.client != nil {
+			_ = bus.client.Disconnect()
+		}
+	}()
+
+	for {
+		select {
+		case <-bus.ctx.Done():
+			return
+		case msgEnvelope, ok := <-bus.messages:
+			if !ok {
+				// the subscription has ended without an error
+				if !client.reconnect(bus) {
+					return
+				}
+				continue
+			}
+			if msgEnvelope.ContentType != http.ContentTypeJSON {
+				continue
+			}
+			var respKV dtos.KV
+			if err := json.Unmarshal(msgEnvelope.Payload, &respKV); err != nil {
+				continue
+			}
+			for _, w := range client.matchingWatchers(respKV.Key) {
+				w.notifyChange(respKV.Key)
+			}
+		case err := <-bus.errors:
+			for _, w := range client.matchingWatchers("") {
+				w.notifyError(err)
+			}
+			if !client.reconnect(bus) {
+				return
+			}
+		}
+	}
+}
+
+// matchingWatchers returns the watchers of the key, or all the watchers if the key is empty
+func (client *keeperClient) matchingWatchers(key string) []*watcher {
+	client.watchLock.Lock()
+	defer client.watchLock.Unlock()
+
+	var result []*watcher
+	for w := range client.watchers {
+		if key == "" || w.matches(key) {
+			result = append(result, w)
+		}
+	}
+	return result
+}
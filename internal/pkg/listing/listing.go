@@ -0,0 +1,102 @@
+//
+// Copyright (C) 2022 IOTech Ltd
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package listing normalizes the keys listed by the configuration providers, so each of them lists the same keys
+// relative to the base path of the service.
+package listing
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/edgexfoundry/go-mod-configuration/v2/pkg/types"
+)
+
+const keyDelimiter = "/"
+
+// Lister lists the keys under a prefix of the service's configuration
+type Lister struct {
+	basePath string
+	prefix   string
+	options  types.ListOptions
+}
+
+// NewLister returns the Lister of the keys under the prefix, given with the levels delimited by the separator of the
+// options, of the configuration stored at the base path
+func NewLister(basePath string, prefix string, options types.ListOptions) Lister {
+	prefix = strings.ReplaceAll(strings.Trim(prefix, options.Separator), options.Separator, keyDelimiter)
+	return Lister{
+		basePath: strings.Trim(basePath, keyDelimiter),
+		prefix:   prefix,
+		options:  options,
+	}
+}
+
+// Prefix returns the '/' separated full path of the prefix, which the keys are read from
+func (lister Lister) Prefix() string {
+	switch {
+	case lister.basePath == "":
+		return lister.prefix
+	case lister.prefix == "":
+		return lister.basePath
+	default:
+		return lister.basePath + keyDelimiter + lister.prefix
+	}
+}
+
+// Key returns the key of the stored full key, relative to the base path and with the levels delimited by the
+// separator. A key deeper than the depth is returned as its section at the depth, ending with the separator, and
+// isValue is false. ok is false if the key isn't under the prefix, or is a folder as some Consul tools create.
+func (lister Lister) Key(fullKey string) (key string, isValue bool, ok bool) {
+	prefix := lister.Prefix()
+	if strings.HasSuffix(fullKey, keyDelimiter) {
+		return "", false, false
+	}
+	if prefix != "" && fullKey != prefix && !strings.HasPrefix(fullKey, prefix+keyDelimiter) {
+		return "", false, false
+	}
+
+	key = strings.TrimPrefix(strings.TrimPrefix(fullKey, lister.basePath), keyDelimiter)
+	levels := strings.Split(key, keyDelimiter)
+	isValue = true
+	if depth := lister.options.Depth; depth > 0 {
+		prefixLevels := 0
+		if lister.prefix != "" {
+			prefixLevels = strings.Count(lister.prefix, keyDelimiter) + 1
+		}
+		if len(levels) > prefixLevels+depth {
+			levels = append(levels[:prefixLevels+depth], "")
+			isValue = false
+		}
+	}
+
+	return strings.Join(levels, lister.options.Separator), isValue, true
+}
+
+// Keys returns the sorted keys of the stored full keys, each section deeper than the depth listed once
+func (lister Lister) Keys(fullKeys []string) []string {
+	unique := make(map[string]bool)
+	keys := make([]string, 0, len(fullKeys))
+	for _, fullKey := range fullKeys {
+		key, _, ok := lister.Key(fullKey)
+		if ok && !unique[key] {
+			unique[key] = true
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Values returns the stored values, keyed by their full key, up to the depth keyed by their key
+func (lister Lister) Values(values map[string][]byte) map[string][]byte {
+	result := make(map[string][]byte)
+	for fullKey, value := range values {
+		if key, isValue, ok := lister.Key(fullKey); ok && isValue {
+			result[key] = value
+		}
+	}
+	return result
+}
@@ -0,0 +1,90 @@
+//
+// Copyright (C) 2022 IOTech Ltd
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package listing
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/edgexfoundry/go-mod-configuration/v2/pkg/types"
+)
+
+var storedKeys = []string{
+	"edgex/core-data/Writable/LogLevel",
+	"edgex/core-data/Writable/InsecureSecrets/DB/Path",
+	"edgex/core-data/Writable/InsecureSecrets/DB/SecretName",
+	"edgex/core-data/WritableX/LogLevel",
+	"edgex/core-data/Service/Port",
+	"edgex/core-data/Folder/",
+	"edgex/core-metadata/Service/Port",
+}
+
+func TestKeys(t *testing.T) {
+	tests := []struct {
+		Name     string
+		BasePath string
+		Prefix   string
+		Options  []types.ListOption
+		Expected []string
+	}{
+		{"All", "edgex/core-data/", "", nil, []string{
+			"Service/Port",
+			"Writable/InsecureSecrets/DB/Path",
+			"Writable/InsecureSecrets/DB/SecretName",
+			"Writable/LogLevel",
+			"WritableX/LogLevel",
+		}},
+		{"Prefix", "edgex/core-data", "Writable", nil, []string{
+			"Writable/InsecureSecrets/DB/Path",
+			"Writable/InsecureSecrets/DB/SecretName",
+			"Writable/LogLevel",
+		}},
+		{"Value", "edgex/core-data", "Writable/LogLevel", nil, []string{"Writable/LogLevel"}},
+		{"Depth", "edgex/core-data", "Writable", []types.ListOption{types.WithDepth(1)}, []string{
+			"Writable/InsecureSecrets/",
+			"Writable/LogLevel",
+		}},
+		{"Top level", "edgex/core-data", "", []types.ListOption{types.WithDepth(1)}, []string{
+			"Service/",
+			"Writable/",
+			"WritableX/",
+		}},
+		{"Separator", "edgex/core-data", "Writable.InsecureSecrets", []types.ListOption{types.WithSeparator("."), types.WithDepth(1)}, []string{
+			"Writable.InsecureSecrets.DB.",
+		}},
+		{"No base path", "", "edgex", []types.ListOption{types.WithDepth(1)}, []string{
+			"edgex/core-data/",
+			"edgex/core-metadata/",
+		}},
+		{"Missing", "edgex/core-data", "Clients", nil, []string{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			lister := NewLister(test.BasePath, test.Prefix, types.NewListOptions(test.Options...))
+			assert.Equal(t, test.Expected, lister.Keys(storedKeys))
+		})
+	}
+}
+
+func TestValues(t *testing.T) {
+	values := make(map[string][]byte)
+	for _, key := range storedKeys {
+		values[key] = []byte(key)
+	}
+
+	lister := NewLister("edgex/core-data", "Writable", types.NewListOptions(types.WithDepth(1)))
+	assert.Equal(t, map[string][]byte{
+		"Writable/LogLevel": []byte("edgex/core-data/Writable/LogLevel"),
+	}, lister.Values(values))
+
+	lister = NewLister("edgex/core-data", "Writable.InsecureSecrets", types.NewListOptions(types.WithSeparator(".")))
+	assert.Equal(t, map[string][]byte{
+		"Writable.InsecureSecrets.DB.Path":       []byte("edgex/core-data/Writable/InsecureSecrets/DB/Path"),
+		"Writable.InsecureSecrets.DB.SecretName": []byte("edgex/core-data/Writable/InsecureSecrets/DB/SecretName"),
+	}, lister.Values(values))
+}
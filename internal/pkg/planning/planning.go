@@ -0,0 +1,57 @@
+//
+// Copyright (C) 2022 IOTech Ltd
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package planning makes the plans of the configuration changes and guards their execution against the changes made
+// since, so each provider plans and checks the same way.
+package planning
+
+import (
+	"fmt"
+
+	"github.com/edgexfoundry/go-mod-configuration/v2/internal/pkg/watch"
+	"github.com/edgexfoundry/go-mod-configuration/v2/pkg/types"
+)
+
+// RevisionFunc returns the revision of the stored value of the key
+type RevisionFunc func(key string, value []byte) types.Revision
+
+// New returns the plan changing the stored values into the desired ones, both keyed relative to the base path. The
+// stored values missing from the desired ones are removed.
+func New(stored map[string][]byte, desired map[string][]byte, revision RevisionFunc) types.Plan {
+	plan := types.Plan{
+		Changes:   watch.NewChangeSet(stored, desired, nil),
+		Revisions: make(map[string]types.Revision),
+	}
+	for _, changes := range [][]types.KeyChange{plan.Changes.Modified, plan.Changes.Removed} {
+		for _, change := range changes {
+			plan.Revisions[change.Key] = revision(change.Key, change.OldValue)
+		}
+	}
+	return plan
+}
+
+// Check returns an error matching types.ErrConflict if a value changed by the plan has been added, changed or
+// removed since the plan was made. current returns the current revision of a value, types.NoRevision if it doesn't
+// exist.
+func Check(plan types.Plan, current func(key string) types.Revision) error {
+	for _, change := range plan.Changes.Added {
+		if current(change.Key) != types.NoRevision {
+			return Drift(change.Key)
+		}
+	}
+	for _, changes := range [][]types.KeyChange{plan.Changes.Modified, plan.Changes.Removed} {
+		for _, change := range changes {
+			if current(change.Key) != plan.Revisions[change.Key] {
+				return Drift(change.Key)
+			}
+		}
+	}
+	return nil
+}
+
+// Drift returns the error reporting that the value of the key has been changed since the plan was made
+func Drift(key string) error {
+	return types.NewProviderError(types.ErrConflict, fmt.Errorf("the configuration has drifted since the plan was made, %s has been changed", key))
+}
@@ -0,0 +1,85 @@
+//
+// Copyright (C) 2022 IOTech Ltd
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package planning
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/edgexfoundry/go-mod-configuration/v2/pkg/types"
+)
+
+func TestNew(t *testing.T) {
+	stored := map[string][]byte{
+		"Host":              []byte("localhost"),
+		"Port":              []byte("59880"),
+		"Writable/LogLevel": []byte("INFO"),
+	}
+	desired := map[string][]byte{
+		"Host":              []byte("localhost"),
+		"Port":              []byte("59881"),
+		"Writable/Timeout":  []byte("5s"),
+		"Writable/Insecure": []byte("true"),
+	}
+
+	plan := New(stored, desired, func(key string, value []byte) types.Revision {
+		return types.Revision(key + "@" + string(value))
+	})
+
+	assert.Equal(t, []types.KeyChange{
+		{Key: "Writable/Insecure", NewValue: []byte("true")},
+		{Key: "Writable/Timeout", NewValue: []byte("5s")},
+	}, plan.Changes.Added)
+	assert.Equal(t, []types.KeyChange{{Key: "Port", OldValue: []byte("59880"), NewValue: []byte("59881")}}, plan.Changes.Modified)
+	assert.Equal(t, []types.KeyChange{{Key: "Writable/LogLevel", OldValue: []byte("INFO")}}, plan.Changes.Removed)
+	// only the values changed by the plan are checked
+	assert.Equal(t, map[string]types.Revision{
+		"Port":              "Port@59880",
+		"Writable/LogLevel": "Writable/LogLevel@INFO",
+	}, plan.Revisions)
+	assert.False(t, plan.IsEmpty())
+
+	assert.True(t, New(stored, stored, nil).IsEmpty())
+}
+
+func TestCheck(t *testing.T) {
+	plan := New(
+		map[string][]byte{"Port": []byte("59880"), "Host": []byte("localhost")},
+		map[string][]byte{"Port": []byte("59881"), "LogLevel": []byte("INFO")},
+		func(key string, _ []byte) types.Revision { return types.Revision(key + "1") },
+	)
+	planned := map[string]types.Revision{"Port": "Port1", "Host": "Host1"}
+
+	tests := []struct {
+		Name      string
+		Revisions map[string]types.Revision
+		Drifted   bool
+	}{
+		{"Unchanged", planned, false},
+		{"Unrelated value changed", map[string]types.Revision{"Port": "Port1", "Host": "Host1", "Other": "1"}, false},
+		{"Modified value changed", map[string]types.Revision{"Port": "Port2", "Host": "Host1"}, true},
+		{"Removed value changed", map[string]types.Revision{"Port": "Port1", "Host": "Host2"}, true},
+		{"Modified value removed", map[string]types.Revision{"Host": "Host1"}, true},
+		{"Added value created", map[string]types.Revision{"Port": "Port1", "Host": "Host1", "LogLevel": "1"}, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			err := Check(plan, func(key string) types.Revision {
+				return test.Revisions[key]
+			})
+			if !test.Drifted {
+				require.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.True(t, errors.Is(err, types.ErrConflict), "unexpected error: %v", err)
+		})
+	}
+}
@@ -0,0 +1,98 @@
+//
+// Copyright (C) 2022 IOTech Ltd
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/edgexfoundry/go-mod-configuration/v2/pkg/types"
+)
+
+func TestDo(t *testing.T) {
+	unavailable := types.NewProviderError(types.ErrUnavailable, errors.New("connection refused"))
+	unauthorized := types.NewProviderError(types.ErrUnauthorized, errors.New("forbidden"))
+
+	testCases := []struct {
+		Name             string
+		Policy           types.RetryPolicy
+		Errors           []error
+		ExpectedAttempts int
+		ExpectedError    error
+	}{
+		{
+			Name:             "No policy",
+			Policy:           types.RetryPolicy{},
+			Errors:           []error{unavailable, nil},
+			ExpectedAttempts: 1,
+			ExpectedError:    unavailable,
+		},
+		{
+			Name:             "Succeeds after transient errors",
+			Policy:           types.RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond},
+			Errors:           []error{unavailable, unavailable, nil},
+			ExpectedAttempts: 3,
+		},
+		{
+			Name:             "Attempts exhausted",
+			Policy:           types.RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond},
+			Errors:           []error{unavailable, unavailable, nil},
+			ExpectedAttempts: 2,
+			ExpectedError:    unavailable,
+		},
+		{
+			Name:             "Not retryable",
+			Policy:           types.RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond},
+			Errors:           []error{unauthorized, nil},
+			ExpectedAttempts: 1,
+			ExpectedError:    unauthorized,
+		},
+		{
+			Name: "Retryable error class",
+			Policy: types.RetryPolicy{
+				MaxAttempts:    3,
+				InitialBackoff: time.Millisecond,
+				RetryOn:        []error{types.ErrUnauthorized},
+			},
+			Errors:           []error{unauthorized, nil},
+			ExpectedAttempts: 2,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.Name, func(t *testing.T) {
+			attempts := 0
+			err := Do(context.Background(), test.Policy, func() error {
+				err := test.Errors[attempts]
+				attempts++
+				return err
+			})
+
+			assert.Equal(t, test.ExpectedAttempts, attempts)
+			assert.Equal(t, test.ExpectedError, err)
+		})
+	}
+}
+
+func TestDoContextDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	unavailable := types.NewProviderError(types.ErrUnavailable, errors.New("connection refused"))
+	policy := types.RetryPolicy{MaxAttempts: 5, InitialBackoff: time.Hour}
+
+	attempts := 0
+	go cancel()
+	err := Do(ctx, policy, func() error {
+		attempts++
+		return unavailable
+	})
+
+	assert.Equal(t, 1, attempts)
+	assert.Equal(t, unavailable, err)
+}
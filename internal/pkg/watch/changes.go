@@ -0,0 +1,45 @@
+//
+// Copyright (C) 2022 IOTech Ltd
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package watch
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/edgexfoundry/go-mod-configuration/v2/pkg/types"
+)
+
+// NewChangeSet compares the raw values of the watched keys before and after a change. The touched keys are reported
+// as modified when they exist before and after the change, even when their value is the same, i.e. because it has
+// been written again.
+func NewChangeSet(previous map[string][]byte, current map[string][]byte, touched map[string]bool) types.ChangeSet {
+	var changes types.ChangeSet
+	for key, value := range current {
+		previousValue, exists := previous[key]
+		switch {
+		case !exists:
+			changes.Added = append(changes.Added, types.KeyChange{Key: key, NewValue: value})
+		case touched[key] || !bytes.Equal(previousValue, value):
+			changes.Modified = append(changes.Modified, types.KeyChange{Key: key, OldValue: previousValue, NewValue: value})
+		}
+	}
+	for key, value := range previous {
+		if _, exists := current[key]; !exists {
+			changes.Removed = append(changes.Removed, types.KeyChange{Key: key, OldValue: value})
+		}
+	}
+
+	sortChanges(changes.Added)
+	sortChanges(changes.Modified)
+	sortChanges(changes.Removed)
+	return changes
+}
+
+func sortChanges(changes []types.KeyChange) {
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Key < changes[j].Key
+	})
+}
@@ -0,0 +1,50 @@
+//
+// Copyright (C) 2022 IOTech Ltd
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package watch
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/edgexfoundry/go-mod-configuration/v2/pkg/types"
+)
+
+func TestNewChangeSet(t *testing.T) {
+	previous := map[string][]byte{
+		"Writable/LogLevel": []byte("INFO"),
+		"Host":              []byte("localhost"),
+		"Port":              []byte("59880"),
+		"Timeout":           []byte("5s"),
+	}
+	current := map[string][]byte{
+		"Writable/LogLevel": []byte("DEBUG"),
+		"Host":              []byte("localhost"),
+		"Timeout":           []byte("5s"),
+		"Writable/Interval": []byte("10"),
+		"Writable/Enabled":  []byte("true"),
+	}
+
+	changes := NewChangeSet(previous, current, map[string]bool{"Timeout": true, "Port": true})
+	assert.Equal(t, types.ChangeSet{
+		Added: []types.KeyChange{
+			{Key: "Writable/Enabled", NewValue: []byte("true")},
+			{Key: "Writable/Interval", NewValue: []byte("10")},
+		},
+		Modified: []types.KeyChange{
+			{Key: "Timeout", OldValue: []byte("5s"), NewValue: []byte("5s")},
+			{Key: "Writable/LogLevel", OldValue: []byte("INFO"), NewValue: []byte("DEBUG")},
+		},
+		Removed: []types.KeyChange{
+			{Key: "Port", OldValue: []byte("59880")},
+		},
+	}, changes)
+	assert.False(t, changes.IsEmpty())
+	assert.Equal(t, []string{"Port", "Timeout", "Writable/Enabled", "Writable/Interval", "Writable/LogLevel"}, changes.Keys())
+
+	assert.True(t, NewChangeSet(current, current, nil).IsEmpty())
+	assert.Equal(t, 5, len(NewChangeSet(nil, current, nil).Added))
+}
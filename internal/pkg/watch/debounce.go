@@ -0,0 +1,72 @@
+//
+// Copyright (C) 2022 IOTech Ltd
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package watch
+
+import (
+	"context"
+	"time"
+
+	"github.com/edgexfoundry/go-mod-configuration/v2/pkg/types"
+)
+
+// DeliverFunc passes an update of a watch to its consumer, it returns false once the watch has to stop
+type DeliverFunc func(ctx context.Context, update types.WatchUpdate) bool
+
+// Debounce returns a DeliverFunc which coalesces the updates delivered within the window following an update into a
+// single update, with the latest configuration and the merged changes, and passes it to deliver at the end of the
+// window. The returned stop function must be called once the watch stops, the update pending then is dropped.
+// The updates are passed to deliver as they come if the window isn't set.
+func Debounce(ctx context.Context, window time.Duration, deliver DeliverFunc) (DeliverFunc, func()) {
+	if window <= 0 {
+		return deliver, func() {}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	updates := make(chan types.WatchUpdate)
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+
+		var pending *types.WatchUpdate
+		var timer <-chan time.Time
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case update := <-updates:
+				if pending == nil {
+					pending = &update
+					timer = time.After(window)
+					continue
+				}
+				pending.Configuration = update.Configuration
+				pending.Changes = pending.Changes.Merge(update.Changes)
+			case <-timer:
+				update := *pending
+				pending, timer = nil, nil
+				if !deliver(ctx, update) {
+					return
+				}
+			}
+		}
+	}()
+
+	debounced := func(callCtx context.Context, update types.WatchUpdate) bool {
+		select {
+		case updates <- update:
+			return true
+		case <-callCtx.Done():
+		case <-stopped:
+		}
+		return false
+	}
+	stop := func() {
+		cancel()
+		<-stopped
+	}
+	return debounced, stop
+}
@@ -0,0 +1,74 @@
+//
+// Copyright (C) 2022 IOTech Ltd
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package watch
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/edgexfoundry/go-mod-configuration/v2/pkg/types"
+)
+
+func TestDebounce(t *testing.T) {
+	delivered := make(chan types.WatchUpdate)
+	deliver := func(ctx context.Context, update types.WatchUpdate) bool {
+		select {
+		case delivered <- update:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	debounced, stop := Debounce(context.Background(), 50*time.Millisecond, deliver)
+	defer stop()
+
+	require.True(t, debounced(context.Background(), types.WatchUpdate{
+		Configuration: 1,
+		Changes:       types.ChangeSet{Added: []types.KeyChange{{Key: "A", NewValue: []byte("1")}}},
+	}))
+	require.True(t, debounced(context.Background(), types.WatchUpdate{
+		Configuration: 2,
+		Changes:       types.ChangeSet{Added: []types.KeyChange{{Key: "B", NewValue: []byte("2")}}},
+	}))
+
+	select {
+	case update := <-delivered:
+		assert.Equal(t, 2, update.Configuration)
+		assert.Equal(t, []string{"A", "B"}, update.Changes.Keys())
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "timed out waiting for update")
+	}
+
+	// The pending update is dropped once stopped
+	require.True(t, debounced(context.Background(), types.WatchUpdate{Configuration: 3}))
+	stop()
+	assert.False(t, debounced(context.Background(), types.WatchUpdate{Configuration: 4}))
+	select {
+	case update := <-delivered:
+		require.Fail(t, "unexpected update", "%v", update)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestDebounceNoWindow(t *testing.T) {
+	calls := 0
+	deliver := func(ctx context.Context, update types.WatchUpdate) bool {
+		calls++
+		return true
+	}
+
+	debounced, stop := Debounce(context.Background(), 0, deliver)
+	defer stop()
+
+	assert.True(t, debounced(context.Background(), types.WatchUpdate{}))
+	assert.True(t, debounced(context.Background(), types.WatchUpdate{}))
+	assert.Equal(t, 2, calls)
+}
@@ -0,0 +1,74 @@
+//
+// Copyright (C) 2022 IOTech Ltd
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package watch
+
+import (
+	"context"
+	"sync"
+)
+
+// Handle is the types.WatchHandle shared by the configuration providers
+type Handle struct {
+	cancel    context.CancelFunc
+	done      chan struct{}
+	once      sync.Once
+	ready     chan struct{}
+	readyOnce sync.Once
+}
+
+// NewHandle creates the handle of a new watch along with the context the watch runs with. The context is done once
+// either the parent context is done or the watch is stopped through the handle.
+func NewHandle(parent context.Context) (context.Context, *Handle) {
+	ctx, cancel := context.WithCancel(parent)
+	return ctx, &Handle{
+		cancel: cancel,
+		done:   make(chan struct{}),
+		ready:  make(chan struct{}),
+	}
+}
+
+// Stopped returns the handle of a watch which failed to start
+func Stopped() *Handle {
+	_, handle := NewHandle(context.Background())
+	handle.SetStopped()
+	return handle
+}
+
+// SetStopped is called by the watch once it has stopped, it releases the callers waiting in Stop
+func (handle *Handle) SetStopped() {
+	handle.once.Do(func() {
+		handle.cancel()
+		close(handle.done)
+	})
+}
+
+// SetReady is called by the watch once the changes made from then on are detected
+func (handle *Handle) SetReady() {
+	handle.readyOnce.Do(func() {
+		close(handle.ready)
+	})
+}
+
+// Cancel stops the watch without waiting until it has stopped
+func (handle *Handle) Cancel() {
+	handle.cancel()
+}
+
+// Stop stops the watch and waits until it has stopped
+func (handle *Handle) Stop() {
+	handle.cancel()
+	<-handle.done
+}
+
+// Done returns a channel which is closed once the watch has stopped
+func (handle *Handle) Done() <-chan struct{} {
+	return handle.done
+}
+
+// Ready returns a channel which is closed once the watch is established
+func (handle *Handle) Ready() <-chan struct{} {
+	return handle.ready
+}
@@ -0,0 +1,83 @@
+//
+// Copyright (C) 2022 IOTech Ltd
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package watch
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/edgexfoundry/go-mod-configuration/v2/pkg/types"
+)
+
+var _ types.WatchHandle = &Handle{}
+
+func TestHandleStop(t *testing.T) {
+	ctx, handle := NewHandle(context.Background())
+
+	go func() {
+		<-ctx.Done()
+		handle.SetStopped()
+	}()
+
+	handle.Stop()
+	select {
+	case <-handle.Done():
+	default:
+		require.Fail(t, "watch expected to be stopped")
+	}
+
+	// Stopping again doesn't block
+	handle.Stop()
+}
+
+func TestHandleParentDone(t *testing.T) {
+	parent, cancel := context.WithCancel(context.Background())
+	ctx, handle := NewHandle(parent)
+	go func() {
+		<-ctx.Done()
+		handle.SetStopped()
+	}()
+
+	cancel()
+	select {
+	case <-handle.Done():
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "watch not stopped by its parent context")
+	}
+}
+
+func TestStopped(t *testing.T) {
+	handle := Stopped()
+	handle.Stop()
+	_, open := <-handle.Done()
+	assert.False(t, open)
+
+	// A watch which failed to start is never ready
+	select {
+	case <-handle.Ready():
+		require.Fail(t, "watch not expected to be ready")
+	default:
+	}
+}
+
+func TestHandleReady(t *testing.T) {
+	_, handle := NewHandle(context.Background())
+	select {
+	case <-handle.Ready():
+		require.Fail(t, "watch not expected to be ready yet")
+	default:
+	}
+
+	// Setting it ready again is fine
+	handle.SetReady()
+	handle.SetReady()
+	_, open := <-handle.Ready()
+	assert.False(t, open)
+}
@@ -0,0 +1,43 @@
+//
+// Copyright (C) 2022 IOTech Ltd
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package watch
+
+import (
+	"fmt"
+	"time"
+)
+
+// PollIntervalKey is the ServiceConfig.Optional key of the interval at which the polling watches check for changes.
+// The value is either a time.Duration or a duration string, i.e. "500ms".
+const PollIntervalKey = "PollInterval"
+
+// PollInterval returns the poll interval set in the optional properties of the service config, or the default one if
+// it isn't set
+func PollInterval(optional map[string]any, defaultInterval time.Duration) (time.Duration, error) {
+	value, ok := optional[PollIntervalKey]
+	if !ok {
+		return defaultInterval, nil
+	}
+
+	var interval time.Duration
+	switch v := value.(type) {
+	case time.Duration:
+		interval = v
+	case string:
+		var err error
+		if interval, err = time.ParseDuration(v); err != nil {
+			return 0, fmt.Errorf("invalid %s '%s': %v", PollIntervalKey, v, err)
+		}
+	default:
+		return 0, fmt.Errorf("invalid %s type %T, expected a duration", PollIntervalKey, value)
+	}
+
+	if interval <= 0 {
+		return 0, fmt.Errorf("invalid %s '%v', must be greater than zero", PollIntervalKey, interval)
+	}
+
+	return interval, nil
+}
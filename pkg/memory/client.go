@@ -0,0 +1,853 @@
+//
+// Copyright (C) 2022 IOTech Ltd
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package memory provides an in-memory Configuration client, which lets the services depending on
+// configuration.Client be unit tested without a Configuration service.
+package memory
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pelletier/go-toml"
+
+	"github.com/edgexfoundry/go-mod-configuration/v2/internal/pkg/codec"
+	"github.com/edgexfoundry/go-mod-configuration/v2/internal/pkg/decode"
+	"github.com/edgexfoundry/go-mod-configuration/v2/internal/pkg/listing"
+	"github.com/edgexfoundry/go-mod-configuration/v2/internal/pkg/planning"
+	"github.com/edgexfoundry/go-mod-configuration/v2/internal/pkg/watch"
+	"github.com/edgexfoundry/go-mod-configuration/v2/pkg/types"
+)
+
+const keyDelimiter = "/"
+
+// Operation identifies a Client operation for the fault injection
+type Operation string
+
+const (
+	OperationHasConfiguration                 Operation = "HasConfiguration"
+	OperationHasSubConfiguration              Operation = "HasSubConfiguration"
+	OperationPutConfiguration                 Operation = "PutConfiguration"
+	OperationGetConfiguration                 Operation = "GetConfiguration"
+	OperationWatchForChanges                  Operation = "WatchForChanges"
+	OperationConfigurationValueExists         Operation = "ConfigurationValueExists"
+	OperationGetConfigurationValue            Operation = "GetConfigurationValue"
+	OperationPutConfigurationValue            Operation = "PutConfigurationValue"
+	OperationListConfigurationKeys            Operation = "ListConfigurationKeys"
+	OperationGetConfigurationValues           Operation = "GetConfigurationValues"
+	OperationGetConfigurationValueWithVersion Operation = "GetConfigurationValueWithVersion"
+	OperationPutConfigurationValueCAS         Operation = "PutConfigurationValueCAS"
+	OperationDeleteConfigurationValue         Operation = "DeleteConfigurationValue"
+	OperationDeleteSubConfiguration           Operation = "DeleteSubConfiguration"
+	OperationPlanConfiguration                Operation = "PlanConfiguration"
+	OperationApplyPlan                        Operation = "ApplyPlan"
+)
+
+// Client is a goroutine-safe in-memory implementation of configuration.Client and configuration.ContextClient.
+// The values are stored as flat '/' separated keys, the same way as in Consul and Core Keeper, and the watches are
+// notified of every change under their key. Errors, latency and the unavailability of the Configuration service can
+// be injected to test how the callers handle them.
+type Client struct {
+	configBasePath string
+
+	lock   sync.RWMutex
+	values map[string][]byte
+	// revisions are the indexes of the last writes of the values, index is the index of the last write
+	revisions map[string]uint64
+	index     uint64
+	watches   map[*watcher]struct{}
+	// valueTypes are the types of the last imported document which its raw values don't tell
+	valueTypes codec.Types
+
+	faultLock       sync.RWMutex
+	err             error
+	operationErrors map[Operation]error
+	latency         time.Duration
+	unavailable     bool
+
+	watchingDoneCtx context.Context
+	watchingDone    context.CancelFunc
+	watchingWait    sync.WaitGroup
+}
+
+type watcher struct {
+	prefix  string
+	changed chan struct{}
+	// touched are the keys written since the last update, relative to the prefix
+	touched map[string]bool
+}
+
+// relativeKey returns the key relative to the watched key
+func (w *watcher) relativeKey(key string) string {
+	return strings.TrimPrefix(strings.TrimPrefix(key, w.prefix), keyDelimiter)
+}
+
+// NewMemoryClient creates a new, empty in-memory Configuration client. Only the BasePath of the config is used.
+func NewMemoryClient(config types.ServiceConfig) *Client {
+	client := &Client{
+		configBasePath:  strings.Trim(config.BasePath, keyDelimiter),
+		values:          make(map[string][]byte),
+		revisions:       make(map[string]uint64),
+		watches:         make(map[*watcher]struct{}),
+		operationErrors: make(map[Operation]error),
+	}
+
+	client.watchingDoneCtx, client.watchingDone = context.WithCancel(context.Background())
+
+	return client
+}
+
+// SetError makes every operation fail with the err until it is cleared by setting nil
+func (client *Client) SetError(err error) {
+	client.faultLock.Lock()
+	defer client.faultLock.Unlock()
+	client.err = err
+}
+
+// SetOperationError makes the operation fail with the err until it is cleared by setting nil. The Ctx variant of the
+// operation fails as well.
+func (client *Client) SetOperationError(operation Operation, err error) {
+	client.faultLock.Lock()
+	defer client.faultLock.Unlock()
+	if err == nil {
+		delete(client.operationErrors, operation)
+		return
+	}
+	client.operationErrors[operation] = err
+}
+
+// SetLatency delays every operation by the latency, or until the operation's context is done
+func (client *Client) SetLatency(latency time.Duration) {
+	client.faultLock.Lock()
+	defer client.faultLock.Unlock()
+	client.latency = latency
+}
+
+// SetUnavailable toggles whether the Configuration service is unavailable. While unavailable, IsAlive returns false
+// and every operation fails with an error matching types.ErrUnavailable.
+func (client *Client) SetUnavailable(unavailable bool) {
+	client.faultLock.Lock()
+	defer client.faultLock.Unlock()
+	client.unavailable = unavailable
+}
+
+// begin applies the injected latency and returns the injected error for the operation, if any
+func (client *Client) begin(ctx context.Context, operation Operation) error {
+	if err := client.delay(ctx); err != nil {
+		return err
+	}
+
+	return client.fault(operation)
+}
+
+func (client *Client) delay(ctx context.Context) error {
+	client.faultLock.RLock()
+	latency := client.latency
+	client.faultLock.RUnlock()
+
+	if latency <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(latency)
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		timer.Stop()
+		return ctx.Err()
+	}
+}
+
+func (client *Client) fault(operation Operation) error {
+	client.faultLock.RLock()
+	defer client.faultLock.RUnlock()
+
+	switch {
+	case client.unavailable:
+		return types.NewProviderError(types.ErrUnavailable, fmt.Errorf("%s failed: the in-memory Configuration service is unavailable", operation))
+	case client.operationErrors[operation] != nil:
+		return client.operationErrors[operation]
+	default:
+		return client.err
+	}
+}
+
+func (client *Client) fullPath(name string) string {
+	return strings.Trim(path.Join(client.configBasePath, name), keyDelimiter)
+}
+
+// hasPrefix returns true if the key is the prefix itself or one of the keys under it
+func hasPrefix(key string, prefix string) bool {
+	return prefix == "" || key == prefix || strings.HasPrefix(key, prefix+keyDelimiter)
+}
+
+// pairs returns a copy of the values stored under the prefix
+func (client *Client) pairs(prefix string) map[string][]byte {
+	client.lock.RLock()
+	defer client.lock.RUnlock()
+
+	pairs := make(map[string][]byte)
+	for key, value := range client.values {
+		if hasPrefix(key, prefix) {
+			pairs[key] = append([]byte{}, value...)
+		}
+	}
+	return pairs
+}
+
+// setLocked stores a copy of the value with a new revision. Must be called with the lock held.
+func (client *Client) setLocked(key string, value []byte) {
+	client.index++
+	client.values[key] = append([]byte{}, value...)
+	client.revisions[key] = client.index
+}
+
+// deleteLocked deletes the value along with its revision. Must be called with the lock held.
+func (client *Client) deleteLocked(key string) {
+	delete(client.values, key)
+	delete(client.revisions, key)
+}
+
+// revisionLocked returns the revision of the stored value, types.NoRevision if it doesn't exist. Must be called with
+// the lock held.
+func (client *Client) revisionLocked(key string) types.Revision {
+	if _, exists := client.values[key]; !exists {
+		return types.NoRevision
+	}
+	return types.Revision(strconv.FormatUint(client.revisions[key], 10))
+}
+
+// notify signals the watches of the changed keys. Must be called with the lock held.
+func (client *Client) notify(changedKeys ...string) {
+	for w := range client.watches {
+		for _, key := range changedKeys {
+			if hasPrefix(key, w.prefix) {
+				w.touched[w.relativeKey(key)] = true
+				select {
+				case w.changed <- struct{}{}:
+				default:
+					// a change is already pending
+				}
+			}
+		}
+	}
+}
+
+// IsAlive returns false while the in-memory Configuration service is set unavailable
+func (client *Client) IsAlive() bool {
+	return client.IsAliveCtx(context.Background())
+}
+
+// IsAliveCtx returns false while the in-memory Configuration service is set unavailable
+func (client *Client) IsAliveCtx(ctx context.Context) bool {
+	if err := client.delay(ctx); err != nil {
+		return false
+	}
+
+	client.faultLock.RLock()
+	defer client.faultLock.RUnlock()
+	return !client.unavailable
+}
+
+// HasConfiguration checks to see if the service's configuration is stored
+func (client *Client) HasConfiguration() (bool, error) {
+	return client.HasConfigurationCtx(context.Background())
+}
+
+// HasConfigurationCtx checks to see if the service's configuration is stored
+func (client *Client) HasConfigurationCtx(ctx context.Context) (bool, error) {
+	if err := client.begin(ctx, OperationHasConfiguration); err != nil {
+		return false, err
+	}
+
+	return len(client.pairs(client.configBasePath)) > 0, nil
+}
+
+// HasSubConfiguration checks to see if the service's sub configuration is stored
+func (client *Client) HasSubConfiguration(name string) (bool, error) {
+	return client.HasSubConfigurationCtx(context.Background(), name)
+}
+
+// HasSubConfigurationCtx checks to see if the service's sub configuration is stored
+func (client *Client) HasSubConfigurationCtx(ctx context.Context, name string) (bool, error) {
+	if err := client.begin(ctx, OperationHasSubConfiguration); err != nil {
+		return false, err
+	}
+
+	return len(client.pairs(client.fullPath(name))) > 0, nil
+}
+
+// PutConfigurationToml puts a full toml configuration
+func (client *Client) PutConfigurationToml(configuration *toml.Tree, overwrite bool) error {
+	return client.PutConfigurationTomlCtx(context.Background(), configuration, overwrite)
+}
+
+// PutConfigurationTomlCtx puts a full toml configuration
+func (client *Client) PutConfigurationTomlCtx(ctx context.Context, configuration *toml.Tree, overwrite bool) error {
+	return client.putConfigurationMap(ctx, configuration.ToMap(), overwrite)
+}
+
+// putConfigurationMap puts the configuration values of the map at once
+func (client *Client) putConfigurationMap(ctx context.Context, configuration map[string]interface{}, overwrite bool) error {
+	if err := client.begin(ctx, OperationPutConfiguration); err != nil {
+		return err
+	}
+
+	values := codec.ToValues(client.configBasePath, configuration)
+
+	client.lock.Lock()
+	defer client.lock.Unlock()
+
+	var changedKeys []string
+	for key, value := range values {
+		if _, exists := client.values[key]; !exists || overwrite {
+			client.setLocked(key, value)
+			changedKeys = append(changedKeys, key)
+		}
+	}
+	client.notify(changedKeys...)
+
+	return nil
+}
+
+// PutConfiguration puts a full configuration struct
+func (client *Client) PutConfiguration(configuration interface{}, overwrite bool) error {
+	return client.PutConfigurationCtx(context.Background(), configuration, overwrite)
+}
+
+// PutConfigurationCtx puts a full configuration struct
+func (client *Client) PutConfigurationCtx(ctx context.Context, configuration interface{}, overwrite bool) error {
+	bytes, err := toml.Marshal(configuration)
+	if err != nil {
+		return err
+	}
+
+	tree, err := toml.LoadBytes(bytes)
+	if err != nil {
+		return err
+	}
+
+	return client.PutConfigurationTomlCtx(ctx, tree, overwrite)
+}
+
+// GetConfiguration gets the full configuration into the target configuration struct.
+// Passed in struct is only a reference for decoder, empty struct is ok
+// Returns the configuration in the target struct as interface{}, which caller must cast
+func (client *Client) GetConfiguration(configStruct interface{}) (interface{}, error) {
+	return client.GetConfigurationCtx(context.Background(), configStruct)
+}
+
+// GetConfigurationCtx gets the full configuration into the target configuration struct.
+func (client *Client) GetConfigurationCtx(ctx context.Context, configStruct interface{}) (interface{}, error) {
+	if err := client.begin(ctx, OperationGetConfiguration); err != nil {
+		return nil, err
+	}
+
+	pairs := client.pairs(client.configBasePath)
+	if len(pairs) == 0 {
+		return nil, fmt.Errorf("the in-memory Configuration service doesn't contain configuration for %s: %w", client.configBasePath, types.ErrNotFound)
+	}
+
+	if err := decode.Decode(client.configBasePath, pairs, configStruct); err != nil {
+		return nil, err
+	}
+
+	return configStruct, nil
+}
+
+// ExportConfiguration writes the configuration to w as a document in the format
+func (client *Client) ExportConfiguration(w io.Writer, format types.ConfigurationFormat) error {
+	return client.ExportConfigurationCtx(context.Background(), w, format)
+}
+
+// ExportConfigurationCtx writes the configuration to w as a document in the format. The values are stored raw and
+// typed back by the types of the last imported document, so it's exported exactly as it was imported. The faults
+// injected for GetConfigurationValues apply.
+func (client *Client) ExportConfigurationCtx(ctx context.Context, w io.Writer, format types.ConfigurationFormat) error {
+	values, err := client.GetConfigurationValuesCtx(ctx, "")
+	if err != nil {
+		return err
+	}
+	if len(values) == 0 {
+		return fmt.Errorf("the in-memory Configuration service doesn't contain configuration for %s: %w", client.configBasePath, types.ErrNotFound)
+	}
+
+	client.lock.RLock()
+	valueTypes := client.valueTypes
+	client.lock.RUnlock()
+	return codec.Write(w, format, codec.FromValues(values, valueTypes))
+}
+
+// ImportConfiguration puts the configuration document in the format read from r
+func (client *Client) ImportConfiguration(r io.Reader, format types.ConfigurationFormat, overwrite bool) error {
+	return client.ImportConfigurationCtx(context.Background(), r, format, overwrite)
+}
+
+// ImportConfigurationCtx puts the configuration document in the format read from r, the same way as
+// PutConfigurationToml, and keeps the types its raw values don't tell for ExportConfiguration. The faults injected
+// for PutConfiguration apply.
+func (client *Client) ImportConfigurationCtx(ctx context.Context, r io.Reader, format types.ConfigurationFormat, overwrite bool) error {
+	configuration, err := codec.Read(r, format)
+	if err != nil {
+		return err
+	}
+
+	if err := client.putConfigurationMap(ctx, configuration, overwrite); err != nil {
+		return err
+	}
+	client.lock.Lock()
+	client.valueTypes = codec.TypesOf(configuration)
+	client.lock.Unlock()
+	return nil
+}
+
+// PlanConfiguration compares the desired configuration with the stored one and returns the plan of the changes
+func (client *Client) PlanConfiguration(desired interface{}) (types.Plan, error) {
+	return client.PlanConfigurationCtx(context.Background(), desired)
+}
+
+// PlanConfigurationCtx compares the desired configuration, either a struct, a map or a *toml.Tree, with the stored
+// one and returns the plan of the values to add, modify and remove. The desired values are formatted the same way as
+// PutConfiguration formats them.
+func (client *Client) PlanConfigurationCtx(ctx context.Context, desired interface{}) (types.Plan, error) {
+	if err := client.begin(ctx, OperationPlanConfiguration); err != nil {
+		return types.Plan{}, err
+	}
+
+	configuration, err := codec.ToMap(desired)
+	if err != nil {
+		return types.Plan{}, err
+	}
+	desiredValues := codec.ToValues("", configuration)
+
+	lister := listing.NewLister(client.configBasePath, "", types.NewListOptions())
+
+	client.lock.RLock()
+	defer client.lock.RUnlock()
+	storedValues := make(map[string][]byte)
+	for _, fullKey := range client.keysLocked(lister.Prefix()) {
+		if key, isValue, ok := lister.Key(fullKey); ok && isValue {
+			storedValues[key] = append([]byte{}, client.values[fullKey]...)
+		}
+	}
+
+	return planning.New(storedValues, desiredValues, func(key string, _ []byte) types.Revision {
+		return client.revisionLocked(client.fullPath(key))
+	}), nil
+}
+
+// ApplyPlan executes exactly the changes of the plan
+func (client *Client) ApplyPlan(plan types.Plan) error {
+	return client.ApplyPlanCtx(context.Background(), plan)
+}
+
+// ApplyPlanCtx executes exactly the changes of the plan at once, unless a value changed by the plan has been changed
+// since the plan was made. Returns an error matching types.ErrConflict then.
+func (client *Client) ApplyPlanCtx(ctx context.Context, plan types.Plan) error {
+	if err := client.begin(ctx, OperationApplyPlan); err != nil {
+		return err
+	}
+
+	client.lock.Lock()
+	defer client.lock.Unlock()
+	err := planning.Check(plan, func(key string) types.Revision {
+		return client.revisionLocked(client.fullPath(key))
+	})
+	if err != nil {
+		return err
+	}
+
+	var changedKeys []string
+	for _, changes := range [][]types.KeyChange{plan.Changes.Added, plan.Changes.Modified} {
+		for _, change := range changes {
+			key := client.fullPath(change.Key)
+			client.setLocked(key, change.NewValue)
+			changedKeys = append(changedKeys, key)
+		}
+	}
+	for _, change := range plan.Changes.Removed {
+		key := client.fullPath(change.Key)
+		client.deleteLocked(key)
+		changedKeys = append(changedKeys, key)
+	}
+	client.notify(changedKeys...)
+
+	return nil
+}
+
+// WatchForChanges watches the target key and sends back updates on the update channel each time a value under it
+// changes. Passed in struct is only a reference for decoder, empty struct is ok
+// Sends the configuration in a new struct of the same type as interface{} on updateChannel, which caller must cast
+func (client *Client) WatchForChanges(updateChannel chan<- interface{}, errorChannel chan<- error, configuration interface{}, waitKey string, options ...types.WatchOption) types.WatchHandle {
+	return client.WatchForChangesCtx(context.Background(), updateChannel, errorChannel, configuration, waitKey, options...)
+}
+
+// WatchForChangesCtx watches the target key and sends back updates on the update channel each time a value under
+// it changes. The current configuration is sent first if it exists. The watch stops when either the context is done,
+// it is stopped through the returned handle or StopWatching is called.
+func (client *Client) WatchForChangesCtx(ctx context.Context, updateChannel chan<- interface{}, errorChannel chan<- error, configuration interface{}, waitKey string, options ...types.WatchOption) types.WatchHandle {
+	return client.watch(ctx, errorChannel, configuration, waitKey, types.NewWatchOptions(options...), func(ctx context.Context, update types.WatchUpdate) bool {
+		select {
+		case updateChannel <- update.Configuration:
+			return true
+		case <-ctx.Done():
+		case <-client.watchingDoneCtx.Done():
+		}
+		return false
+	})
+}
+
+// WatchForChangeSets watches the target key and sends back an update on the update channel each time a value under
+// it changes, along with the values which have been added, modified or removed.
+// Passed in struct is only a reference for decoder, empty struct is ok
+func (client *Client) WatchForChangeSets(updateChannel chan<- types.WatchUpdate, errorChannel chan<- error, configuration interface{}, waitKey string, options ...types.WatchOption) types.WatchHandle {
+	return client.WatchForChangeSetsCtx(context.Background(), updateChannel, errorChannel, configuration, waitKey, options...)
+}
+
+// WatchForChangeSetsCtx watches the target key and sends back an update on the update channel each time a value
+// under it changes, along with the values which have been added, modified or removed. The current configuration is
+// sent first if it exists, with all its values added. Writing a value again, even unchanged, reports it as modified.
+// The watch stops when either the context is done, it is stopped through the returned handle or StopWatching is
+// called.
+func (client *Client) WatchForChangeSetsCtx(ctx context.Context, updateChannel chan<- types.WatchUpdate, errorChannel chan<- error, configuration interface{}, waitKey string, options ...types.WatchOption) types.WatchHandle {
+	return client.watch(ctx, errorChannel, configuration, waitKey, types.NewWatchOptions(options...), func(ctx context.Context, update types.WatchUpdate) bool {
+		select {
+		case updateChannel <- update:
+			return true
+		case <-ctx.Done():
+		case <-client.watchingDoneCtx.Done():
+		}
+		return false
+	})
+}
+
+// watch runs a watch of the target key, which passes the updates to deliver until it returns false
+func (client *Client) watch(ctx context.Context, errorChannel chan<- error, configuration interface{}, waitKey string, options types.WatchOptions, deliver watch.DeliverFunc) types.WatchHandle {
+	ctx, handle := watch.NewHandle(ctx)
+	targetType := reflect.TypeOf(configuration)
+	if targetType.Kind() == reflect.Ptr {
+		targetType = targetType.Elem()
+	}
+
+	w := &watcher{
+		prefix:  client.fullPath(waitKey),
+		changed: make(chan struct{}, 1),
+		touched: make(map[string]bool),
+	}
+
+	client.lock.Lock()
+	client.watches[w] = struct{}{}
+	if len(client.keysLocked(w.prefix)) > 0 {
+		w.changed <- struct{}{}
+	}
+	client.lock.Unlock()
+	// the changes are notified to the watch from now on
+	handle.SetReady()
+
+	client.watchingWait.Add(1)
+	go func() {
+		defer func() {
+			client.lock.Lock()
+			delete(client.watches, w)
+			client.lock.Unlock()
+			handle.SetStopped()
+			client.watchingWait.Done()
+		}()
+
+		// the updates are coalesced over the debounce window of the watch, if set
+		deliver, stopDelivering := watch.Debounce(ctx, options.Debounce, deliver)
+		defer stopDelivering()
+
+		var previous map[string][]byte
+		for {
+			select {
+			case <-w.changed:
+			case <-ctx.Done():
+				return
+			case <-client.watchingDoneCtx.Done():
+				return
+			}
+
+			var update types.WatchUpdate
+			err := client.begin(ctx, OperationWatchForChanges)
+			if err == nil {
+				current, touched := client.snapshot(w)
+				update.Configuration = reflect.New(targetType).Interface()
+				if err = decode.Decode("", current, update.Configuration); err == nil {
+					update.Changes = watch.NewChangeSet(previous, current, touched)
+					previous = current
+				}
+			}
+
+			if err != nil {
+				select {
+				case errorChannel <- err:
+				case <-ctx.Done():
+					return
+				case <-client.watchingDoneCtx.Done():
+					return
+				}
+				continue
+			}
+
+			if !deliver(ctx, update) {
+				return
+			}
+		}
+	}()
+
+	return handle
+}
+
+// snapshot returns a copy of the values under the watched key and the keys written since the previous snapshot,
+// relative to the watched key
+func (client *Client) snapshot(w *watcher) (map[string][]byte, map[string]bool) {
+	client.lock.Lock()
+	defer client.lock.Unlock()
+
+	values := make(map[string][]byte)
+	for key, value := range client.values {
+		if hasPrefix(key, w.prefix) {
+			values[w.relativeKey(key)] = append([]byte{}, value...)
+		}
+	}
+	touched := w.touched
+	w.touched = make(map[string]bool)
+	return values, touched
+}
+
+// keysLocked returns the keys stored under the prefix. Must be called with the lock held.
+func (client *Client) keysLocked(prefix string) []string {
+	var keys []string
+	for key := range client.values {
+		if hasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// StopWatching causes all WatchForChanges processing to stop and waits until they have exited.
+func (client *Client) StopWatching() {
+	client.watchingDone()
+	client.watchingWait.Wait()
+}
+
+// StopWatchingCtx causes all WatchForChanges processing to stop and waits until they have exited
+// or the context is done.
+func (client *Client) StopWatchingCtx(ctx context.Context) error {
+	client.watchingDone()
+
+	stopped := make(chan struct{})
+	go func() {
+		client.watchingWait.Wait()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// ConfigurationValueExists checks if a configuration value is stored
+func (client *Client) ConfigurationValueExists(name string) (bool, error) {
+	return client.ConfigurationValueExistsCtx(context.Background(), name)
+}
+
+// ConfigurationValueExistsCtx checks if a configuration value is stored
+func (client *Client) ConfigurationValueExistsCtx(ctx context.Context, name string) (bool, error) {
+	if err := client.begin(ctx, OperationConfigurationValueExists); err != nil {
+		return false, err
+	}
+
+	client.lock.RLock()
+	defer client.lock.RUnlock()
+	_, exists := client.values[client.fullPath(name)]
+	return exists, nil
+}
+
+// GetConfigurationValue gets a specific configuration value
+func (client *Client) GetConfigurationValue(name string) ([]byte, error) {
+	return client.GetConfigurationValueCtx(context.Background(), name)
+}
+
+// GetConfigurationValueCtx gets a specific configuration value. Returns nil without an error if the value doesn't
+// exist.
+func (client *Client) GetConfigurationValueCtx(ctx context.Context, name string) ([]byte, error) {
+	if err := client.begin(ctx, OperationGetConfigurationValue); err != nil {
+		return nil, err
+	}
+
+	client.lock.RLock()
+	defer client.lock.RUnlock()
+	value, exists := client.values[client.fullPath(name)]
+	if !exists {
+		return nil, nil
+	}
+	return append([]byte{}, value...), nil
+}
+
+// ListConfigurationKeys lists the keys of the configuration values under the prefix
+func (client *Client) ListConfigurationKeys(prefix string, options ...types.ListOption) ([]string, error) {
+	return client.ListConfigurationKeysCtx(context.Background(), prefix, options...)
+}
+
+// ListConfigurationKeysCtx lists the keys of the configuration values under the prefix, sorted and relative to the
+// base path
+func (client *Client) ListConfigurationKeysCtx(ctx context.Context, prefix string, options ...types.ListOption) ([]string, error) {
+	if err := client.begin(ctx, OperationListConfigurationKeys); err != nil {
+		return nil, err
+	}
+
+	lister := listing.NewLister(client.configBasePath, prefix, types.NewListOptions(options...))
+
+	client.lock.RLock()
+	defer client.lock.RUnlock()
+	return lister.Keys(client.keysLocked(lister.Prefix())), nil
+}
+
+// GetConfigurationValues gets the configuration values under the prefix
+func (client *Client) GetConfigurationValues(prefix string, options ...types.ListOption) (map[string][]byte, error) {
+	return client.GetConfigurationValuesCtx(context.Background(), prefix, options...)
+}
+
+// GetConfigurationValuesCtx gets the configuration values under the prefix, keyed relative to the base path
+func (client *Client) GetConfigurationValuesCtx(ctx context.Context, prefix string, options ...types.ListOption) (map[string][]byte, error) {
+	if err := client.begin(ctx, OperationGetConfigurationValues); err != nil {
+		return nil, err
+	}
+
+	lister := listing.NewLister(client.configBasePath, prefix, types.NewListOptions(options...))
+	return lister.Values(client.pairs(lister.Prefix())), nil
+}
+
+// PutConfigurationValue puts a specific configuration value
+func (client *Client) PutConfigurationValue(name string, value []byte) error {
+	return client.PutConfigurationValueCtx(context.Background(), name, value)
+}
+
+// PutConfigurationValueCtx puts a specific configuration value
+func (client *Client) PutConfigurationValueCtx(ctx context.Context, name string, value []byte) error {
+	if err := client.begin(ctx, OperationPutConfigurationValue); err != nil {
+		return err
+	}
+
+	key := client.fullPath(name)
+
+	client.lock.Lock()
+	defer client.lock.Unlock()
+	client.setLocked(key, value)
+	client.notify(key)
+
+	return nil
+}
+
+// GetConfigurationValueWithVersion gets a specific configuration value along with its revision
+func (client *Client) GetConfigurationValueWithVersion(name string) ([]byte, types.Revision, error) {
+	return client.GetConfigurationValueWithVersionCtx(context.Background(), name)
+}
+
+// GetConfigurationValueWithVersionCtx gets a specific configuration value along with its revision, which changes at
+// each write of the value. Returns nil and types.NoRevision without an error if the value doesn't exist.
+func (client *Client) GetConfigurationValueWithVersionCtx(ctx context.Context, name string) ([]byte, types.Revision, error) {
+	if err := client.begin(ctx, OperationGetConfigurationValueWithVersion); err != nil {
+		return nil, types.NoRevision, err
+	}
+
+	key := client.fullPath(name)
+
+	client.lock.RLock()
+	defer client.lock.RUnlock()
+	value, exists := client.values[key]
+	if !exists {
+		return nil, types.NoRevision, nil
+	}
+	return append([]byte{}, value...), client.revisionLocked(key), nil
+}
+
+// PutConfigurationValueCAS puts a specific configuration value only if its revision is still the given one
+func (client *Client) PutConfigurationValueCAS(name string, value []byte, revision types.Revision) error {
+	return client.PutConfigurationValueCASCtx(context.Background(), name, value, revision)
+}
+
+// PutConfigurationValueCASCtx puts a specific configuration value only if its revision is still the given one.
+// types.NoRevision only creates the value if it doesn't exist. Returns an error matching types.ErrConflict otherwise.
+func (client *Client) PutConfigurationValueCASCtx(ctx context.Context, name string, value []byte, revision types.Revision) error {
+	if err := client.begin(ctx, OperationPutConfigurationValueCAS); err != nil {
+		return err
+	}
+
+	key := client.fullPath(name)
+
+	client.lock.Lock()
+	defer client.lock.Unlock()
+	if current := client.revisionLocked(key); current != revision {
+		return types.NewProviderError(types.ErrConflict, fmt.Errorf("unable to put value for %s: revision %q is stale, the current one is %q", key, revision, current))
+	}
+	client.setLocked(key, value)
+	client.notify(key)
+
+	return nil
+}
+
+// DeleteConfigurationValue deletes a specific configuration value
+func (client *Client) DeleteConfigurationValue(name string) error {
+	return client.DeleteConfigurationValueCtx(context.Background(), name)
+}
+
+// DeleteConfigurationValueCtx deletes a specific configuration value
+func (client *Client) DeleteConfigurationValueCtx(ctx context.Context, name string) error {
+	if err := client.begin(ctx, OperationDeleteConfigurationValue); err != nil {
+		return err
+	}
+
+	key := client.fullPath(name)
+
+	client.lock.Lock()
+	defer client.lock.Unlock()
+	if _, exists := client.values[key]; exists {
+		client.deleteLocked(key)
+		client.notify(key)
+	}
+
+	return nil
+}
+
+// DeleteSubConfiguration deletes all configuration values under the sub configuration with the specified name.
+// An empty name deletes the service's whole configuration.
+func (client *Client) DeleteSubConfiguration(name string) error {
+	return client.DeleteSubConfigurationCtx(context.Background(), name)
+}
+
+// DeleteSubConfigurationCtx deletes all configuration values under the sub configuration with the specified name.
+// An empty name deletes the service's whole configuration.
+func (client *Client) DeleteSubConfigurationCtx(ctx context.Context, name string) error {
+	if err := client.begin(ctx, OperationDeleteSubConfiguration); err != nil {
+		return err
+	}
+
+	prefix := client.fullPath(name)
+
+	client.lock.Lock()
+	defer client.lock.Unlock()
+	keys := client.keysLocked(prefix)
+	for _, key := range keys {
+		client.deleteLocked(key)
+	}
+	client.notify(keys...)
+
+	return nil
+}
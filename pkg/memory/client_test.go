@@ -0,0 +1,541 @@
+//
+// Copyright (C) 2022 IOTech Ltd
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package memory
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pelletier/go-toml"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/edgexfoundry/go-mod-configuration/v2/pkg/types"
+)
+
+type LoggingInfo struct {
+	EnableRemote bool
+	File         string
+}
+
+type TestConfig struct {
+	Logging  LoggingInfo
+	Port     int
+	Host     string
+	LogLevel string
+	Temp     float64
+}
+
+var expectedConfig = TestConfig{
+	Logging:  LoggingInfo{EnableRemote: true, File: "/tmp/core-data.log"},
+	Port:     59880,
+	Host:     "localhost",
+	LogLevel: "INFO",
+	Temp:     36.6,
+}
+
+func makeMemoryClient() *Client {
+	return NewMemoryClient(types.ServiceConfig{BasePath: "edgex/core-data"})
+}
+
+func TestGetConfiguration(t *testing.T) {
+	client := makeMemoryClient()
+
+	exists, err := client.HasConfiguration()
+	require.NoError(t, err)
+	assert.False(t, exists)
+	_, err = client.GetConfiguration(&TestConfig{})
+	assert.True(t, errors.Is(err, types.ErrNotFound))
+
+	require.NoError(t, client.PutConfiguration(&expectedConfig, false))
+
+	exists, err = client.HasConfiguration()
+	require.NoError(t, err)
+	assert.True(t, exists)
+	exists, err = client.HasSubConfiguration("Logging")
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	result, err := client.GetConfiguration(&TestConfig{})
+	require.NoError(t, err)
+	assert.Equal(t, expectedConfig, *result.(*TestConfig))
+
+	value, err := client.GetConfigurationValue("Logging/File")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("/tmp/core-data.log"), value)
+	value, err = client.GetConfigurationValue("Temp")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("36.6"), value)
+}
+
+func TestPutConfigurationOverwrite(t *testing.T) {
+	client := makeMemoryClient()
+	require.NoError(t, client.PutConfiguration(&expectedConfig, false))
+
+	changed := expectedConfig
+	changed.Port = 1234
+
+	require.NoError(t, client.PutConfiguration(&changed, false))
+	result, err := client.GetConfiguration(&TestConfig{})
+	require.NoError(t, err)
+	assert.Equal(t, expectedConfig.Port, result.(*TestConfig).Port)
+
+	require.NoError(t, client.PutConfiguration(&changed, true))
+	result, err = client.GetConfiguration(&TestConfig{})
+	require.NoError(t, err)
+	assert.Equal(t, changed.Port, result.(*TestConfig).Port)
+}
+
+func TestConfigurationValues(t *testing.T) {
+	client := makeMemoryClient()
+
+	value, err := client.GetConfigurationValue("Host")
+	require.NoError(t, err)
+	assert.Nil(t, value)
+
+	require.NoError(t, client.PutConfigurationValue("Host", []byte("localhost")))
+	require.NoError(t, client.PutConfigurationValue("Writable/LogLevel", []byte("DEBUG")))
+	require.NoError(t, client.PutConfigurationValue("Writable/InsecureSecrets/DB/Path", []byte("redisdb")))
+
+	exists, err := client.ConfigurationValueExists("Host")
+	require.NoError(t, err)
+	assert.True(t, exists)
+	value, err = client.GetConfigurationValue("Host")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("localhost"), value)
+
+	require.NoError(t, client.DeleteConfigurationValue("Host"))
+	exists, err = client.ConfigurationValueExists("Host")
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	require.NoError(t, client.DeleteSubConfiguration("Writable/InsecureSecrets"))
+	exists, err = client.HasSubConfiguration("Writable/InsecureSecrets")
+	require.NoError(t, err)
+	assert.False(t, exists)
+	exists, err = client.ConfigurationValueExists("Writable/LogLevel")
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	require.NoError(t, client.DeleteSubConfiguration(""))
+	exists, err = client.HasConfiguration()
+	require.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func TestPutConfigurationValueCAS(t *testing.T) {
+	client := makeMemoryClient()
+
+	value, revision, err := client.GetConfigurationValueWithVersion("Writable/LogLevel")
+	require.NoError(t, err)
+	assert.Nil(t, value)
+	assert.Equal(t, types.NoRevision, revision)
+
+	// no revision only creates the value
+	require.NoError(t, client.PutConfigurationValueCAS("Writable/LogLevel", []byte("INFO"), types.NoRevision))
+	err = client.PutConfigurationValueCAS("Writable/LogLevel", []byte("TRACE"), types.NoRevision)
+	assert.True(t, errors.Is(err, types.ErrConflict), "unexpected error: %v", err)
+
+	value, revision, err = client.GetConfigurationValueWithVersion("Writable/LogLevel")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("INFO"), value)
+
+	// writing the same value again still makes the revision stale
+	require.NoError(t, client.PutConfigurationValue("Writable/LogLevel", []byte("INFO")))
+	err = client.PutConfigurationValueCAS("Writable/LogLevel", []byte("DEBUG"), revision)
+	assert.True(t, errors.Is(err, types.ErrConflict), "unexpected error: %v", err)
+
+	_, revision, err = client.GetConfigurationValueWithVersion("Writable/LogLevel")
+	require.NoError(t, err)
+	require.NoError(t, client.PutConfigurationValueCAS("Writable/LogLevel", []byte("DEBUG"), revision))
+	value, err = client.GetConfigurationValue("Writable/LogLevel")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("DEBUG"), value)
+}
+
+func TestListConfigurationKeys(t *testing.T) {
+	client := makeMemoryClient()
+	require.NoError(t, client.PutConfiguration(expectedConfig, false))
+	require.NoError(t, client.PutConfigurationValue("Writable/InsecureSecrets/DB/Path", []byte("redisdb")))
+
+	keys, err := client.ListConfigurationKeys("")
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"Host",
+		"LogLevel",
+		"Logging/EnableRemote",
+		"Logging/File",
+		"Port",
+		"Temp",
+		"Writable/InsecureSecrets/DB/Path",
+	}, keys)
+
+	keys, err = client.ListConfigurationKeys("Writable", types.WithDepth(1))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Writable/InsecureSecrets/"}, keys)
+
+	values, err := client.GetConfigurationValues("Logging", types.WithSeparator("."))
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]byte{
+		"Logging.EnableRemote": []byte("true"),
+		"Logging.File":         []byte("/tmp/core-data.log"),
+	}, values)
+
+	client.SetOperationError(OperationGetConfigurationValues, errors.New("failed"))
+	_, err = client.GetConfigurationValues("")
+	assert.Error(t, err)
+}
+
+func TestExportImportConfiguration(t *testing.T) {
+	source := makeMemoryClient()
+	require.NoError(t, source.PutConfiguration(expectedConfig, false))
+	require.NoError(t, source.PutConfigurationValue("Topics/0", []byte("events")))
+	require.NoError(t, source.PutConfigurationValue("Version", []byte("3.0")))
+
+	var exported bytes.Buffer
+	require.NoError(t, source.ExportConfiguration(&exported, types.FormatJSON))
+
+	target := NewMemoryClient(types.ServiceConfig{BasePath: "edgex/core-command"})
+	require.NoError(t, target.ImportConfiguration(bytes.NewReader(exported.Bytes()), types.FormatJSON, false))
+	var restored bytes.Buffer
+	require.NoError(t, target.ExportConfiguration(&restored, types.FormatJSON))
+	assert.Equal(t, exported.String(), restored.String())
+
+	sourceValues, err := source.GetConfigurationValues("")
+	require.NoError(t, err)
+	targetValues, err := target.GetConfigurationValues("")
+	require.NoError(t, err)
+	assert.Equal(t, sourceValues, targetValues)
+
+	result, err := target.GetConfiguration(&TestConfig{})
+	require.NoError(t, err)
+	assert.Equal(t, expectedConfig, *result.(*TestConfig))
+
+	client := makeMemoryClient()
+	err = client.ExportConfiguration(&bytes.Buffer{}, types.FormatJSON)
+	assert.True(t, errors.Is(err, types.ErrNotFound), "unexpected error: %v", err)
+	err = client.ImportConfiguration(strings.NewReader("{"), types.FormatJSON, false)
+	assert.True(t, errors.Is(err, types.ErrDecode), "unexpected error: %v", err)
+}
+
+func TestPlanConfiguration(t *testing.T) {
+	client := makeMemoryClient()
+	require.NoError(t, client.PutConfiguration(expectedConfig, true))
+	require.NoError(t, client.PutConfigurationValue("Writable/LogLevel", []byte("INFO")))
+
+	desired := expectedConfig
+	desired.Port = 59881
+	desired.Logging.File = "NONE"
+	plan, err := client.PlanConfiguration(desired)
+	require.NoError(t, err)
+	assert.Empty(t, plan.Changes.Added)
+	assert.Equal(t, []types.KeyChange{
+		{Key: "Logging/File", OldValue: []byte("/tmp/core-data.log"), NewValue: []byte("NONE")},
+		{Key: "Port", OldValue: []byte("59880"), NewValue: []byte("59881")},
+	}, plan.Changes.Modified)
+	assert.Equal(t, []types.KeyChange{{Key: "Writable/LogLevel", OldValue: []byte("INFO")}}, plan.Changes.Removed)
+
+	// nothing is changed until the plan is applied
+	value, err := client.GetConfigurationValue("Port")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("59880"), value)
+
+	tree, err := toml.Load(`Port = 59880
+[Writable]
+  LogLevel = "INFO"
+  Insecure = true
+`)
+	require.NoError(t, err)
+	plan, err = client.PlanConfiguration(tree)
+	require.NoError(t, err)
+	assert.Equal(t, []types.KeyChange{{Key: "Writable/Insecure", NewValue: []byte("true")}}, plan.Changes.Added)
+	assert.Empty(t, plan.Changes.Modified)
+	assert.Len(t, plan.Changes.Removed, 5)
+}
+
+func TestApplyPlan(t *testing.T) {
+	client := makeMemoryClient()
+	require.NoError(t, client.PutConfigurationValue("Port", []byte("8000")))
+	require.NoError(t, client.PutConfigurationValue("Writable/LogLevel", []byte("INFO")))
+	desired := map[string]interface{}{"Port": 8001, "Host": "localhost"}
+
+	plan, err := client.PlanConfiguration(desired)
+	require.NoError(t, err)
+	require.NoError(t, client.ApplyPlan(plan))
+
+	values, err := client.GetConfigurationValues("")
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]byte{"Port": []byte("8001"), "Host": []byte("localhost")}, values)
+	plan, err = client.PlanConfiguration(desired)
+	require.NoError(t, err)
+	assert.True(t, plan.IsEmpty())
+
+	// writing a value changed by the plan since it was made, even with the same value, prevents applying any of it
+	plan, err = client.PlanConfiguration(map[string]interface{}{"Port": 8002, "Host": "127.0.0.1"})
+	require.NoError(t, err)
+	require.NoError(t, client.PutConfigurationValue("Host", []byte("localhost")))
+	err = client.ApplyPlan(plan)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, types.ErrConflict), "unexpected error: %v", err)
+
+	values, err = client.GetConfigurationValues("")
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]byte{"Port": []byte("8001"), "Host": []byte("localhost")}, values)
+}
+
+func TestDecodeError(t *testing.T) {
+	client := makeMemoryClient()
+	require.NoError(t, client.PutConfigurationValue("Port", []byte("not a number")))
+
+	_, err := client.GetConfiguration(&TestConfig{})
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, types.ErrDecode))
+}
+
+func TestWatchForChanges(t *testing.T) {
+	client := makeMemoryClient()
+	require.NoError(t, client.PutConfiguration(&expectedConfig, false))
+
+	updateChannel := make(chan interface{})
+	errorChannel := make(chan error)
+	client.WatchForChanges(updateChannel, errorChannel, &LoggingInfo{}, "Logging")
+	defer client.StopWatching()
+
+	receive := func() *LoggingInfo {
+		select {
+		case update := <-updateChannel:
+			actual, ok := update.(*LoggingInfo)
+			require.True(t, ok)
+			return actual
+		case err := <-errorChannel:
+			require.NoError(t, err)
+		case <-time.After(5 * time.Second):
+			require.Fail(t, "timed out waiting for update")
+		}
+		return nil
+	}
+
+	// The current configuration is sent first
+	assert.Equal(t, expectedConfig.Logging, *receive())
+
+	require.NoError(t, client.PutConfigurationValue("Port", []byte("1")))
+	require.NoError(t, client.PutConfigurationValue("Logging/File", []byte("changed.log")))
+	assert.Equal(t, LoggingInfo{EnableRemote: true, File: "changed.log"}, *receive())
+
+	// Changes outside of the watched key aren't sent
+	require.NoError(t, client.PutConfigurationValue("Port", []byte("2")))
+	select {
+	case <-updateChannel:
+		require.Fail(t, "update not expected for a change outside of the watched key")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// The injected errors are sent to the error channel
+	expectedErr := errors.New("watch failed")
+	client.SetOperationError(OperationWatchForChanges, expectedErr)
+	require.NoError(t, client.PutConfigurationValue("Logging/EnableRemote", []byte("false")))
+	select {
+	case err := <-errorChannel:
+		assert.Equal(t, expectedErr, err)
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "timed out waiting for error")
+	}
+}
+
+func TestWatchForChangeSets(t *testing.T) {
+	client := makeMemoryClient()
+	require.NoError(t, client.PutConfiguration(&expectedConfig, false))
+
+	updateChannel := make(chan types.WatchUpdate)
+	errorChannel := make(chan error)
+	handle := client.WatchForChangeSets(updateChannel, errorChannel, &LoggingInfo{}, "Logging")
+	defer handle.Stop()
+	select {
+	case <-handle.Ready():
+	default:
+		require.Fail(t, "watch expected to be ready once started")
+	}
+
+	receive := func() types.WatchUpdate {
+		select {
+		case update := <-updateChannel:
+			return update
+		case err := <-errorChannel:
+			require.NoError(t, err)
+		case <-time.After(5 * time.Second):
+			require.Fail(t, "timed out waiting for update")
+		}
+		return types.WatchUpdate{}
+	}
+
+	// All the values are added in the first update
+	update := receive()
+	assert.Equal(t, &expectedConfig.Logging, update.Configuration)
+	assert.Equal(t, types.ChangeSet{Added: []types.KeyChange{
+		{Key: "EnableRemote", NewValue: []byte("true")},
+		{Key: "File", NewValue: []byte("/tmp/core-data.log")},
+	}}, update.Changes)
+
+	require.NoError(t, client.PutConfigurationValue("Logging/File", []byte("changed.log")))
+	update = receive()
+	assert.Equal(t, &LoggingInfo{EnableRemote: true, File: "changed.log"}, update.Configuration)
+	assert.Equal(t, types.ChangeSet{Modified: []types.KeyChange{
+		{Key: "File", OldValue: []byte("/tmp/core-data.log"), NewValue: []byte("changed.log")},
+	}}, update.Changes)
+
+	require.NoError(t, client.DeleteConfigurationValue("Logging/EnableRemote"))
+	update = receive()
+	assert.Equal(t, &LoggingInfo{File: "changed.log"}, update.Configuration)
+	assert.Equal(t, types.ChangeSet{Removed: []types.KeyChange{
+		{Key: "EnableRemote", OldValue: []byte("true")},
+	}}, update.Changes)
+
+	// Writing a value again is reported as modified, even if it's unchanged
+	require.NoError(t, client.PutConfigurationValue("Logging/File", []byte("changed.log")))
+	update = receive()
+	assert.Equal(t, []string{"File"}, update.Changes.Keys())
+}
+
+func TestWatchForChangesDebounce(t *testing.T) {
+	client := makeMemoryClient()
+	require.NoError(t, client.PutConfiguration(&expectedConfig, false))
+
+	updateChannel := make(chan interface{})
+	errorChannel := make(chan error)
+	handle := client.WatchForChanges(updateChannel, errorChannel, &LoggingInfo{}, "Logging", types.WithDebounce(100*time.Millisecond))
+	defer handle.Stop()
+
+	receive := func() interface{} {
+		select {
+		case update := <-updateChannel:
+			return update
+		case err := <-errorChannel:
+			require.NoError(t, err)
+		case <-time.After(5 * time.Second):
+			require.Fail(t, "timed out waiting for update")
+		}
+		return nil
+	}
+	assert.Equal(t, &expectedConfig.Logging, receive())
+
+	// The changes made within the window are sent as a single update
+	require.NoError(t, client.PutConfigurationValue("Logging/File", []byte("changed.log")))
+	require.NoError(t, client.PutConfigurationValue("Logging/EnableRemote", []byte("false")))
+	assert.Equal(t, &LoggingInfo{File: "changed.log"}, receive())
+	select {
+	case update := <-updateChannel:
+		require.Fail(t, "unexpected update", "%v", update)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+func TestStopWatching(t *testing.T) {
+	client := makeMemoryClient()
+	require.NoError(t, client.PutConfiguration(&expectedConfig, false))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	updateChannel := make(chan interface{})
+	errorChannel := make(chan error)
+	client.WatchForChangesCtx(ctx, updateChannel, errorChannel, &TestConfig{}, "")
+	client.WatchForChanges(updateChannel, errorChannel, &TestConfig{}, "")
+
+	// The first watch is stopped by its context, the other one by StopWatching while blocked sending an update
+	cancel()
+	stopped := make(chan struct{})
+	go func() {
+		client.StopWatching()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "watches not stopped")
+	}
+
+	client.lock.RLock()
+	defer client.lock.RUnlock()
+	assert.Empty(t, client.watches)
+}
+
+func TestFaultInjection(t *testing.T) {
+	expectedErr := errors.New("forced error")
+
+	testCases := []struct {
+		Name          string
+		Inject        func(client *Client)
+		ExpectedError error
+		ExpectedAlive bool
+	}{
+		{
+			Name:          "No fault",
+			Inject:        func(client *Client) {},
+			ExpectedAlive: true,
+		},
+		{
+			Name:          "Forced error",
+			Inject:        func(client *Client) { client.SetError(expectedErr) },
+			ExpectedError: expectedErr,
+			ExpectedAlive: true,
+		},
+		{
+			Name:          "Operation error",
+			Inject:        func(client *Client) { client.SetOperationError(OperationGetConfigurationValue, expectedErr) },
+			ExpectedError: expectedErr,
+			ExpectedAlive: true,
+		},
+		{
+			Name:          "Other operation error",
+			Inject:        func(client *Client) { client.SetOperationError(OperationPutConfigurationValue, expectedErr) },
+			ExpectedAlive: true,
+		},
+		{
+			Name:          "Unavailable",
+			Inject:        func(client *Client) { client.SetUnavailable(true) },
+			ExpectedError: types.ErrUnavailable,
+			ExpectedAlive: false,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.Name, func(t *testing.T) {
+			client := makeMemoryClient()
+			test.Inject(client)
+
+			assert.Equal(t, test.ExpectedAlive, client.IsAlive())
+
+			_, err := client.GetConfigurationValue("Host")
+			if test.ExpectedError == nil {
+				require.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.True(t, errors.Is(err, test.ExpectedError))
+		})
+	}
+}
+
+func TestLatency(t *testing.T) {
+	client := makeMemoryClient()
+	client.SetLatency(time.Hour)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err := client.GetConfigurationValueCtx(ctx, "Host")
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.False(t, client.IsAliveCtx(ctx))
+
+	client.SetLatency(10 * time.Millisecond)
+	start := time.Now()
+	_, err = client.GetConfigurationValue("Host")
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, time.Since(start), 10*time.Millisecond)
+}
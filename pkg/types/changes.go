@@ -0,0 +1,95 @@
+//
+// Copyright (C) 2022 IOTech Ltd
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package types
+
+import "sort"
+
+// KeyChange is the change of a single configuration value. The key is relative to the watched key.
+type KeyChange struct {
+	Key string
+	// OldValue is the raw value before the change, nil if the key has been added
+	OldValue []byte
+	// NewValue is the raw value after the change, nil if the key has been removed
+	NewValue []byte
+}
+
+// ChangeSet lists the configuration values which have been added, modified or removed, sorted by key
+type ChangeSet struct {
+	Added    []KeyChange
+	Modified []KeyChange
+	Removed  []KeyChange
+}
+
+// IsEmpty checks if the change set doesn't contain any change
+func (changes ChangeSet) IsEmpty() bool {
+	return len(changes.Added) == 0 && len(changes.Modified) == 0 && len(changes.Removed) == 0
+}
+
+// Keys returns the sorted keys of all the changes
+func (changes ChangeSet) Keys() []string {
+	var keys []string
+	for _, list := range [][]KeyChange{changes.Added, changes.Modified, changes.Removed} {
+		for _, change := range list {
+			keys = append(keys, change.Key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Merge returns the changes of both change sets, as if they had been made at once. For a key changed in both, the
+// old value is the one before the changes and the new value the one after the later changes. A key added then
+// removed is dropped, while a key changed back to its old value is still modified.
+func (changes ChangeSet) Merge(later ChangeSet) ChangeSet {
+	// merged is the change of a key, along with whether the key exists before and after it
+	type merged struct {
+		change  KeyChange
+		existed bool
+		exists  bool
+	}
+	byKey := make(map[string]*merged)
+	var keys []string
+	add := func(list []KeyChange, existed bool, exists bool) {
+		for _, change := range list {
+			if previous, ok := byKey[change.Key]; ok {
+				previous.change.NewValue = change.NewValue
+				previous.exists = exists
+				continue
+			}
+			byKey[change.Key] = &merged{change: change, existed: existed, exists: exists}
+			keys = append(keys, change.Key)
+		}
+	}
+	for _, set := range []ChangeSet{changes, later} {
+		add(set.Added, false, true)
+		add(set.Modified, true, true)
+		add(set.Removed, true, false)
+	}
+	sort.Strings(keys)
+
+	var result ChangeSet
+	for _, key := range keys {
+		switch m := byKey[key]; {
+		case !m.existed && m.exists:
+			result.Added = append(result.Added, m.change)
+		case m.existed && m.exists:
+			result.Modified = append(result.Modified, m.change)
+		case m.existed && !m.exists:
+			result.Removed = append(result.Removed, m.change)
+		}
+	}
+	return result
+}
+
+// WatchUpdate is sent by WatchForChangeSets each time the watched configuration changes
+type WatchUpdate struct {
+	// Configuration is the whole configuration under the watched key, decoded into a new struct of the same type as
+	// the struct passed to WatchForChangeSets
+	Configuration interface{}
+	// Changes are the values which have changed since the previous update. All the values are added in the first
+	// update.
+	Changes ChangeSet
+}
@@ -0,0 +1,59 @@
+//
+// Copyright (C) 2022 IOTech Ltd
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChangeSetMerge(t *testing.T) {
+	earlier := ChangeSet{
+		Added: []KeyChange{
+			{Key: "Added", NewValue: []byte("1")},
+			{Key: "AddedRemoved", NewValue: []byte("1")},
+		},
+		Modified: []KeyChange{
+			{Key: "Modified", OldValue: []byte("1"), NewValue: []byte("2")},
+			{Key: "ModifiedBack", OldValue: []byte("1"), NewValue: []byte("2")},
+		},
+		Removed: []KeyChange{
+			{Key: "RemovedAdded", OldValue: []byte("1")},
+		},
+	}
+	later := ChangeSet{
+		Added: []KeyChange{
+			{Key: "RemovedAdded", NewValue: []byte("2")},
+		},
+		Modified: []KeyChange{
+			{Key: "Added", OldValue: []byte("1"), NewValue: []byte("2")},
+			{Key: "Modified", OldValue: []byte("2"), NewValue: []byte("3")},
+			{Key: "ModifiedBack", OldValue: []byte("2"), NewValue: []byte("1")},
+		},
+		Removed: []KeyChange{
+			{Key: "AddedRemoved", OldValue: []byte("1")},
+			{Key: "Removed", OldValue: []byte("1")},
+		},
+	}
+
+	expected := ChangeSet{
+		Added: []KeyChange{
+			{Key: "Added", NewValue: []byte("2")},
+		},
+		Modified: []KeyChange{
+			{Key: "Modified", OldValue: []byte("1"), NewValue: []byte("3")},
+			{Key: "ModifiedBack", OldValue: []byte("1"), NewValue: []byte("1")},
+			{Key: "RemovedAdded", OldValue: []byte("1"), NewValue: []byte("2")},
+		},
+		Removed: []KeyChange{
+			{Key: "Removed", OldValue: []byte("1")},
+		},
+	}
+	assert.Equal(t, expected, earlier.Merge(later))
+	assert.Equal(t, later, ChangeSet{}.Merge(later))
+	assert.True(t, ChangeSet{}.Merge(ChangeSet{}).IsEmpty())
+}
@@ -17,13 +17,31 @@
 package types
 
 import (
+	"errors"
 	"fmt"
+	"math/rand"
 	"net/url"
 	"strconv"
 	"strings"
+	"time"
 )
 
-const DefaultProtocol = "http"
+const (
+	DefaultProtocol = "http"
+
+	DefaultRetryInitialBackoff = 100 * time.Millisecond
+	DefaultRetryMaxBackoff     = 10 * time.Second
+	DefaultRetryMultiplier     = 2.0
+
+	// MessageBusKey is the ServiceConfig.Optional key of the message bus connection, used when ServiceConfig.MessageBus
+	// isn't set. The value is either a MessageBusConfig or a map of its fields, i.e. decoded from a configuration file.
+	MessageBusKey = "MessageBus"
+
+	AuthModeNone             = "none"
+	AuthModeUsernamePassword = "usernamepassword"
+	AuthModeClientCert       = "clientcert"
+	AuthModeCACert           = "cacert"
+)
 
 type GetAccessTokenCallback func() (string, error)
 
@@ -40,16 +58,168 @@ type ServiceConfig struct {
 	Type string
 	// BasePath is the base path with in the Configuration service where the your service's configuration is stored
 	BasePath string
+	// FilePath is the path of the TOML, YAML or JSON file holding the configuration when Type is file
+	FilePath string
 	// AccessToken is the token that is used to access the service configuration
 	AccessToken string
 	// GetAccessToken is a callback function that retrieves a new Access Token.
-	// This callback is used when a '403 Forbidden' status is received from any call to the configuration provider service.
+	// This callback is used when a '403 Forbidden' status, or a '401 Unauthorized' status for Core Keeper, is received
+	// from any call to the configuration provider service.
 	GetAccessToken GetAccessTokenCallback
+	// TLS contains the TLS settings used to connect to the Configuration service when Protocol is https
+	TLS TLSConfig
+	// Retry is the policy used to retry the calls to the Configuration service which fail with a transient error.
+	// The calls aren't retried if not set.
+	Retry RetryPolicy
+	// MessageBus is the message bus connection used to receive the configuration changes, i.e. the ones published by
+	// Core Keeper. When not set, it's taken from the MessageBusKey property of Optional, then from the MessageQueue
+	// section of the service's configuration.
+	MessageBus MessageBusConfig
 	// Optional contains all other properties of the configuration provider might use.
 	// For example, it might need the message bus connection information to publish the config changes.
 	Optional map[string]any
 }
 
+// TLSConfig defines the TLS settings used to connect to the Configuration service. The CA, certificate and key can be
+// provided either as file or as PEM encoded data.
+type TLSConfig struct {
+	// CAFile is the path to the PEM encoded CA bundle used to verify the Configuration service's certificate
+	CAFile string
+	// CAPem is the PEM encoded CA bundle used to verify the Configuration service's certificate
+	CAPem []byte
+	// CertFile is the path to the PEM encoded client certificate used for mutual TLS
+	CertFile string
+	// CertPem is the PEM encoded client certificate used for mutual TLS
+	CertPem []byte
+	// KeyFile is the path to the PEM encoded private key of the client certificate
+	KeyFile string
+	// KeyPem is the PEM encoded private key of the client certificate
+	KeyPem []byte
+	// ServerName is the name used to verify the Configuration service's certificate, if different from Host
+	ServerName string
+	// InsecureSkipVerify disables the verification of the Configuration service's certificate
+	InsecureSkipVerify bool
+}
+
+// IsEmpty returns true when none of the TLS settings is set
+func (tlsConfig TLSConfig) IsEmpty() bool {
+	return tlsConfig.CAFile == "" && len(tlsConfig.CAPem) == 0 &&
+		tlsConfig.CertFile == "" && len(tlsConfig.CertPem) == 0 &&
+		tlsConfig.KeyFile == "" && len(tlsConfig.KeyPem) == 0 &&
+		tlsConfig.ServerName == "" && !tlsConfig.InsecureSkipVerify
+}
+
+// MessageBusConfig defines the message bus connection used by the configuration provider to receive the
+// configuration changes, including the credentials which would otherwise have to be stored in the configuration itself
+type MessageBusConfig struct {
+	// Type is the message bus implementation, i.e. mqtt, redis or zero
+	Type string
+	// Protocol is the protocol used to connect to the message bus, i.e. tcp or ssl
+	Protocol string
+	// Host is the hostname or IP address of the broker
+	Host string
+	// Port is the port of the broker
+	Port int
+	// AuthMode is the type of secure connection to the message bus, either AuthModeNone, AuthModeUsernamePassword,
+	// AuthModeClientCert or AuthModeCACert. Not all of them are supported by each implementation.
+	AuthMode string
+	// Username is the user name used with AuthModeUsernamePassword
+	Username string
+	// Password is the password used with AuthModeUsernamePassword
+	Password string
+	// TLS contains the certificates used with AuthModeClientCert and AuthModeCACert
+	TLS TLSConfig
+	// TopicRoot is the topic the configuration changes are published under, i.e. gems/site1/edgex/configs when the
+	// topics are namespaced per site. edgex/configs is used if not set.
+	TopicRoot string
+	// Optional contains the additional properties of the message bus implementation, i.e. ClientId or Qos for MQTT
+	Optional map[string]string
+}
+
+// IsEmpty returns true when the message bus connection isn't set
+func (config MessageBusConfig) IsEmpty() bool {
+	return config.Type == "" && config.Host == "" && config.Port == 0
+}
+
+// RetryPolicy defines how the calls to the Configuration service are retried when they fail with a transient error.
+// The delay before each retry grows exponentially from InitialBackoff up to MaxBackoff.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts for each call, including the first one. Values less than 2
+	// disable the retries.
+	MaxAttempts int
+	// InitialBackoff is the delay before the first retry. DefaultRetryInitialBackoff is used if not set.
+	InitialBackoff time.Duration
+	// MaxBackoff is the upper limit of the delay between two attempts. DefaultRetryMaxBackoff is used if not set.
+	MaxBackoff time.Duration
+	// Multiplier is the factor the delay grows by after each retry. DefaultRetryMultiplier is used if less than 1.
+	Multiplier float64
+	// Jitter is the fraction, between 0 and 1, of each delay which is randomized so that several clients don't
+	// retry in lockstep. No jitter is applied if not set.
+	Jitter float64
+	// RetryOn are the error classes, i.e. ErrUnavailable, which are retried. Only ErrUnavailable is retried if not set.
+	RetryOn []error
+}
+
+// IsEnabled returns true when the policy allows more than one attempt
+func (policy RetryPolicy) IsEnabled() bool {
+	return policy.MaxAttempts > 1
+}
+
+// IsRetryable returns true when the error matches one of the error classes the policy retries
+func (policy RetryPolicy) IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	retryOn := policy.RetryOn
+	if len(retryOn) == 0 {
+		retryOn = []error{ErrUnavailable}
+	}
+
+	for _, target := range retryOn {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Delay returns the delay before the specified retry, starting with 1 for the retry after the first attempt
+func (policy RetryPolicy) Delay(retry int) time.Duration {
+	initialBackoff := policy.InitialBackoff
+	if initialBackoff <= 0 {
+		initialBackoff = DefaultRetryInitialBackoff
+	}
+	maxBackoff := policy.MaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = DefaultRetryMaxBackoff
+	}
+	multiplier := policy.Multiplier
+	if multiplier < 1 {
+		multiplier = DefaultRetryMultiplier
+	}
+
+	delay := float64(initialBackoff)
+	for i := 1; i < retry && delay < float64(maxBackoff); i++ {
+		delay *= multiplier
+	}
+	if delay > float64(maxBackoff) {
+		delay = float64(maxBackoff)
+	}
+
+	if policy.Jitter > 0 {
+		jitter := policy.Jitter
+		if jitter > 1 {
+			jitter = 1
+		}
+		// nolint: gosec
+		delay -= delay * jitter * rand.Float64()
+	}
+
+	return time.Duration(delay)
+}
+
 //
 // A few helper functions for building URLs.
 //
@@ -17,7 +17,9 @@
 package types
 
 import (
+	"errors"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -132,3 +134,65 @@ func TestPopulateFromUrl(t *testing.T) {
 		})
 	}
 }
+
+func TestTLSConfigIsEmpty(t *testing.T) {
+	testCases := []struct {
+		Name     string
+		Config   TLSConfig
+		Expected bool
+	}{
+		{Name: "Empty", Config: TLSConfig{}, Expected: true},
+		{Name: "CA File", Config: TLSConfig{CAFile: "/tmp/ca.pem"}, Expected: false},
+		{Name: "CA PEM", Config: TLSConfig{CAPem: []byte("pem")}, Expected: false},
+		{Name: "Client Cert", Config: TLSConfig{CertFile: "/tmp/cert.pem", KeyFile: "/tmp/key.pem"}, Expected: false},
+		{Name: "Server Name", Config: TLSConfig{ServerName: "localhost"}, Expected: false},
+		{Name: "Skip Verify", Config: TLSConfig{InsecureSkipVerify: true}, Expected: false},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.Name, func(t *testing.T) {
+			assert.Equal(t, test.Expected, test.Config.IsEmpty())
+		})
+	}
+}
+
+func TestRetryPolicyDelay(t *testing.T) {
+	testCases := []struct {
+		Name     string
+		Policy   RetryPolicy
+		Retry    int
+		Expected time.Duration
+	}{
+		{Name: "Defaults", Policy: RetryPolicy{}, Retry: 1, Expected: DefaultRetryInitialBackoff},
+		{Name: "First retry", Policy: RetryPolicy{InitialBackoff: time.Second}, Retry: 1, Expected: time.Second},
+		{Name: "Exponential", Policy: RetryPolicy{InitialBackoff: time.Second}, Retry: 3, Expected: 4 * time.Second},
+		{Name: "Multiplier", Policy: RetryPolicy{InitialBackoff: time.Second, Multiplier: 3}, Retry: 3, Expected: 9 * time.Second},
+		{Name: "Max backoff", Policy: RetryPolicy{InitialBackoff: time.Second, MaxBackoff: 5 * time.Second}, Retry: 10, Expected: 5 * time.Second},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.Name, func(t *testing.T) {
+			assert.Equal(t, test.Expected, test.Policy.Delay(test.Retry))
+		})
+	}
+}
+
+func TestRetryPolicyJitter(t *testing.T) {
+	policy := RetryPolicy{InitialBackoff: time.Second, Jitter: 0.5}
+	for i := 0; i < 100; i++ {
+		delay := policy.Delay(1)
+		assert.GreaterOrEqual(t, delay, 500*time.Millisecond)
+		assert.LessOrEqual(t, delay, time.Second)
+	}
+}
+
+func TestRetryPolicyIsRetryable(t *testing.T) {
+	unavailable := NewProviderError(ErrUnavailable, errors.New("connection refused"))
+	notFound := NewProviderError(ErrNotFound, errors.New("missing"))
+
+	assert.False(t, RetryPolicy{}.IsRetryable(nil))
+	assert.True(t, RetryPolicy{}.IsRetryable(unavailable))
+	assert.False(t, RetryPolicy{}.IsRetryable(notFound))
+	assert.True(t, RetryPolicy{RetryOn: []error{ErrNotFound}}.IsRetryable(notFound))
+	assert.False(t, RetryPolicy{RetryOn: []error{ErrNotFound}}.IsRetryable(unavailable))
+}
@@ -0,0 +1,51 @@
+//
+// Copyright (C) 2022 IOTech Ltd
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package types
+
+import "errors"
+
+// The sentinel errors wrapped by the errors returned from the configuration providers, which callers can check
+// with errors.Is
+var (
+	// ErrNotFound indicates that the requested key or configuration doesn't exist in the Configuration service
+	ErrNotFound = errors.New("not found")
+	// ErrUnauthorized indicates that the Configuration service rejected the request because of missing or invalid
+	// credentials
+	ErrUnauthorized = errors.New("unauthorized")
+	// ErrUnavailable indicates that the Configuration service can't be reached or is temporarily unavailable
+	ErrUnavailable = errors.New("configuration service unavailable")
+	// ErrDecode indicates that the data received from the Configuration service can't be decoded
+	ErrDecode = errors.New("decoding failed")
+	// ErrConflict indicates that a compare-and-swap write was rejected because the value has been changed since the
+	// revision it was given was read
+	ErrConflict = errors.New("conflict")
+)
+
+// ProviderError classifies an error returned from a configuration provider with one of the sentinel errors above
+// while keeping the provider's own error message
+type ProviderError struct {
+	// Kind is the sentinel error matched by errors.Is
+	Kind error
+	// Err is the underlying error from the provider
+	Err error
+}
+
+// NewProviderError returns err classified with the kind sentinel error
+func NewProviderError(kind error, err error) error {
+	return &ProviderError{Kind: kind, Err: err}
+}
+
+func (e *ProviderError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ProviderError) Unwrap() error {
+	return e.Err
+}
+
+func (e *ProviderError) Is(target error) bool {
+	return target == e.Kind
+}
@@ -0,0 +1,16 @@
+//
+// Copyright (C) 2022 IOTech Ltd
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package types
+
+// ConfigurationFormat is the format of a configuration document, as exported by ExportConfiguration and imported by
+// ImportConfiguration
+type ConfigurationFormat string
+
+const (
+	FormatTOML ConfigurationFormat = "toml"
+	FormatYAML ConfigurationFormat = "yaml"
+	FormatJSON ConfigurationFormat = "json"
+)
@@ -0,0 +1,49 @@
+//
+// Copyright (C) 2022 IOTech Ltd
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package types
+
+// DefaultListSeparator delimits the levels of the listed keys unless WithSeparator is given
+const DefaultListSeparator = "/"
+
+// ListOption sets one of the optional settings of ListConfigurationKeys and GetConfigurationValues
+type ListOption func(options *ListOptions)
+
+// ListOptions are the optional settings of ListConfigurationKeys and GetConfigurationValues
+type ListOptions struct {
+	// Depth is the number of levels under the prefix which are listed, all of them if not set. The deeper keys are
+	// listed once as the section at that depth, ending with the separator, and their values aren't returned.
+	Depth int
+	// Separator delimits the levels of the prefix and of the listed keys
+	Separator string
+}
+
+// WithDepth only lists the keys up to depth levels under the prefix, so WithDepth(1) lists the values and the
+// sections right under it, the same way as a Consul listing with a separator
+func WithDepth(depth int) ListOption {
+	return func(options *ListOptions) {
+		options.Depth = depth
+	}
+}
+
+// WithSeparator delimits the levels of the prefix and of the listed keys with the separator rather than '/', e.g.
+// "." lists Writable.LogLevel. The key names mustn't contain the separator.
+func WithSeparator(separator string) ListOption {
+	return func(options *ListOptions) {
+		options.Separator = separator
+	}
+}
+
+// NewListOptions returns the settings of a listing with the options applied
+func NewListOptions(options ...ListOption) ListOptions {
+	result := ListOptions{Separator: DefaultListSeparator}
+	for _, option := range options {
+		option(&result)
+	}
+	if result.Separator == "" {
+		result.Separator = DefaultListSeparator
+	}
+	return result
+}
@@ -0,0 +1,21 @@
+//
+// Copyright (C) 2022 IOTech Ltd
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package types
+
+// Plan is the change of the stored configuration into a desired one, made by PlanConfiguration and executed by
+// ApplyPlan
+type Plan struct {
+	// Changes are the values to add, modify and remove, keyed relative to the service's base path
+	Changes ChangeSet
+	// Revisions are the revisions of the stored values the modifications and removals have been planned against,
+	// keyed the same way. ApplyPlan only executes the plan if they are still the current ones.
+	Revisions map[string]Revision
+}
+
+// IsEmpty checks if the plan doesn't change anything
+func (plan Plan) IsEmpty() bool {
+	return plan.Changes.IsEmpty()
+}
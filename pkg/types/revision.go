@@ -0,0 +1,26 @@
+//
+// Copyright (C) 2022 IOTech Ltd
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package types
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// Revision identifies the version of a configuration value, as returned by GetConfigurationValueWithVersion and
+// checked by PutConfigurationValueCAS. It is opaque to the callers and only comparable between reads of the same
+// provider. The empty Revision stands for a value which doesn't exist.
+type Revision string
+
+// NoRevision is the Revision of a value which doesn't exist, PutConfigurationValueCAS given it only creates the value
+const NoRevision Revision = ""
+
+// ValueRevision returns the Revision derived from the content of the value, used by the providers which don't
+// version the values themselves. Writing a value back to a previous content gives it back its previous Revision.
+func ValueRevision(value []byte) Revision {
+	hash := sha256.Sum256(value)
+	return Revision(hex.EncodeToString(hash[:]))
+}
@@ -0,0 +1,50 @@
+//
+// Copyright (C) 2022 IOTech Ltd
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package types
+
+import "time"
+
+// WatchHandle is returned by WatchForChanges to control that single watch, independently of the other watches
+// of the client
+type WatchHandle interface {
+	// Stop stops the watch and waits until it has stopped. Calling Stop more than once is fine.
+	Stop()
+	// Done returns a channel which is closed once the watch has stopped, either by Stop, StopWatching, its context
+	// being done or a failure to start.
+	Done() <-chan struct{}
+	// Ready returns a channel which is closed once the watch is established, i.e. subscribed to the changes or done
+	// reading the current configuration, so that all the changes made from then on are reported. It's never closed
+	// if the watch fails to start, so it's usually waited for along with Done.
+	Ready() <-chan struct{}
+}
+
+// WatchOption sets one of the optional settings of a single watch
+type WatchOption func(options *WatchOptions)
+
+// WatchOptions are the optional settings of a single watch
+type WatchOptions struct {
+	// Debounce is the window during which the changes following a change are coalesced into a single update. The
+	// updates are sent as soon as the changes are detected if not set.
+	Debounce time.Duration
+}
+
+// WithDebounce coalesces the changes arriving within the window following a change into a single update, carrying
+// the latest configuration and the merged change set. The window isn't extended by the later changes, so a steady
+// stream of changes still gets an update per window.
+func WithDebounce(window time.Duration) WatchOption {
+	return func(options *WatchOptions) {
+		options.Debounce = window
+	}
+}
+
+// NewWatchOptions returns the settings of a watch with the options applied
+func NewWatchOptions(options ...WatchOption) WatchOptions {
+	var result WatchOptions
+	for _, option := range options {
+		option(&result)
+	}
+	return result
+}